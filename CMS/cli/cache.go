@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/services"
+)
+
+// cache flush borra las claves de caché de Redis; los streams y demás datos se conservan
+func cache(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "flush" {
+		return errors.New("usage: cache flush")
+	}
+	if !cfg.Redis.Enabled {
+		return errors.New("there is no cache to flush: redis is disabled")
+	}
+	redis := &services.RedisClient{}
+	for _, prefix := range services.CachePrefixes {
+		n, err := redis.DeletePrefix(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		log.Printf("Deleted %d keys with prefix %q", n, prefix)
+	}
+	return nil
+}
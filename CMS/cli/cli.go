@@ -0,0 +1,164 @@
+// Package cli implementa los subcomandos del binario cms
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/database"
+)
+
+// command es un subcomando. stores indica qué conexiones necesita antes de ejecutarse.
+type command struct {
+	usage  string
+	stores stores
+	run    func(ctx context.Context, cfg *config.Config, args []string) error
+}
+
+type stores int
+
+const (
+	noStores stores = iota
+	postgresOnly
+	postgresAndMongo
+	allStores
+)
+
+var commands = map[string]command{
+	"serve":             {usage: "serve", stores: allStores, run: serve},
+	"migrate":           {usage: migrateUsage, stores: postgresAndMongo, run: migrate},
+	"user":              {usage: userUsage, stores: postgresOnly, run: user},
+	"content":           {usage: contentUsage, stores: allStores, run: content},
+	"cache":             {usage: "cache flush", stores: allStores, run: cache},
+	"check-consistency": {usage: "check-consistency [-repair] [-dry-run]", stores: allStores, run: checkConsistency},
+	"seed":              {usage: "seed [-admin-username u] [-admin-email e] [-admin-password p]", stores: allStores, run: seed},
+	"config":            {usage: "config print", stores: noStores, run: printConfig},
+	// reindex se mantiene por compatibilidad con los scripts anteriores
+	"reindex": {usage: "reindex", stores: allStores, run: func(ctx context.Context, cfg *config.Config, args []string) error {
+		return content(ctx, cfg, append([]string{"reindex"}, args...))
+	}},
+}
+
+// Run carga la configuración y ejecuta el subcomando de args; sin subcomando, serve
+func Run(args []string) error {
+	// La ayuda no necesita configuración válida
+	if len(args) > 0 && args[0] == "help" {
+		Usage(os.Stdout)
+		return nil
+	}
+	cfg, args, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		Usage(os.Stderr)
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	// Con storage=memory no hay nada que migrar ni administrar fuera del proceso
+	if cfg.Storage.Memory() && cmd.stores != noStores {
+		if name != "serve" {
+			return fmt.Errorf("command %q needs a persistent storage", name)
+		}
+		log.Println("Using in-memory storage: data is lost on exit")
+	} else if cmd.stores != noStores {
+		openStores(cfg, cmd.stores)
+		defer closeStores()
+	}
+	return cmd.run(context.Background(), cfg, args)
+}
+
+// Usage lista los subcomandos
+func Usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "usage: cms [flags] <command> [args]\n\ncommands:")
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+}
+
+// Orden de arranque: Postgres (o SQLite), Mongo y Redis; se cierran en orden inverso
+func openStores(cfg *config.Config, needed stores) {
+	database.ConnectRetries = cfg.Server.ConnectRetries
+	database.OperationTimeout = cfg.Server.OperationTimeout
+	if cfg.Storage.SQLite() {
+		database.SQLiteInit(cfg.SQLite.Path)
+	} else {
+		database.PostgresInit(cfg.Postgres.URL)
+	}
+	if needed >= postgresAndMongo && cfg.Storage.Mongo() {
+		database.MongoInit(cfg.Mongo.URI)
+	}
+	// El esquema debe estar al día antes de que nada lo use. Un archivo de SQLite
+	// solo lo usa este proceso, así que se migra siempre y uno nuevo queda listo.
+	if needed == allStores && (cfg.Postgres.AutoMigrate || cfg.Storage.SQLite()) {
+		if err := migrate(context.Background(), cfg, []string{"up"}); err != nil {
+			closeStores()
+			log.Fatal(err)
+		}
+	}
+	if needed == allStores && cfg.Redis.Enabled {
+		database.RedisInit(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	}
+}
+
+// closeStores cierra las conexiones en orden inverso al de arranque. Solo actúa la primera vez.
+var closeStores = sync.OnceFunc(func() {
+	if err := database.RedisClose(); err != nil {
+		log.Printf("Error closing redis: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := database.MongoClose(ctx); err != nil {
+		log.Printf("Error closing mongo: %v", err)
+	}
+	if err := database.PostgresClose(); err != nil {
+		log.Printf("Error closing %s: %v", database.PostgresGetDB().Dialector.Name(), err)
+	}
+})
+
+// printConfig muestra la configuración efectiva sin los secretos
+func printConfig(_ context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errors.New("usage: config print")
+	}
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		return fmt.Errorf("printing config: %w", err)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// subcommand separa el primer argumento, que elige la operación
+func subcommand(usage string, args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", nil, errors.New("usage: " + usage)
+	}
+	return args[0], args[1:], nil
+}
+
+// parseFlags parsea los flags de un subcomando; -h muestra la ayuda y devuelve flag.ErrHelp
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	flags.SetOutput(os.Stderr)
+	return flags.Parse(args)
+}
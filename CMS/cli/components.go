@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/database"
+	"JSanches/CMD/services"
+)
+
+// components son los servicios compartidos por serve y los comandos que
+// modifican contenidos, para que ambos apliquen los mismos efectos (índice,
+// caché, outbox y eventos). Con storage=memory taxonomy, outbox, webhooks y
+// checker quedan nil.
+type components struct {
+	users    services.UserAdminRepository
+	contents *services.ContentHandler
+	search   services.SearchIndex
+	media    services.MediaRepository
+	// mediaCache es opcional: recuerda las variantes de imagen ya generadas
+	mediaCache services.MediaCache
+	feeds      services.FeedRepository
+	taxonomy   *services.TaxonomyClient
+	outbox     *services.OutboxRelay
+	webhooks   *services.WebhookDispatcher
+	stream     *services.ContentStream
+	checker    *services.ConsistencyChecker
+}
+
+func build(cfg *config.Config) (*components, error) {
+	if cfg.Storage.Memory() {
+		return buildMemory(cfg)
+	}
+	// Con storage=postgres o sqlite los cuerpos van a content_bodies y Redis es opcional
+	var bodies services.MongoRepository = &services.MongoClient{}
+	consistencyRepo := &services.ConsistencyClient{}
+	if !cfg.Storage.Mongo() {
+		bodyStore := services.NewSQLBodyStore(database.PostgresGetDB())
+		bodies, consistencyRepo.Bodies = bodyStore, bodyStore
+	}
+	var cache services.CacheRepository
+	var mediaCache services.MediaCache
+	if cfg.Redis.Enabled {
+		redis := &services.RedisClient{}
+		cache, mediaCache = redis, redis
+	}
+	contentHandler := services.NewContentHandler(&services.PGClient{}, bodies, cache)
+
+	searchIndex, err := services.NewSearchIndex(cfg.Search.Backend, database.PostgresGetDB(), cfg.Search.IndexPath, cfg.Search.Language)
+	if err != nil {
+		return nil, fmt.Errorf("creating search index: %w", err)
+	}
+	contentHandler.Search = searchIndex
+
+	taxonomyRepo := &services.TaxonomyClient{}
+	contentHandler.Taxonomy = taxonomyRepo
+	contentHandler.Slugs = &services.SlugClient{}
+
+	// locales.list="es,en" habilita la localización; el primero es el idioma por defecto
+	if cfg.Locales.List != "" {
+		locales, err := services.NewLocales(cfg.Locales.List, cfg.Locales.Fallbacks)
+		if err != nil {
+			return nil, fmt.Errorf("loading locales: %w", err)
+		}
+		contentHandler.Locales = locales
+		contentHandler.Translations = &services.TranslationClient{}
+	}
+
+	outboxRelay := services.NewOutboxRelay(&services.OutboxClient{}, contentHandler.Mongo)
+	outboxRelay.Translations = contentHandler.Translations
+	outboxRelay.Cache = contentHandler.ContentCache
+	contentHandler.Outbox = outboxRelay
+
+	webhookDispatcher := services.NewWebhookDispatcher(&services.WebhookClient{})
+
+	// stream.backend=memory sirve para una sola instancia; con Redis se reparte entre todas
+	streamBackend := cfg.Stream.Backend
+	if !cfg.Redis.Enabled {
+		streamBackend = "memory"
+	}
+	streamBroker, err := services.NewStreamBroker(streamBackend)
+	if err != nil {
+		return nil, fmt.Errorf("creating stream broker: %w", err)
+	}
+	contentStream := services.NewContentStream(streamBroker)
+	contentHandler.Events = services.EventPublishers{webhookDispatcher, contentStream}
+
+	checker := services.NewConsistencyChecker(consistencyRepo, contentHandler.Mongo)
+	checker.Translations = contentHandler.Translations
+	checker.Cache = contentHandler.ContentCache
+
+	return &components{
+		users:      &services.PostgresUserRepository{DB: database.PostgresGetDB()},
+		contents:   contentHandler,
+		search:     searchIndex,
+		media:      &services.MediaClient{},
+		mediaCache: mediaCache,
+		feeds:      &services.FeedClient{},
+		taxonomy:   taxonomyRepo,
+		outbox:     outboxRelay,
+		webhooks:   webhookDispatcher,
+		stream:     contentStream,
+		checker:    checker,
+	}, nil
+}
+
+// buildMemory guarda todo en el proceso: contenidos, cuerpos, usuarios, caché,
+// metadatos de medios y traducciones. Los archivos de medios van al blob store
+// configurado, local por defecto. Taxonomía, tipos de contenido, webhooks,
+// outbox y consistencia no tienen equivalente en memoria y serve responde 501.
+func buildMemory(cfg *config.Config) (*components, error) {
+	contentStore := services.NewMemoryContentStore()
+	cache := services.NewMemoryCache()
+	contentHandler := services.NewContentHandler(contentStore, services.NewMemoryBodyStore(), cache)
+	contentHandler.Slugs = contentStore
+
+	if cfg.Locales.List != "" {
+		locales, err := services.NewLocales(cfg.Locales.List, cfg.Locales.Fallbacks)
+		if err != nil {
+			return nil, fmt.Errorf("loading locales: %w", err)
+		}
+		contentHandler.Locales = locales
+		contentHandler.Translations = services.NewMemoryTranslationStore()
+	}
+
+	searchIndex, err := services.NewSearchIndex("memory", nil, cfg.Search.IndexPath, cfg.Search.Language)
+	if err != nil {
+		return nil, fmt.Errorf("creating search index: %w", err)
+	}
+	contentHandler.Search = searchIndex
+
+	// Sin Redis el stream solo puede repartir eventos dentro del proceso
+	contentStream := services.NewContentStream(services.NewMemoryStreamBroker(1000))
+	contentHandler.Events = contentStream
+
+	return &components{
+		users:      services.NewMemoryUserStore(),
+		contents:   contentHandler,
+		search:     searchIndex,
+		media:      services.NewMemoryMediaStore(),
+		mediaCache: cache,
+		feeds:      contentStore,
+		stream:     contentStream,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+
+	"JSanches/CMD/config"
+)
+
+var errInconsistent = errors.New("postgres and mongo are not consistent")
+
+// checkConsistency imprime el informe en JSON; falla si quedan diferencias sin reparar
+func checkConsistency(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("check-consistency", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "delete orphan bodies and create missing ones")
+	dryRun := flags.Bool("dry-run", false, "list repairs without applying them")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	c, err := build(cfg)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.checker.Check(ctx, *repair, *dryRun)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if !report.Consistent() && (!*repair || *dryRun) {
+		return errInconsistent
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/services"
+)
+
+const contentUsage = "content reindex|export [-o file]|import [-i file]"
+
+// content opera sobre todos los contenidos. export e import usan JSON Lines,
+// por defecto en la salida y la entrada estándar.
+func content(ctx context.Context, cfg *config.Config, args []string) error {
+	op, args, err := subcommand(contentUsage, args)
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("content "+op, flag.ContinueOnError)
+	var output, input *string
+	switch op {
+	case "export":
+		output = flags.String("o", "-", "file to write, - for stdout")
+	case "import":
+		input = flags.String("i", "-", "file to read, - for stdin")
+	case "reindex":
+	default:
+		return fmt.Errorf("unknown content command %q", op)
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	c, err := build(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "reindex":
+		n, err := services.ReindexAll(ctx, c.contents.PG, c.contents.Mongo, c.search)
+		if err != nil {
+			return fmt.Errorf("reindexing contents: %w", err)
+		}
+		log.Printf("Reindexed %d contents", n)
+	case "export":
+		var w io.Writer = os.Stdout
+		if *output != "-" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := c.contents.ExportContents(ctx, w)
+		if err != nil {
+			return fmt.Errorf("exporting contents: %w", err)
+		}
+		log.Printf("Exported %d contents", n)
+	case "import":
+		var r io.Reader = os.Stdin
+		if *input != "-" {
+			f, err := os.Open(*input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		result, err := c.contents.ImportContents(ctx, r)
+		log.Printf("Imported %d contents, skipped %d existing", result.Created, result.Skipped)
+		if err != nil {
+			return fmt.Errorf("importing contents: %w", err)
+		}
+	}
+	return nil
+}
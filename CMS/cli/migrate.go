@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/database"
+	"JSanches/CMD/migrations"
+)
+
+const migrateUsage = "migrate up|down [-steps n]|status"
+
+// migrate aplica, deshace o lista las migraciones de Postgres o SQLite y los índices de Mongo
+func migrate(ctx context.Context, cfg *config.Config, args []string) error {
+	op, args, err := subcommand(migrateUsage, args)
+	if err != nil {
+		return err
+	}
+	db := database.PostgresGetDB()
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.NewMigrator(migrations.Dialect(db.Dialector.Name()), sqlDB)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if cfg.Storage.Mongo() {
+			if err := migrations.EnsureMongoIndexes(ctx, database.MongoGetClient()); err != nil {
+				return err
+			}
+		}
+		log.Printf("Schema up to date")
+		return nil
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		if err := parseFlags(flags, args); err != nil {
+			return err
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			log.Printf("Reverted migration %04d_%s", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		var indexes []migrations.MongoIndexStatus
+		if cfg.Storage.Mongo() {
+			if indexes, err = migrations.MongoStatus(ctx, database.MongoGetClient()); err != nil {
+				return err
+			}
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			state, appliedAt := "pending", ""
+			if st.Applied {
+				state, appliedAt = "applied", st.AppliedAt.Format(time.RFC3339)
+			}
+			if st.Modified {
+				state = "modified"
+			}
+			if st.Unknown {
+				state = "unknown"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		if len(indexes) > 0 {
+			fmt.Fprintln(w, "\nMONGO INDEX\tCOLLECTION\tSTATUS\t")
+		}
+		for _, index := range indexes {
+			state := "missing"
+			if index.Exists {
+				state = "present"
+			}
+			fmt.Fprintf(w, "%s\t%s.%s\t%s\t\n", index.Name, index.Database, index.Collection, state)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q", op)
+	}
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+)
+
+// seed crea datos de ejemplo para desarrollo: un administrador, una categoría y
+// unos contenidos. Lo que ya existe se deja como está, así que se puede repetir.
+func seed(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	username := flags.String("admin-username", "admin", "username of the admin user")
+	email := flags.String("admin-email", "admin@example.com", "email of the admin user")
+	password := flags.String("admin-password", "", "password of the admin user; a random one is generated and printed if empty")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	c, err := build(cfg)
+	if err != nil {
+		return err
+	}
+
+	admin := services.NewUserAdmin(&services.PostgresUserRepository{DB: database.PostgresGetDB()})
+	author, err := admin.Find(ctx, *username)
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		var generated string
+		author, generated, err = admin.Create(ctx, *username, *email, *password, services.RoleAdmin)
+		if err != nil {
+			return fmt.Errorf("creating admin user: %w", err)
+		}
+		printUser("Created", author, generated, *password == "")
+	case err != nil:
+		return err
+	default:
+		log.Printf("User %s already exists", author.Username)
+	}
+
+	category, err := seedCategory(ctx, c.taxonomy, "General")
+	if err != nil {
+		return fmt.Errorf("creating category: %w", err)
+	}
+
+	now := time.Now().UTC()
+	records := []services.ContentRecord{
+		{
+			Title:       "Welcome",
+			Slug:        "welcome",
+			Description: "First published content",
+			Body:        "# Welcome\n\nThis content was created by `cms seed`.\n\n## Next steps\n\nEdit or delete it from the API.",
+			Format:      services.FormatMarkdown,
+			PublishedAt: &now,
+			Tags:        []string{"news"},
+		},
+		{
+			Title:       "Getting started",
+			Slug:        "getting-started",
+			Description: "How to create contents",
+			Body:        "Create contents with POST /collection/contents and publish them with published=true.",
+			PublishedAt: &now,
+			Tags:        []string{"guides"},
+		},
+		{
+			Title:       "Draft",
+			Slug:        "draft",
+			Description: "An unpublished content",
+			Body:        "Drafts do not appear in the feeds.",
+		},
+	}
+	created := 0
+	for _, record := range records {
+		record.UserID = int(author.ID)
+		record.CategoryID = &category.ID
+		ok, err := c.contents.ImportContent(ctx, record)
+		if err != nil {
+			return fmt.Errorf("creating content %s: %w", record.Slug, err)
+		}
+		if ok {
+			created++
+		}
+	}
+	log.Printf("Seeded %d contents, %d already existed", created, len(records)-created)
+	return nil
+}
+
+func seedCategory(ctx context.Context, taxonomy services.TaxonomyRepository, name string) (*models.Category, error) {
+	var categories []models.Category
+	if err := taxonomy.ListCategories(ctx, &categories); err != nil {
+		return nil, err
+	}
+	slug := services.Slugify(name)
+	for _, category := range categories {
+		if category.Slug == slug {
+			return &category, nil
+		}
+	}
+	category := &models.Category{Name: name, Slug: slug}
+	return category, taxonomy.SaveCategory(ctx, category)
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/database"
+	"JSanches/CMD/routes"
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// serve levanta el servidor HTTP y los workers hasta recibir SIGINT o SIGTERM
+func serve(_ context.Context, cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return errors.New("usage: serve")
+	}
+	c, err := build(cfg)
+	if err != nil {
+		return err
+	}
+
+	handler := &services.Handler{
+		UserRepo:     c.users,
+		TokenService: services.NewTokenService(cfg.Auth.SecretKey),
+	}
+
+	s3 := cfg.Media.S3
+	blobStore, err := services.NewBlobStore(cfg.Media.BlobStore, cfg.Media.Dir, services.S3Config{
+		Endpoint:  s3.Endpoint,
+		AccessKey: s3.AccessKey,
+		SecretKey: s3.SecretKey,
+		Bucket:    s3.Bucket,
+		Region:    s3.Region,
+		UseSSL:    s3.UseSSL,
+	})
+	if err != nil {
+		return fmt.Errorf("creating blob store: %w", err)
+	}
+	mediaHandler := services.NewMediaHandler(c.media, blobStore, cfg.Media.MaxSize)
+	mediaHandler.SigningKey = []byte(cfg.Media.SigningKey)
+	if cfg.Media.MaxPixels > 0 {
+		mediaHandler.MaxPixels = cfg.Media.MaxPixels
+	}
+	mediaHandler.Cache = c.mediaCache
+
+	app := fiber.New(fiber.Config{
+		IdleTimeout: 10 * time.Second,
+		// Los cuerpos los limita utils.BodyLimit para que solo la subida de medios admita archivos grandes
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		ErrorHandler:                 utils.ErrorHandler,
+	})
+	// Un panic en un handler se responde como un 500 genérico
+	app.Use(recover.New())
+	app.Use(utils.BodyLimit(fiber.DefaultBodyLimit, isMediaUpload))
+	app.Use(compress.New())
+
+	var healthHandler *services.HealthHandler
+	if cfg.Storage.Memory() {
+		healthHandler = services.NewHealthHandler()
+	} else {
+		healthHandler = services.NewHealthHandler(services.DatabaseHealthChecks(database.PostgresGetDB().Dialector.Name(), cfg.Storage.Mongo(), cfg.Redis.Enabled)...)
+	}
+	routes.PublicHealthRoutes(app, healthHandler)
+	routes.PublicRoutes(app, handler)
+	routes.PublicMediaRoutes(app, mediaHandler)
+	routes.PublicFeedRoutes(app, services.NewFeedHandler(c.feeds, c.contents, cfg.Feeds.Title, cfg.Feeds.BaseURL))
+
+	protected := app.Use(utils.AuthMiddleware(cfg.Auth.SecretKey))
+	// Cualquiera puede registrarse, así que la administración exige el rol admin
+	adminOnly := utils.RequireRole(services.RoleAdmin)
+
+	collection := protected.Group("/collection")
+	routes.RegisterContentRoutes(collection, c.contents)
+	routes.RegisterStreamRoutes(collection, services.NewStreamHandler(c.stream))
+	// Las entradas de tipos de contenido solo existen en Mongo
+	if cfg.Storage.Mongo() {
+		contentTypeHandler := services.NewContentTypeHandler(&services.ContentTypeClient{}, &services.EntryClient{})
+		contentTypeHandler.Stream = c.stream
+		routes.RegisterEntryRoutes(collection, contentTypeHandler)
+		routes.RegisterContentTypeRoutes(protected.Group("/content-types", adminOnly), contentTypeHandler)
+	} else {
+		protected.Use("/content-types", unsupported("Content types require storage=databases"))
+	}
+	routes.RegisterMediaRoutes(protected.Group("/media"), mediaHandler)
+	if cfg.Storage.Memory() {
+		// Sin equivalente en memoria: se responde 501 en lugar de un 404 que parezca un error de ruta
+		for _, prefix := range []string{"/taxonomy", "/outbox", "/admin/consistency", "/webhooks"} {
+			protected.Use(prefix, unsupported("Not available with storage=memory"))
+		}
+	} else {
+		taxonomyHandler := services.NewTaxonomyHandler(c.taxonomy)
+		taxonomyHandler.Cache = c.contents.ContentCache
+		routes.RegisterTaxonomyRoutes(protected.Group("/taxonomy", adminOnly), taxonomyHandler)
+		routes.RegisterOutboxRoutes(protected.Group("/outbox", adminOnly), services.NewOutboxHandler(c.outbox))
+		routes.RegisterConsistencyRoutes(protected.Group("/admin/consistency", adminOnly), services.NewConsistencyHandler(c.checker))
+		routes.RegisterWebhookRoutes(protected.Group("/webhooks", adminOnly), services.NewWebhookHandler(c.webhooks))
+	}
+
+	// Los workers tienen su propio contexto: se paran después de drenar las peticiones,
+	// que todavía pueden encolar eventos del outbox o de webhooks
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runners := []func(context.Context){c.stream.Run}
+	if c.outbox != nil {
+		runners = append(runners, c.outbox.Run, c.webhooks.Run)
+	}
+	for _, run := range runners {
+		workers.Add(1)
+		go func(run func(context.Context)) {
+			defer workers.Done()
+			run(workersCtx)
+		}(run)
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(cfg.Server.Addr())
+	}()
+
+	select {
+	case err := <-listenErr:
+		stopWorkers()
+		workers.Wait()
+		return fmt.Errorf("starting server: %w", err)
+	case <-signals.Done():
+		log.Println("Shutting down")
+	}
+
+	// server.shutdown_timeout limita lo que se espera a las peticiones en curso
+	deadline := time.Now().Add(cfg.Server.ShutdownTimeout)
+
+	// /readyz empieza a fallar para que no lleguen peticiones nuevas, y las
+	// conexiones de /collection/stream, que no terminan solas, se cierran
+	healthHandler.SetDraining()
+	c.stream.Close()
+	if err := app.ShutdownWithTimeout(time.Until(deadline)); err != nil {
+		log.Printf("Error draining requests: %v", err)
+	}
+
+	stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		log.Println("Background workers did not stop before the deadline")
+	}
+	log.Println("Server stopped")
+	return nil
+}
+
+// unsupported responde 501 en las rutas de funciones que el almacenamiento elegido no ofrece
+func unsupported(detail string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return utils.Problem(c, fiber.StatusNotImplemented, detail)
+	}
+}
+
+// isMediaUpload reconoce la subida de medios, que aplica su propio límite de cuerpo
+func isMediaUpload(c *fiber.Ctx) bool {
+	return c.Method() == fiber.MethodPost && strings.EqualFold(strings.TrimSuffix(c.Path(), "/"), "/media")
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"JSanches/CMD/config"
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+)
+
+const userUsage = "user create|ban|unban|set-role|reset-password [flags] [<username|email>]"
+
+// user gestiona cuentas sin pasar por la API
+func user(ctx context.Context, _ *config.Config, args []string) error {
+	op, args, err := subcommand(userUsage, args)
+	if err != nil {
+		return err
+	}
+	admin := services.NewUserAdmin(&services.PostgresUserRepository{DB: database.PostgresGetDB()})
+
+	flags := flag.NewFlagSet("user "+op, flag.ContinueOnError)
+	switch op {
+	case "create":
+		username := flags.String("username", "", "username")
+		email := flags.String("email", "", "email")
+		password := flags.String("password", "", "password; a random one is generated and printed if empty")
+		role := flags.String("role", services.RoleAuthor, "role: "+strings.Join(services.Roles, ", "))
+		if err := parseFlags(flags, args); err != nil {
+			return err
+		}
+		created, generated, err := admin.Create(ctx, *username, *email, *password, *role)
+		if err != nil {
+			return err
+		}
+		printUser("Created", created, generated, *password == "")
+		return nil
+	case "ban", "unban":
+		login, err := userLogin(op, flags, args)
+		if err != nil {
+			return err
+		}
+		updated, err := admin.SetBanned(ctx, login, op == "ban")
+		if err != nil {
+			return err
+		}
+		printUser(map[string]string{"ban": "Banned", "unban": "Unbanned"}[op], updated, "", false)
+		return nil
+	case "set-role":
+		role := flags.String("role", "", "role: "+strings.Join(services.Roles, ", "))
+		login, err := userLogin(op, flags, args)
+		if err != nil {
+			return err
+		}
+		updated, err := admin.SetRole(ctx, login, *role)
+		if err != nil {
+			return err
+		}
+		printUser("Updated", updated, "", false)
+		return nil
+	case "reset-password":
+		password := flags.String("password", "", "new password; a random one is generated and printed if empty")
+		login, err := userLogin(op, flags, args)
+		if err != nil {
+			return err
+		}
+		updated, generated, err := admin.ResetPassword(ctx, login, *password)
+		if err != nil {
+			return err
+		}
+		printUser("Reset password of", updated, generated, *password == "")
+		return nil
+	default:
+		return fmt.Errorf("unknown user command %q", op)
+	}
+}
+
+// userLogin parsea los flags y exige el nombre o email del usuario como único argumento
+func userLogin(op string, flags *flag.FlagSet, args []string) (string, error) {
+	if err := parseFlags(flags, args); err != nil {
+		return "", err
+	}
+	if flags.NArg() != 1 {
+		return "", errors.New("usage: user " + op + " [flags] <username|email>")
+	}
+	return flags.Arg(0), nil
+}
+
+func printUser(action string, u *models.User, password string, generated bool) {
+	fmt.Printf("%s user %d (%s, %s, role %s, banned %t)\n", action, u.ID, u.Username, u.Email, u.Role, u.IsBanned)
+	if generated {
+		fmt.Printf("Generated password: %s\n", password)
+	}
+}
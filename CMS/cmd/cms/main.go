@@ -0,0 +1,18 @@
+// cms sirve la API y ejecuta las tareas de operación: migraciones, usuarios,
+// exportación e importación de contenidos, caché y consistencia
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"JSanches/CMD/cli"
+)
+
+func main() {
+	if err := cli.Run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,473 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config reúne toda la configuración del CMS. Cada valor se resuelve, de menor a
+// mayor prioridad: valor por defecto, archivo YAML/TOML, variable de entorno y flag.
+// Las etiquetas env admiten varios nombres; el primero es el preferido y el resto
+// se mantiene por compatibilidad.
+type Config struct {
+	Storage  Storage  `yaml:"storage" toml:"storage"`
+	Server   Server   `yaml:"server" toml:"server"`
+	Postgres Postgres `yaml:"postgres" toml:"postgres"`
+	SQLite   SQLite   `yaml:"sqlite" toml:"sqlite"`
+	Mongo    Mongo    `yaml:"mongo" toml:"mongo"`
+	Redis    Redis    `yaml:"redis" toml:"redis"`
+	Auth     Auth     `yaml:"auth" toml:"auth"`
+	Search   Search   `yaml:"search" toml:"search"`
+	Locales  Locales  `yaml:"locales" toml:"locales"`
+	Media    Media    `yaml:"media" toml:"media"`
+	Stream   Stream   `yaml:"stream" toml:"stream"`
+	Feeds    Feeds    `yaml:"feeds" toml:"feeds"`
+}
+
+// Storage elige dónde se guardan los datos:
+//   - databases: filas en Postgres, cuerpos y traducciones en Mongo y caché en Redis.
+//   - postgres: también los cuerpos en Postgres (JSONB); Redis es opcional y Mongo no se usa.
+//   - sqlite: lo mismo que postgres pero en un único archivo de SQLite, sin servidor.
+//   - memory: todo en el proceso, para demos y pruebas; los datos se pierden al salir.
+//     Los archivos de medios siguen en media.blob_store. Taxonomía, tipos de contenido,
+//     webhooks, outbox y consistencia responden 501.
+type Storage struct {
+	Backend string `yaml:"backend" toml:"backend" env:"STORAGE" flag:"storage" usage:"databases, postgres, sqlite or memory"`
+}
+
+const (
+	StorageDatabases = "databases"
+	StoragePostgres  = "postgres"
+	StorageSQLite    = "sqlite"
+	StorageMemory    = "memory"
+)
+
+// Memory indica que todo se guarda en el proceso
+func (s Storage) Memory() bool {
+	return s.Backend == StorageMemory
+}
+
+// Mongo indica que los cuerpos y las traducciones se guardan en Mongo
+func (s Storage) Mongo() bool {
+	return s.Backend == StorageDatabases
+}
+
+// SQLite indica que las filas y los cuerpos se guardan en un archivo de SQLite
+func (s Storage) SQLite() bool {
+	return s.Backend == StorageSQLite
+}
+
+type Server struct {
+	Port            int           `yaml:"port" toml:"port" env:"PORT" flag:"port" usage:"HTTP port"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"time allowed to drain requests on shutdown"`
+	ConnectRetries  int           `yaml:"connect_retries" toml:"connect_retries" env:"DB_CONNECT_RETRIES" flag:"connect-retries" usage:"attempts to connect to each database at startup"`
+	// OperationTimeout limita cada consulta a las bases de datos y a Redis; 0 la deja sin límite propio
+	OperationTimeout time.Duration `yaml:"operation_timeout" toml:"operation_timeout" env:"DB_OPERATION_TIMEOUT" flag:"operation-timeout" usage:"deadline of each database or cache operation, 0 for none"`
+}
+
+type Postgres struct {
+	URL         string `yaml:"url" toml:"url" env:"POSTGRES_URL,DB_URL" flag:"postgres-url" secret:"true" usage:"Postgres connection string"`
+	AutoMigrate bool   `yaml:"auto_migrate" toml:"auto_migrate" env:"POSTGRES_AUTO_MIGRATE" flag:"auto-migrate" usage:"apply pending migrations and Mongo indexes when serving"`
+}
+
+type SQLite struct {
+	Path string `yaml:"path" toml:"path" env:"SQLITE_PATH" flag:"sqlite-path" usage:"SQLite database file used by the sqlite storage"`
+}
+
+type Mongo struct {
+	URI string `yaml:"uri" toml:"uri" env:"MONGO_URI,URI" flag:"mongo-uri" secret:"true" usage:"MongoDB connection URI"`
+}
+
+type Redis struct {
+	Enabled  bool   `yaml:"enabled" toml:"enabled" env:"REDIS_ENABLED" flag:"redis-enabled" usage:"use Redis for the cache and the stream; only optional with the postgres and sqlite storages"`
+	Addr     string `yaml:"addr" toml:"addr" env:"REDIS_ADDR,ADDRe" flag:"redis-addr" usage:"Redis host:port"`
+	Password string `yaml:"password" toml:"password" env:"REDIS_PASSWORD,PW" flag:"redis-password" secret:"true" usage:"Redis password"`
+	DB       int    `yaml:"db" toml:"db" env:"REDIS_DB,DB" flag:"redis-db" usage:"Redis database number"`
+}
+
+type Auth struct {
+	SecretKey string `yaml:"secret_key" toml:"secret_key" env:"SECRET_KEY" flag:"secret-key" secret:"true" usage:"key used to sign session tokens"`
+}
+
+type Search struct {
+	Backend   string `yaml:"backend" toml:"backend" env:"SEARCH_BACKEND" flag:"search-backend" usage:"memory or postgres"`
+	IndexPath string `yaml:"index_path" toml:"index_path" env:"SEARCH_INDEX_PATH" flag:"search-index-path" usage:"file where the memory index is persisted"`
+	Language  string `yaml:"language" toml:"language" env:"SEARCH_LANGUAGE" flag:"search-language" usage:"Postgres text search configuration"`
+}
+
+type Locales struct {
+	List      string `yaml:"list" toml:"list" env:"LOCALES" flag:"locales" usage:"supported locales, the first one is the default"`
+	Fallbacks string `yaml:"fallbacks" toml:"fallbacks" env:"LOCALE_FALLBACKS" flag:"locale-fallbacks" usage:"fallback chains such as ca:es,pt:es"`
+}
+
+type Media struct {
+	BlobStore  string `yaml:"blob_store" toml:"blob_store" env:"BLOB_STORE" flag:"blob-store" usage:"local or s3"`
+	Dir        string `yaml:"dir" toml:"dir" env:"MEDIA_DIR" flag:"media-dir" usage:"directory of the local blob store"`
+	MaxSize    int64  `yaml:"max_size" toml:"max_size" env:"MEDIA_MAX_SIZE" flag:"media-max-size" usage:"maximum upload size in bytes"`
+	MaxPixels  int64  `yaml:"max_pixels" toml:"max_pixels" env:"MEDIA_MAX_PIXELS" flag:"media-max-pixels" usage:"largest image, in pixels, that is decoded to build variants"`
+	SigningKey string `yaml:"signing_key" toml:"signing_key" env:"MEDIA_SIGNING_KEY" flag:"media-signing-key" secret:"true" usage:"key used to sign image variant URLs"`
+	S3         S3     `yaml:"s3" toml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint" env:"S3_ENDPOINT" flag:"s3-endpoint" usage:"S3 endpoint"`
+	AccessKey string `yaml:"access_key" toml:"access_key" env:"S3_ACCESS_KEY" flag:"s3-access-key" usage:"S3 access key"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key" env:"S3_SECRET_KEY" flag:"s3-secret-key" secret:"true" usage:"S3 secret key"`
+	Bucket    string `yaml:"bucket" toml:"bucket" env:"S3_BUCKET" flag:"s3-bucket" usage:"S3 bucket"`
+	Region    string `yaml:"region" toml:"region" env:"S3_REGION" flag:"s3-region" usage:"S3 region"`
+	UseSSL    bool   `yaml:"use_ssl" toml:"use_ssl" env:"S3_USE_SSL" flag:"s3-use-ssl" usage:"use HTTPS to reach S3"`
+}
+
+type Stream struct {
+	Backend string `yaml:"backend" toml:"backend" env:"STREAM_BACKEND" flag:"stream-backend" usage:"redis or memory"`
+}
+
+type Feeds struct {
+	Title   string `yaml:"title" toml:"title" env:"FEED_TITLE" flag:"feed-title" usage:"title of the public feeds"`
+	BaseURL string `yaml:"base_url" toml:"base_url" env:"FEED_BASE_URL" flag:"feed-base-url" usage:"site URL used for feed links"`
+}
+
+// Default devuelve la configuración antes de aplicar archivo, entorno y flags
+func Default() *Config {
+	return &Config{
+		Storage: Storage{Backend: StorageDatabases},
+		Server:  Server{Port: 4000, ShutdownTimeout: 20 * time.Second, ConnectRetries: 5, OperationTimeout: 5 * time.Second},
+		SQLite:  SQLite{Path: "cms.db"},
+		Redis:   Redis{Enabled: true, Addr: "localhost:6379"},
+		Search:  Search{Backend: "memory"},
+		Media:   Media{BlobStore: "local", Dir: "media", S3: S3{UseSSL: true}},
+		Stream:  Stream{Backend: "redis"},
+		Feeds:   Feeds{Title: "CMS"},
+	}
+}
+
+// Load lee .env si existe, el archivo indicado con -config o CMS_CONFIG, el entorno
+// y los flags de args, y valida el resultado. Devuelve los argumentos que quedan
+// después de los flags (el comando y sus opciones).
+func Load(args []string) (*Config, []string, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	cfg := Default()
+	fields := cfg.fields()
+
+	flags := flag.NewFlagSet("cms", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("CMS_CONFIG"), "YAML or TOML config file (env CMS_CONFIG)")
+	raw := map[string]*string{}
+	for _, f := range fields {
+		if f.flag != "" {
+			usage := f.usage
+			if len(f.env) > 0 {
+				usage += " (env " + f.env[0] + ")"
+			}
+			raw[f.flag] = flags.String(f.flag, f.display(), usage)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var problems []error
+	for _, f := range fields {
+		for _, name := range f.env {
+			if value, ok := os.LookupEnv(name); ok && value != "" {
+				if err := f.set(value); err != nil {
+					problems = append(problems, fmt.Errorf("%s: %w", name, err))
+				}
+				break
+			}
+		}
+	}
+	flags.Visit(func(fl *flag.Flag) {
+		for _, f := range fields {
+			if f.flag == fl.Name {
+				if err := f.set(*raw[fl.Name]); err != nil {
+					problems = append(problems, fmt.Errorf("-%s: %w", fl.Name, err))
+				}
+			}
+		}
+	})
+	if len(problems) > 0 {
+		return nil, nil, errors.Join(problems...)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// Un archivo vacío deja la configuración como estaba
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Validate comprueba la configuración completa y devuelve todos los problemas juntos
+func (c *Config) Validate() error {
+	var problems []error
+	fieldErr := func(path, msg string) {
+		for _, f := range c.fields() {
+			if f.path == path {
+				msg += " (" + f.sources() + ")"
+			}
+		}
+		problems = append(problems, fmt.Errorf("%s %s", path, msg))
+	}
+	oneOf := func(path, value string, options ...string) {
+		for _, option := range options {
+			if value == option {
+				return
+			}
+		}
+		fieldErr(path, fmt.Sprintf("must be one of %s, got %q", strings.Join(options, ", "), value))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fieldErr("server.port", "must be between 1 and 65535")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fieldErr("server.shutdown_timeout", "must be positive")
+	}
+	if c.Server.ConnectRetries < 1 {
+		fieldErr("server.connect_retries", "must be at least 1")
+	}
+	if c.Server.OperationTimeout < 0 {
+		fieldErr("server.operation_timeout", "must not be negative")
+	}
+	oneOf("storage.backend", c.Storage.Backend, StorageDatabases, StoragePostgres, StorageSQLite, StorageMemory)
+	if !c.Storage.Memory() && !c.Storage.SQLite() && c.Postgres.URL == "" {
+		fieldErr("postgres.url", "is required")
+	}
+	if c.Storage.SQLite() && c.SQLite.Path == "" {
+		fieldErr("sqlite.path", "is required with the sqlite storage")
+	}
+	if c.Storage.Mongo() {
+		if c.Mongo.URI == "" {
+			fieldErr("mongo.uri", "is required")
+		}
+		if !c.Redis.Enabled {
+			fieldErr("redis.enabled", "must be true with the databases storage")
+		}
+	}
+	if !c.Storage.Memory() && c.Redis.Enabled && c.Redis.Addr == "" {
+		fieldErr("redis.addr", "is required")
+	}
+	if c.Redis.DB < 0 {
+		fieldErr("redis.db", "must not be negative")
+	}
+	if c.Auth.SecretKey == "" {
+		fieldErr("auth.secret_key", "is required")
+	}
+	oneOf("search.backend", c.Search.Backend, "memory", "postgres")
+	if (c.Storage.Memory() || c.Storage.SQLite()) && c.Search.Backend != "memory" {
+		fieldErr("search.backend", "must be memory with the "+c.Storage.Backend+" storage")
+	}
+	if !c.Storage.Mongo() && c.Locales.List != "" {
+		fieldErr("locales.list", "is only supported with the databases storage")
+	}
+	oneOf("media.blob_store", c.Media.BlobStore, "local", "s3")
+	if c.Media.BlobStore == "s3" {
+		if c.Media.S3.Endpoint == "" {
+			fieldErr("media.s3.endpoint", "is required with the s3 blob store")
+		}
+		if c.Media.S3.Bucket == "" {
+			fieldErr("media.s3.bucket", "is required with the s3 blob store")
+		}
+	}
+	if c.Media.MaxSize < 0 {
+		fieldErr("media.max_size", "must not be negative")
+	}
+	if c.Media.MaxPixels < 0 {
+		fieldErr("media.max_pixels", "must not be negative")
+	}
+	oneOf("stream.backend", c.Stream.Backend, "redis", "memory")
+	if c.Feeds.BaseURL != "" {
+		if u, err := url.Parse(c.Feeds.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fieldErr("feeds.base_url", "must be an absolute URL")
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// ValidationError agrupa todos los problemas encontrados por Validate
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = "  " + problem.Error()
+	}
+	return "invalid configuration:\n" + strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// Addr es la dirección en la que escucha el servidor HTTP
+func (s Server) Addr() string {
+	return ":" + strconv.Itoa(s.Port)
+}
+
+const redacted = "[redacted]"
+
+// Redacted devuelve una copia con los secretos ocultos, apta para imprimir o registrar
+func (c *Config) Redacted() *Config {
+	out := *c
+	for _, f := range out.fields() {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString(redacted)
+		}
+	}
+	return &out
+}
+
+// YAML serializa la configuración; para mostrarla usar antes Redacted
+func (c *Config) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), encoder.Close()
+}
+
+// --- Recorrido de los campos por reflexión ---
+
+type field struct {
+	path   string
+	env    []string
+	flag   string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+func (c *Config) fields() []field {
+	return collectFields(reflect.ValueOf(c).Elem(), "")
+}
+
+func collectFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path := prefix + strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(v.Field(i), path+".")...)
+			continue
+		}
+		f := field{
+			path:   path,
+			flag:   sf.Tag.Get("flag"),
+			usage:  sf.Tag.Get("usage"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		}
+		if env := sf.Tag.Get("env"); env != "" {
+			f.env = strings.Split(env, ",")
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (f field) set(raw string) error {
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(raw)
+	case f.value.Kind() == reflect.Int || f.value.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		f.value.SetInt(n)
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// display es el valor por defecto que muestra -help; los secretos no se muestran
+func (f field) display() string {
+	if f.secret {
+		return ""
+	}
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f field) sources() string {
+	var sources []string
+	if len(f.env) > 0 {
+		sources = append(sources, "env "+f.env[0])
+	}
+	if f.flag != "" {
+		sources = append(sources, "flag -"+f.flag)
+	}
+	return strings.Join(append(sources, f.path+" in the config file"), ", ")
+}
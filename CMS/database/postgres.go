@@ -1,28 +1,50 @@
-package database
-
-import (
-	"JSanches/CMD/models"
-	"log"
-	"os"
-
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-)
-
-var DBConn *gorm.DB
-
-func PostgresInit() {
-	db, err := gorm.Open(postgres.Open(os.Getenv("DB_URL")), &gorm.Config{})
-	if err != nil {
-		log.Fatal("Error connecting to postgres database: ", err)
-	}
-
-	err = db.AutoMigrate(models.User{})
-	if err != nil {
-		log.Fatal("Error migrating database: ", err)
-	}
-}
-
-func PostgresGetDB() *gorm.DB {
-	return DBConn
-}
+package database
+
+import (
+	"context"
+	"log"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var DBConn *gorm.DB
+
+// PostgresInit abre la conexión; el esquema lo gestiona el paquete migrations.
+// Con TranslateError las violaciones de unicidad llegan como gorm.ErrDuplicatedKey.
+func PostgresInit(url string) {
+	var db *gorm.DB
+	err := connectWithRetry("postgres", func(ctx context.Context) error {
+		var err error
+		if db, err = gorm.Open(postgres.Open(url), &gorm.Config{TranslateError: true}); err != nil {
+			return err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	if err != nil {
+		log.Fatal("Error connecting to postgres database: ", err)
+	}
+
+	DBConn = db
+	log.Println("Connected to postgres database")
+}
+
+func PostgresGetDB() *gorm.DB {
+	return DBConn
+}
+
+// PostgresClose cierra el pool de conexiones
+func PostgresClose() error {
+	if DBConn == nil {
+		return nil
+	}
+	sqlDB, err := DBConn.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
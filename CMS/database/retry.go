@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ConnectRetries es el número de intentos de conexión a cada base de datos
+var ConnectRetries = 5
+
+// connectWithRetry reintenta connect con espera exponencial (1s, 2s, 4s...) para
+// que el servicio pueda arrancar antes que sus bases de datos
+func connectWithRetry(name string, connect func(ctx context.Context) error) error {
+	attempts := ConnectRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = connect(ctx)
+		cancel()
+		if err == nil || attempt == attempts {
+			return err
+		}
+		log.Printf("Error connecting to %s (attempt %d/%d), retrying in %s: %v", name, attempt, attempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
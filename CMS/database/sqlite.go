@@ -0,0 +1,41 @@
+package database
+
+import (
+	"log"
+	"net/url"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// SQLiteInit abre el archivo de SQLite en DBConn, así que los clientes de GORM
+// lo usan igual que a Postgres. El esquema lo gestiona el paquete migrations.
+func SQLiteInit(path string) {
+	db, err := SQLiteOpen(path)
+	if err != nil {
+		log.Fatal("Error opening sqlite database: ", err)
+	}
+	DBConn = db
+	log.Println("Opened sqlite database", path)
+}
+
+// SQLiteOpen abre path con WAL, espera de hasta 5s si el archivo está bloqueado y
+// claves foráneas activas. SQLite admite un solo escritor, así que el pool se
+// limita a una conexión para que las transacciones no se bloqueen entre sí. Los
+// errores se traducen a los de GORM, como en PostgresInit.
+func SQLiteOpen(path string) (*gorm.DB, error) {
+	pragmas := url.Values{}
+	for _, pragma := range []string{"busy_timeout(5000)", "journal_mode(WAL)", "foreign_keys(1)"} {
+		pragmas.Add("_pragma", pragma)
+	}
+	db, err := gorm.Open(sqlite.Open(path+"?"+pragmas.Encode()), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return db, sqlDB.Ping()
+}
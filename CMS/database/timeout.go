@@ -0,0 +1,20 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// OperationTimeout es el plazo de cada operación contra Postgres, SQLite, Mongo o
+// Redis. Los clientes de services lo aplican sobre el contexto que reciben, que
+// en los handlers es el de la petición; 0 deja solo el plazo de ese contexto.
+var OperationTimeout = 5 * time.Second
+
+// WithTimeout deriva de ctx el contexto de una operación. Si ctx vence antes o
+// se cancela, la operación se cancela con él.
+func WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if OperationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, OperationTimeout)
+}
@@ -0,0 +1,322 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Migraciones SQL versionadas ---
+
+//go:embed postgres/*.sql
+var postgresFiles embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// Migration es un par de scripts NNNN_nombre.up.sql / NNNN_nombre.down.sql.
+// Down vacío indica que la migración no se puede deshacer.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifica el script up; si cambia después de aplicarlo, Up falla
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load lee las migraciones de dir, ordenadas por versión
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Postgres devuelve las migraciones de Postgres incluidas en el binario
+func Postgres() ([]Migration, error) {
+	return Load(postgresFiles, "postgres")
+}
+
+// SQLite devuelve las migraciones de SQLite incluidas en el binario. Siguen la
+// misma numeración que las de Postgres.
+func SQLite() ([]Migration, error) {
+	return Load(sqliteFiles, "sqlite")
+}
+
+// Dialect es la base de datos sobre la que trabaja un Migrator
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// Status es el estado de una migración en la base de datos
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified indica que el script cambió después de aplicarse
+	Modified bool `json:"modified,omitempty"`
+	// Unknown indica una versión aplicada que este binario no conoce
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Migrator aplica las migraciones en orden, cada una en su transacción, y las
+// registra en schema_migrations. En Postgres un advisory lock evita que dos
+// instancias que arrancan a la vez migren al mismo tiempo; SQLite no tiene
+// locks de sesión, pero solo admite un escritor y la clave primaria de
+// schema_migrations hace fallar al segundo que intente aplicar la misma versión.
+type Migrator struct {
+	DB         *sql.DB
+	Dialect    Dialect
+	Migrations []Migration
+	LockID     int64
+}
+
+// migrationsLockID es un número arbitrario que identifica el lock de migraciones
+const migrationsLockID = 7243016945
+
+func NewPostgresMigrator(db *sql.DB) (*Migrator, error) {
+	list, err := Postgres()
+	if err != nil {
+		return nil, fmt.Errorf("loading postgres migrations: %w", err)
+	}
+	return &Migrator{DB: db, Dialect: DialectPostgres, Migrations: list, LockID: migrationsLockID}, nil
+}
+
+func NewSQLiteMigrator(db *sql.DB) (*Migrator, error) {
+	list, err := SQLite()
+	if err != nil {
+		return nil, fmt.Errorf("loading sqlite migrations: %w", err)
+	}
+	return &Migrator{DB: db, Dialect: DialectSQLite, Migrations: list}, nil
+}
+
+// NewMigrator elige las migraciones según el dialecto
+func NewMigrator(dialect Dialect, db *sql.DB) (*Migrator, error) {
+	switch dialect {
+	case DialectPostgres:
+		return NewPostgresMigrator(db)
+	case DialectSQLite:
+		return NewSQLiteMigrator(db)
+	default:
+		return nil, fmt.Errorf("unsupported migrations dialect %q", dialect)
+	}
+}
+
+var placeholder = regexp.MustCompile(`\$\d+`)
+
+// bind adapta los parámetros $1, $2... de Postgres al dialecto
+func (m *Migrator) bind(query string) string {
+	if m.Dialect == DialectSQLite {
+		return placeholder.ReplaceAllString(query, "?")
+	}
+	return query
+}
+
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Up aplica las migraciones pendientes y devuelve las aplicadas
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		for _, migration := range m.Migrations {
+			if record, ok := applied[migration.Version]; ok {
+				if record.Checksum != migration.Checksum() {
+					return fmt.Errorf("migration %d_%s was modified after being applied", migration.Version, migration.Name)
+				}
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, m.bind(`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`),
+					migration.Version, migration.Name, migration.Checksum())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down deshace las últimas steps migraciones aplicadas, de la más reciente a la más antigua
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, m.bind(`DELETE FROM schema_migrations WHERE version = $1`), migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status compara las migraciones conocidas con las aplicadas
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		known := map[int64]bool{}
+		for _, migration := range m.Migrations {
+			known[migration.Version] = true
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if record, ok := applied[migration.Version]; ok {
+				appliedAt := record.AppliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+				status.Modified = record.Checksum != migration.Checksum()
+			}
+			statuses = append(statuses, status)
+		}
+		for version, record := range applied {
+			if !known[version] {
+				appliedAt := record.AppliedAt
+				statuses = append(statuses, Status{Version: version, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+			}
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
+
+// locked toma el advisory lock en una conexión dedicada, asegura la tabla
+// schema_migrations y llama a fn con las versiones ya aplicadas
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]appliedMigration) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	createTable := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		checksum   TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+	if m.Dialect == DialectSQLite {
+		createTable = strings.NewReplacer("TIMESTAMPTZ", "DATETIME", "now()", "CURRENT_TIMESTAMP").Replace(createTable)
+	} else {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, m.LockID); err != nil {
+			return fmt.Errorf("acquiring migrations lock: %w", err)
+		}
+		// El lock es de sesión: se libera aunque ctx ya esté cancelado
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, m.LockID)
+	}
+
+	if _, err = conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.Checksum, &record.AppliedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = record
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Índices de Mongo ---
+
+// MongoIndex es un índice que debe existir. Crear un índice que ya existe con la
+// misma definición no hace nada, así que se pueden asegurar en cada arranque.
+type MongoIndex struct {
+	Database   string
+	Collection string
+	Model      mongo.IndexModel
+}
+
+func (i MongoIndex) Name() string {
+	return *i.Model.Options.Name
+}
+
+var MongoIndexes = []MongoIndex{
+	{
+		Database:   "contents",
+		Collection: "contents",
+		Model: mongo.IndexModel{
+			Keys:    bson.D{{Key: "content_id", Value: 1}},
+			Options: options.Index().SetName("content_id_unique").SetUnique(true),
+		},
+	},
+	{
+		Database:   "contents",
+		Collection: "translations",
+		Model: mongo.IndexModel{
+			Keys:    bson.D{{Key: "content_id", Value: 1}, {Key: "locale", Value: 1}},
+			Options: options.Index().SetName("content_id_locale_unique").SetUnique(true),
+		},
+	},
+	{
+		Database:   "contents",
+		Collection: "entries",
+		Model: mongo.IndexModel{
+			Keys:    bson.D{{Key: "type", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("type_created_at"),
+		},
+	},
+}
+
+// EnsureMongoIndexes crea los índices que falten
+func EnsureMongoIndexes(ctx context.Context, client *mongo.Client) error {
+	for _, index := range MongoIndexes {
+		collection := client.Database(index.Database).Collection(index.Collection)
+		if _, err := collection.Indexes().CreateOne(ctx, index.Model); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("creating index %s on %s.%s: duplicated documents, run check-consistency first: %w", index.Name(), index.Database, index.Collection, err)
+			}
+			return fmt.Errorf("creating index %s on %s.%s: %w", index.Name(), index.Database, index.Collection, err)
+		}
+	}
+	return nil
+}
+
+type MongoIndexStatus struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+	Name       string `json:"name"`
+	Exists     bool   `json:"exists"`
+}
+
+const namespaceNotFound = 26
+
+// MongoStatus indica qué índices existen ya
+func MongoStatus(ctx context.Context, client *mongo.Client) ([]MongoIndexStatus, error) {
+	statuses := make([]MongoIndexStatus, 0, len(MongoIndexes))
+	for _, index := range MongoIndexes {
+		var existing []struct {
+			Name string `bson:"name"`
+		}
+		cursor, err := client.Database(index.Database).Collection(index.Collection).Indexes().List(ctx)
+		var cmdErr mongo.CommandError
+		switch {
+		case errors.As(err, &cmdErr) && cmdErr.Code == namespaceNotFound:
+			// La colección aún no existe: ningún índice creado
+		case err != nil:
+			return nil, err
+		default:
+			if err := cursor.All(ctx, &existing); err != nil {
+				return nil, err
+			}
+		}
+		status := MongoIndexStatus{Database: index.Database, Collection: index.Collection, Name: index.Name()}
+		for _, e := range existing {
+			if e.Name == status.Name {
+				status.Exists = true
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
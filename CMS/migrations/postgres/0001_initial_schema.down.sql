@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS media;
+DROP TABLE IF EXISTS content_types;
+DROP TABLE IF EXISTS slug_redirects;
+DROP TABLE IF EXISTS content_tags;
+DROP TABLE IF EXISTS contents;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,136 @@
+-- Esquema que antes creaba AutoMigrate. IF NOT EXISTS permite adoptar bases ya
+-- migradas con AutoMigrate; los nombres de índices y claves son los de GORM.
+
+CREATE TABLE IF NOT EXISTS users (
+    id                  BIGSERIAL PRIMARY KEY,
+    username            TEXT NOT NULL CONSTRAINT uni_users_username UNIQUE,
+    email               TEXT NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    password            TEXT,
+    is_active           BOOLEAN DEFAULT true,
+    is_banned           BOOLEAN DEFAULT false,
+    failed_attempts     BIGINT DEFAULT 0,
+    last_failed_attempt TIMESTAMPTZ,
+    lockout_until       TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_slug ON tags (slug);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    parent_id  BIGINT,
+    position   BIGINT DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_slug ON categories (slug);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS contents (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    title        TEXT,
+    slug         TEXT,
+    description  TEXT,
+    locale       VARCHAR(16),
+    published_at TIMESTAMPTZ,
+    user_id      BIGINT,
+    category_id  BIGINT CONSTRAINT fk_contents_category REFERENCES categories (id)
+);
+CREATE INDEX IF NOT EXISTS idx_contents_deleted_at ON contents (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contents_slug ON contents (slug) WHERE slug <> '';
+CREATE INDEX IF NOT EXISTS idx_contents_published_at ON contents (published_at);
+CREATE INDEX IF NOT EXISTS idx_contents_category_id ON contents (category_id);
+
+CREATE TABLE IF NOT EXISTS content_tags (
+    content_id BIGINT NOT NULL CONSTRAINT fk_content_tags_content REFERENCES contents (id),
+    tag_id     BIGINT NOT NULL CONSTRAINT fk_content_tags_tag REFERENCES tags (id),
+    PRIMARY KEY (content_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS slug_redirects (
+    id         BIGSERIAL PRIMARY KEY,
+    old_slug   TEXT NOT NULL,
+    content_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_slug_redirects_old_slug ON slug_redirects (old_slug);
+CREATE INDEX IF NOT EXISTS idx_slug_redirects_content_id ON slug_redirects (content_id);
+
+CREATE TABLE IF NOT EXISTS content_types (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    slug        TEXT NOT NULL,
+    description TEXT,
+    fields      JSONB,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_types_slug ON content_types (slug);
+
+CREATE TABLE IF NOT EXISTS media (
+    id          BIGSERIAL PRIMARY KEY,
+    filename    TEXT NOT NULL,
+    mime_type   TEXT NOT NULL,
+    size        BIGINT,
+    sha256      VARCHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    width       BIGINT,
+    height      BIGINT,
+    user_id     BIGINT,
+    created_at  TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_sha256 ON media (sha256);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              BIGSERIAL PRIMARY KEY,
+    content_id      BIGINT NOT NULL,
+    operation       TEXT NOT NULL,
+    payload         JSONB,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        BIGINT,
+    next_attempt_at TIMESTAMPTZ,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ,
+    processed_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_content_id ON outbox_events (content_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_status_next ON outbox_events (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         BIGSERIAL PRIMARY KEY,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    events     JSONB,
+    active     BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      BIGINT NOT NULL,
+    event_id        TEXT NOT NULL,
+    event           TEXT NOT NULL,
+    payload         TEXT,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        BIGINT,
+    next_attempt_at TIMESTAMPTZ,
+    response_code   BIGINT,
+    response_body   TEXT,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ,
+    delivered_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_delivery_status_next ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,2 @@
+-- Rol de cada usuario: admin, editor o author
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'author';
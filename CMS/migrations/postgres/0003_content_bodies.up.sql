@@ -0,0 +1,6 @@
+-- Cuerpos de los contenidos en Postgres para el almacenamiento "postgres", que
+-- no usa Mongo. doc tiene la misma forma que el documento de Mongo.
+CREATE TABLE IF NOT EXISTS content_bodies (
+    content_id BIGINT PRIMARY KEY,
+    doc        JSONB NOT NULL
+);
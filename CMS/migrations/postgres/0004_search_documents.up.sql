@@ -0,0 +1,12 @@
+-- Índice de búsqueda del backend "postgres". tsv se calcula en la aplicación a
+-- partir del título y el cuerpo sin acentos. IF NOT EXISTS adopta la tabla que
+-- antes se creaba al arrancar.
+CREATE TABLE IF NOT EXISTS search_documents (
+    content_id BIGINT PRIMARY KEY,
+    title      TEXT NOT NULL DEFAULT '',
+    body       TEXT NOT NULL DEFAULT '',
+    user_id    BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    tsv        TSVECTOR NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_search_documents_tsv ON search_documents USING GIN (tsv);
@@ -0,0 +1,136 @@
+-- Mismo esquema que migrations/postgres con los tipos de SQLite: los JSONB son
+-- TEXT con JSON y AUTOINCREMENT evita reutilizar ids como las secuencias.
+
+CREATE TABLE IF NOT EXISTS users (
+    id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+    username            TEXT NOT NULL CONSTRAINT uni_users_username UNIQUE,
+    email               TEXT NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    password            TEXT,
+    is_active           BOOLEAN DEFAULT true,
+    is_banned           BOOLEAN DEFAULT false,
+    failed_attempts     INTEGER DEFAULT 0,
+    last_failed_attempt DATETIME,
+    lockout_until       DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    created_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_slug ON tags (slug);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    parent_id  INTEGER,
+    position   INTEGER DEFAULT 0,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_slug ON categories (slug);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS contents (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at   DATETIME,
+    updated_at   DATETIME,
+    deleted_at   DATETIME,
+    title        TEXT,
+    slug         TEXT,
+    description  TEXT,
+    locale       VARCHAR(16),
+    published_at DATETIME,
+    user_id      INTEGER,
+    category_id  INTEGER CONSTRAINT fk_contents_category REFERENCES categories (id)
+);
+CREATE INDEX IF NOT EXISTS idx_contents_deleted_at ON contents (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contents_slug ON contents (slug) WHERE slug <> '';
+CREATE INDEX IF NOT EXISTS idx_contents_published_at ON contents (published_at);
+CREATE INDEX IF NOT EXISTS idx_contents_category_id ON contents (category_id);
+
+CREATE TABLE IF NOT EXISTS content_tags (
+    content_id INTEGER NOT NULL CONSTRAINT fk_content_tags_content REFERENCES contents (id),
+    tag_id     INTEGER NOT NULL CONSTRAINT fk_content_tags_tag REFERENCES tags (id),
+    PRIMARY KEY (content_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS slug_redirects (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    old_slug   TEXT NOT NULL,
+    content_id INTEGER NOT NULL,
+    created_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_slug_redirects_old_slug ON slug_redirects (old_slug);
+CREATE INDEX IF NOT EXISTS idx_slug_redirects_content_id ON slug_redirects (content_id);
+
+CREATE TABLE IF NOT EXISTS content_types (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        TEXT NOT NULL,
+    slug        TEXT NOT NULL,
+    description TEXT,
+    fields      TEXT,
+    created_at  DATETIME,
+    updated_at  DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_types_slug ON content_types (slug);
+
+CREATE TABLE IF NOT EXISTS media (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    filename    TEXT NOT NULL,
+    mime_type   TEXT NOT NULL,
+    size        INTEGER,
+    sha256      VARCHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    width       INTEGER,
+    height      INTEGER,
+    user_id     INTEGER,
+    created_at  DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_sha256 ON media (sha256);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    content_id      INTEGER NOT NULL,
+    operation       TEXT NOT NULL,
+    payload         TEXT,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER,
+    next_attempt_at DATETIME,
+    last_error      TEXT,
+    created_at      DATETIME,
+    processed_at    DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_content_id ON outbox_events (content_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_status_next ON outbox_events (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    events     TEXT,
+    active     BOOLEAN DEFAULT true,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id      INTEGER NOT NULL,
+    event_id        TEXT NOT NULL,
+    event           TEXT NOT NULL,
+    payload         TEXT,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER,
+    next_attempt_at DATETIME,
+    response_code   INTEGER,
+    response_body   TEXT,
+    last_error      TEXT,
+    created_at      DATETIME,
+    delivered_at    DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_delivery_status_next ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,2 @@
+-- Rol de cada usuario: admin, editor o author
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'author';
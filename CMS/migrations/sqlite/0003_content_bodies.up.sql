@@ -0,0 +1,6 @@
+-- Cuerpos de los contenidos para el almacenamiento "sqlite". doc es el JSON del
+-- documento que iría a Mongo.
+CREATE TABLE IF NOT EXISTS content_bodies (
+    content_id INTEGER PRIMARY KEY,
+    doc        TEXT NOT NULL CHECK (json_valid(doc))
+);
@@ -0,0 +1,2 @@
+-- Sin cambios, como el script up
+SELECT 1;
@@ -0,0 +1,3 @@
+-- Sin cambios: con SQLite la búsqueda usa el índice en memoria. La migración
+-- existe para mantener la numeración de Postgres.
+SELECT 1;
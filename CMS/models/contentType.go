@@ -0,0 +1,85 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FieldType string
+
+const (
+	FieldText      FieldType = "text"
+	FieldRichText  FieldType = "richtext"
+	FieldNumber    FieldType = "number"
+	FieldBoolean   FieldType = "boolean"
+	FieldDate      FieldType = "date"
+	FieldEnum      FieldType = "enum"
+	FieldReference FieldType = "reference"
+	FieldMedia     FieldType = "media"
+	FieldJSON      FieldType = "json"
+)
+
+// FieldDefinition describe un campo de un tipo de contenido y sus reglas de validación
+type FieldDefinition struct {
+	Name      string    `json:"name"`
+	Label     string    `json:"label,omitempty"`
+	Type      FieldType `json:"type"`
+	Required  bool      `json:"required,omitempty"`
+	MinLength *int      `json:"min_length,omitempty"`
+	MaxLength *int      `json:"max_length,omitempty"`
+	Pattern   string    `json:"pattern,omitempty"`
+	Min       *float64  `json:"min,omitempty"`
+	Max       *float64  `json:"max,omitempty"`
+	Integer   bool      `json:"integer,omitempty"`
+	Options   []string  `json:"options,omitempty"`
+	// RefType es el slug del tipo al que apunta un campo reference
+	RefType string `json:"ref_type,omitempty"`
+}
+
+// FieldDefinitions se guarda como JSONB en Postgres
+type FieldDefinitions []FieldDefinition
+
+func (f FieldDefinitions) Value() (driver.Value, error) {
+	return json.Marshal(f)
+}
+
+func (f *FieldDefinitions) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*f = nil
+		return nil
+	default:
+		return errors.New("invalid field definitions")
+	}
+	return json.Unmarshal(data, f)
+}
+
+// ContentType es un tipo de contenido definido por un administrador
+type ContentType struct {
+	ID          uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string           `json:"name" gorm:"not null"`
+	Slug        string           `json:"slug" gorm:"uniqueIndex;not null"`
+	Description string           `json:"description"`
+	Fields      FieldDefinitions `json:"fields" gorm:"type:jsonb"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// Entry es una instancia de un tipo de contenido, guardada en Mongo
+type Entry struct {
+	ID        primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	Type      string                 `json:"type" bson:"type"`
+	Data      map[string]interface{} `json:"data" bson:"data"`
+	UserID    int                    `json:"user_id" bson:"user_id"`
+	CreatedAt time.Time              `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at" bson:"updated_at"`
+}
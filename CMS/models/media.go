@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Media es un archivo subido a la biblioteca. El binario vive en el BlobStore
+// bajo StorageKey; SHA256 permite detectar subidas duplicadas.
+type Media struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Filename   string    `json:"filename" gorm:"not null"`
+	MimeType   string    `json:"mime_type" gorm:"not null"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256" gorm:"uniqueIndex;size:64;not null"`
+	StorageKey string    `json:"-" gorm:"not null"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	UserID     int       `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxDone    = "done"
+	OutboxDead    = "dead"
+)
+
+// OutboxEvent es un cambio pendiente de aplicar en Mongo. Se inserta en la misma
+// transacción que la fila de Postgres, así que si existe la fila existe el evento.
+type OutboxEvent struct {
+	ID            uint          `json:"id" gorm:"primaryKey;autoIncrement"`
+	ContentID     uint          `json:"content_id" gorm:"index;not null"`
+	Operation     string        `json:"operation" gorm:"not null"`
+	Payload       OutboxPayload `json:"payload" gorm:"type:jsonb"`
+	Status        string        `json:"status" gorm:"index:idx_outbox_status_next;not null;default:pending"`
+	Attempts      int           `json:"attempts"`
+	NextAttemptAt time.Time     `json:"next_attempt_at" gorm:"index:idx_outbox_status_next"`
+	LastError     string        `json:"last_error"`
+	CreatedAt     time.Time     `json:"created_at"`
+	ProcessedAt   *time.Time    `json:"processed_at"`
+}
+
+// OutboxPayload lleva el cuerpo a escribir; el HTML se vuelve a renderizar al aplicarlo
+type OutboxPayload struct {
+	Body   string `json:"body,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+func (p OutboxPayload) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+func (p *OutboxPayload) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*p = OutboxPayload{}
+		return nil
+	default:
+		return errors.New("invalid outbox payload")
+	}
+	return json.Unmarshal(data, p)
+}
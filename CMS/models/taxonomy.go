@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Tag es una etiqueta libre; Slug es la forma normalizada y única del nombre
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Category es un nodo del árbol de categorías. Position ordena a los hermanos.
+type Category struct {
+	ID        uint        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name      string      `json:"name" gorm:"not null"`
+	Slug      string      `json:"slug" gorm:"uniqueIndex;not null"`
+	ParentID  *uint       `json:"parent_id" gorm:"index"`
+	Position  int         `json:"position" gorm:"default:0"`
+	Children  []*Category `json:"children,omitempty" gorm:"-"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
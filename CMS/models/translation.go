@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ContentTranslation es la variante de un contenido en otro idioma. Se guarda en
+// Mongo junto a los cuerpos; el original sigue siendo Content + ContentBody en
+// Content.Locale.
+type ContentTranslation struct {
+	ContentID   uint          `bson:"content_id" json:"content_id"`
+	Locale      string        `bson:"locale" json:"locale"`
+	Title       string        `bson:"title" json:"title"`
+	Description string        `bson:"description" json:"description"`
+	Body        string        `bson:"body" json:"body"`
+	Format      string        `bson:"format" json:"format"`
+	Rendered    *RenderedBody `bson:"rendered,omitempty" json:"-"`
+	UpdatedAt   time.Time     `bson:"updated_at" json:"updated_at"`
+}
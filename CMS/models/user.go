@@ -1,31 +1,64 @@
-package models
-
-import (
-	"time"
-
-	"gorm.io/gorm"
-)
-
-type User struct {
-	ID                uint       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Username          string     `json:"username" gorm:"unique;not null"`
-	Email             string     `json:"email" gorm:"unique;not null"`
-	Password          string     `json:"-"`
-	IsActive          bool       `json:"is_active" gorm:"default:true"`
-	IsBanned          bool       `json:"is_banned" gorm:"default:false"`
-	FailedAttempts    int        `json:"failed_attempts" gorm:"default:0"`
-	LastFailedAttempt time.Time  `json:"last_failed_attempt,omitempty"`
-	LockoutUntil      *time.Time `json:"lockout_until,omitempty"`
-}
-
-type Content struct {
-	gorm.Model
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	UserID      int    `json:"user_id"`
-}
-
-type ContentBody struct {
-	ContentID uint   `bson:"content_id"`
-	Body      string `bson:"body"`
-}
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	ID                uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Username          string     `json:"username" gorm:"unique;not null"`
+	Email             string     `json:"email" gorm:"unique;not null"`
+	Password          string     `json:"-"`
+	IsActive          bool       `json:"is_active" gorm:"default:true"`
+	IsBanned          bool       `json:"is_banned" gorm:"default:false"`
+	Role              string     `json:"role" gorm:"size:16;not null;default:author"`
+	FailedAttempts    int        `json:"failed_attempts" gorm:"default:0"`
+	LastFailedAttempt time.Time  `json:"last_failed_attempt,omitempty"`
+	LockoutUntil      *time.Time `json:"lockout_until,omitempty"`
+}
+
+type Content struct {
+	gorm.Model
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug" gorm:"index:idx_contents_slug,unique,where:slug <> ''"`
+	Description string     `json:"description"`
+	Locale      string     `json:"locale" gorm:"size:16"`
+	PublishedAt *time.Time `json:"published_at" gorm:"index"`
+	UserID      int        `json:"user_id"`
+	CategoryID  *uint      `json:"category_id" gorm:"index"`
+	Category    *Category  `json:"category,omitempty"`
+	Tags        []Tag      `json:"tags,omitempty" gorm:"many2many:content_tags;"`
+}
+
+type ContentBody struct {
+	ContentID uint          `bson:"content_id"`
+	Body      string        `bson:"body"`
+	Format    string        `bson:"format,omitempty"`
+	Rendered  *RenderedBody `bson:"rendered,omitempty"`
+}
+
+// RenderedBody es el HTML ya saneado de un cuerpo. SourceHash identifica el
+// cuerpo y el formato con los que se generó, para saber cuándo recalcularlo.
+type RenderedBody struct {
+	HTML        string     `bson:"html" json:"html"`
+	TOC         []TOCEntry `bson:"toc" json:"toc"`
+	WordCount   int        `bson:"word_count" json:"word_count"`
+	ReadingTime int        `bson:"reading_time" json:"reading_time"`
+	SourceHash  string     `bson:"source_hash" json:"-"`
+}
+
+type TOCEntry struct {
+	Level int    `bson:"level" json:"level"`
+	Text  string `bson:"text" json:"text"`
+	ID    string `bson:"id" json:"id"`
+}
+
+// SlugRedirect guarda un slug anterior para redirigir al actual con un 301
+type SlugRedirect struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	OldSlug   string    `json:"old_slug" gorm:"uniqueIndex;not null"`
+	ContentID uint      `json:"content_id" gorm:"index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// StringList se guarda como JSONB en Postgres
+type StringList []string
+
+func (s StringList) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*s = nil
+		return nil
+	default:
+		return errors.New("invalid string list")
+	}
+	return json.Unmarshal(data, s)
+}
+
+// Webhook es un endpoint externo suscrito a eventos de contenido. El secreto
+// firma cada entrega y solo se muestra al crearlo.
+type Webhook struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	URL       string     `json:"url" gorm:"not null"`
+	Secret    string     `json:"-" gorm:"not null"`
+	Events    StringList `json:"events" gorm:"type:jsonb"`
+	Active    bool       `json:"active" gorm:"default:true"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery es un intento de entrega de un evento a un webhook
+type WebhookDelivery struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	WebhookID     uint       `json:"webhook_id" gorm:"index;not null"`
+	EventID       string     `json:"event_id" gorm:"index;not null"`
+	Event         string     `json:"event" gorm:"not null"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"index:idx_delivery_status_next;not null;default:pending"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_delivery_status_next"`
+	ResponseCode  int        `json:"response_code"`
+	ResponseBody  string     `json:"response_body"`
+	LastError     string     `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+}
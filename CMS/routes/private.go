@@ -1,15 +1,86 @@
-package routes
-
-import (
-	"JSanches/CMD/services"
-
-	"github.com/gofiber/fiber/v2"
-)
-
-func RegisterContentRoutes(router fiber.Router, ch *services.ContentHandler) {
-	router.Post("/", ch.CreateContent)
-	router.Get("/", ch.ListContents)
-	router.Get("/:id", ch.GetContent)
-	router.Put("/:id", ch.UpdateContent)
-	router.Delete("/:id", ch.DeleteContent)
-}
+package routes
+
+import (
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterContentRoutes(router fiber.Router, ch *services.ContentHandler) {
+	router.Post("/", ch.CreateContent)
+	router.Get("/", ch.ListContents)
+	router.Get("/search", ch.SearchContents)
+	router.Get("/by-slug/:slug", ch.GetContentBySlug)
+	router.Get("/:id<int>", ch.GetContent)
+	router.Put("/:id<int>", ch.UpdateContent)
+	router.Delete("/:id<int>", ch.DeleteContent)
+	router.Get("/:id<int>/translations", ch.ListTranslations)
+	router.Get("/:id<int>/translations/missing", ch.MissingTranslations)
+	router.Put("/:id<int>/translations/:locale", ch.SaveTranslation)
+	router.Delete("/:id<int>/translations/:locale", ch.DeleteTranslation)
+}
+
+// RegisterStreamRoutes expone /collection/stream; como RegisterContentRoutes, va antes que RegisterEntryRoutes
+func RegisterStreamRoutes(router fiber.Router, sh *services.StreamHandler) {
+	router.Get("/stream", sh.StreamContents)
+}
+
+// RegisterEntryRoutes expone el CRUD de cada tipo dinámico en /collection/:type.
+// Debe registrarse después de RegisterContentRoutes para que /:id<int> tenga prioridad.
+func RegisterEntryRoutes(router fiber.Router, th *services.ContentTypeHandler) {
+	router.Get("/:type", th.ListEntries)
+	router.Post("/:type", th.CreateEntry)
+	router.Get("/:type/:id", th.GetEntry)
+	router.Put("/:type/:id", th.UpdateEntry)
+	router.Delete("/:type/:id", th.DeleteEntry)
+}
+
+func RegisterContentTypeRoutes(router fiber.Router, th *services.ContentTypeHandler) {
+	router.Get("/", th.ListContentTypes)
+	router.Post("/", th.CreateContentType)
+	router.Get("/:type", th.GetContentType)
+	router.Put("/:type", th.UpdateContentType)
+	router.Delete("/:type", th.DeleteContentType)
+}
+
+func RegisterTaxonomyRoutes(router fiber.Router, th *services.TaxonomyHandler) {
+	router.Get("/tags", th.ListTags)
+	router.Post("/tags", th.CreateTag)
+	router.Put("/tags/:id", th.UpdateTag)
+	router.Delete("/tags/:id", th.DeleteTag)
+
+	router.Get("/categories", th.ListCategories)
+	router.Post("/categories", th.CreateCategory)
+	router.Get("/categories/:id", th.GetCategory)
+	router.Put("/categories/:id", th.UpdateCategory)
+	router.Delete("/categories/:id", th.DeleteCategory)
+}
+
+func RegisterMediaRoutes(router fiber.Router, mh *services.MediaHandler) {
+	router.Get("/", mh.ListMedia)
+	router.Post("/", utils.BodyLimit(mh.UploadBodyLimit(), nil), mh.UploadMedia)
+	router.Get("/:id<int>/meta", mh.GetMediaInfo)
+	router.Get("/:id<int>/url", mh.SignMediaURL)
+	router.Delete("/:id<int>", mh.DeleteMedia)
+}
+
+func RegisterOutboxRoutes(router fiber.Router, oh *services.OutboxHandler) {
+	router.Get("/", oh.ListEvents)
+	router.Post("/:id<int>/retry", oh.RetryEvent)
+}
+
+func RegisterConsistencyRoutes(router fiber.Router, ch *services.ConsistencyHandler) {
+	router.Get("/", ch.CheckConsistency)
+	router.Post("/repair", ch.RepairConsistency)
+}
+
+func RegisterWebhookRoutes(router fiber.Router, wh *services.WebhookHandler) {
+	router.Get("/", wh.ListWebhooks)
+	router.Post("/", wh.CreateWebhook)
+	router.Get("/:id<int>", wh.GetWebhook)
+	router.Put("/:id<int>", wh.UpdateWebhook)
+	router.Delete("/:id<int>", wh.DeleteWebhook)
+	router.Get("/:id<int>/deliveries", wh.ListDeliveries)
+	router.Post("/deliveries/:deliveryId<int>/redeliver", wh.Redeliver)
+}
@@ -1,12 +1,30 @@
-package routes
-
-import (
-	"JSanches/CMD/services"
-
-	"github.com/gofiber/fiber/v2"
-)
-
-func PublicRoutes(app *fiber.App, handler *services.Handler) {
-	app.Post("/register", handler.RegisterHandler)
-	app.Post("/login", handler.LoginHandler)
-}
+package routes
+
+import (
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func PublicRoutes(app *fiber.App, handler *services.Handler) {
+	app.Post("/register", handler.RegisterHandler)
+	app.Post("/login", handler.LoginHandler)
+}
+
+// PublicMediaRoutes sirve los archivos sin autenticación para poder usarlos en <img>
+func PublicMediaRoutes(app *fiber.App, mh *services.MediaHandler) {
+	app.Get("/media/:id<int>", mh.ServeMedia)
+}
+
+// PublicFeedRoutes sirve los feeds de los contenidos publicados para lectores y socios
+func PublicFeedRoutes(app *fiber.App, fh *services.FeedHandler) {
+	app.Get("/feeds/rss.xml", fh.RSS)
+	app.Get("/feeds/atom.xml", fh.Atom)
+	app.Get("/feeds/feed.json", fh.JSONFeed)
+}
+
+// PublicHealthRoutes expone las sondas de liveness y readiness del orquestador
+func PublicHealthRoutes(app *fiber.App, hh *services.HealthHandler) {
+	app.Get("/healthz", hh.Liveness)
+	app.Get("/readyz", hh.Readiness)
+}
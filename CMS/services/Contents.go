@@ -1,259 +1,609 @@
-package services
-
-import (
-	"context"
-	"fmt"
-	"strconv"
-	"time"
-
-	"JSanches/CMD/database"
-	"JSanches/CMD/models"
-
-	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-)
-
-// --- Interfaces para inyección de dependencias ---
-
-type PostgresRepository interface {
-	CreateContent(content *models.Content) error
-	FindContents(contents *[]models.Content) error
-	GetContent(id string, content *models.Content) error
-	SaveContent(content *models.Content) error
-	DeleteContent(content *models.Content) error
-}
-
-type MongoRepository interface {
-	InsertContentBody(ctx context.Context, contentBody *models.ContentBody) error
-	GetContentBody(ctx context.Context, filter interface{}, contentBody *models.ContentBody) error
-	UpdateContentBody(ctx context.Context, filter interface{}, update interface{}) error
-	DeleteContentBody(ctx context.Context, filter interface{}) error
-}
-
-type CacheRepository interface {
-	Get(ctx context.Context, key string) (string, error)
-	Set(ctx context.Context, key string, value string, expiration time.Duration) error
-}
-
-// --- Handler con dependencias inyectadas ---
-
-type ContentHandler struct {
-	PG    PostgresRepository
-	Mongo MongoRepository
-	Cache CacheRepository
-}
-
-func NewContentHandler(pg PostgresRepository, mongo MongoRepository, cache CacheRepository) *ContentHandler {
-	return &ContentHandler{
-		PG:    pg,
-		Mongo: mongo,
-		Cache: cache,
-	}
-}
-
-// CreateContent crea un nuevo contenido
-func (h *ContentHandler) CreateContent(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userId").(float64)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authorized"})
-	}
-
-	type CreateContentRequest struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-	}
-
-	var req CreateContentRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
-	}
-
-	content := models.Content{
-		Title:       req.Title,
-		Description: req.Description,
-		UserID:      int(userID),
-	}
-
-	if err := h.PG.CreateContent(&content); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create content"})
-	}
-
-	contentBody := models.ContentBody{
-		ContentID: content.ID,
-		Body:      req.Description,
-	}
-	if err := h.Mongo.InsertContentBody(context.Background(), &contentBody); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create content body in MongoDB"})
-	}
-
-	return c.Status(fiber.StatusCreated).JSON(content)
-}
-
-// ListContents obtiene la lista de contenidos
-func (h *ContentHandler) ListContents(c *fiber.Ctx) error {
-	cacheKey := "contents_list"
-	if cached, err := h.Cache.Get(context.Background(), cacheKey); err == nil && cached != "" {
-		return c.Status(fiber.StatusOK).JSON(cached)
-	}
-
-	var contents []models.Content
-	if err := h.PG.FindContents(&contents); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve contents"})
-	}
-
-	_ = h.Cache.Set(context.Background(), cacheKey, fmt.Sprintf("%v", contents), 10*time.Second)
-	return c.Status(fiber.StatusOK).JSON(contents)
-}
-
-// GetContent obtiene un contenido por su id
-func (h *ContentHandler) GetContent(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var content models.Content
-	if err := h.PG.GetContent(id, &content); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Content not found"})
-	}
-
-	// Convertir id a entero para el filtro de Mongo
-	contentID, err := strconv.Atoi(id)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid content ID"})
-	}
-	filter := bson.M{"content_id": contentID}
-	var contentBody models.ContentBody
-	if err := h.Mongo.GetContentBody(context.Background(), filter, &contentBody); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Content body not found"})
-	}
-
-	response := fiber.Map{
-		"id":          content.ID,
-		"title":       content.Title,
-		"author":      content.UserID,
-		"description": contentBody.Body,
-		"created_at":  content.CreatedAt,
-		"updated_at":  content.UpdatedAt,
-	}
-	return c.Status(fiber.StatusOK).JSON(response)
-}
-
-// UpdateContent actualiza un contenido por su id
-func (h *ContentHandler) UpdateContent(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var content models.Content
-	if err := h.PG.GetContent(id, &content); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Content not found"})
-	}
-
-	type UpdateContentRequest struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-	}
-
-	var req UpdateContentRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
-	}
-
-	content.Title = req.Title
-	content.Description = req.Description
-
-	if err := h.PG.SaveContent(&content); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save content"})
-	}
-
-	contentID, err := strconv.Atoi(id)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid content ID"})
-	}
-	filter := bson.M{"content_id": contentID}
-	update := bson.M{
-		"$set": bson.M{
-			"body":  req.Description,
-			"title": req.Title,
-		},
-	}
-	if err := h.Mongo.UpdateContentBody(context.Background(), filter, update); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update MongoDB"})
-	}
-
-	return c.Status(fiber.StatusOK).JSON(content)
-}
-
-// DeleteContent elimina un contenido por su id
-func (h *ContentHandler) DeleteContent(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var content models.Content
-	if err := h.PG.GetContent(id, &content); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Content not found"})
-	}
-	if err := h.PG.DeleteContent(&content); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete content"})
-	}
-
-	contentID, err := strconv.Atoi(id)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid content ID"})
-	}
-	filter := bson.M{"content_id": contentID}
-	_ = h.Mongo.DeleteContentBody(context.Background(), filter)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Content deleted successfully"})
-}
-
-type PGClient struct{}
-
-func (p *PGClient) CreateContent(content *models.Content) error {
-	result := database.PostgresGetDB().Create(content)
-	return result.Error
-}
-
-func (p *PGClient) FindContents(contents *[]models.Content) error {
-	result := database.PostgresGetDB().Find(contents)
-	return result.Error
-}
-
-func (p *PGClient) GetContent(id string, content *models.Content) error {
-	result := database.PostgresGetDB().First(content, id)
-	return result.Error
-}
-
-func (p *PGClient) SaveContent(content *models.Content) error {
-	result := database.PostgresGetDB().Save(content)
-	return result.Error
-}
-
-func (p *PGClient) DeleteContent(content *models.Content) error {
-	result := database.PostgresGetDB().Delete(content)
-	return result.Error
-}
-
-// MongoClient implementa MongoRepository usando la base de datos Mongo real
-type MongoClient struct{}
-
-func (m *MongoClient) InsertContentBody(ctx context.Context, contentBody *models.ContentBody) error {
-	_, err := database.MongoGetCollection("contents", "contents").InsertOne(ctx, contentBody)
-	return err
-}
-
-func (m *MongoClient) GetContentBody(ctx context.Context, filter interface{}, contentBody *models.ContentBody) error {
-	return database.MongoGetCollection("contents", "contents").FindOne(ctx, filter).Decode(contentBody)
-}
-
-func (m *MongoClient) UpdateContentBody(ctx context.Context, filter interface{}, update interface{}) error {
-	_, err := database.MongoGetCollection("contents", "contents").UpdateMany(ctx, filter, update)
-	return err
-}
-
-func (m *MongoClient) DeleteContentBody(ctx context.Context, filter interface{}) error {
-	_, err := database.MongoGetCollection("contents", "contents").DeleteOne(ctx, filter)
-	return err
-}
-
-// RedisClient implementa CacheRepository usando el cliente Redis real
-type RedisClient struct{}
-
-func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
-	return database.RedisGetClient().Get(ctx, key).Result()
-}
-
-func (r *RedisClient) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
-	return database.RedisGetClient().Set(ctx, key, value, expiration).Err()
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+// --- Interfaces para inyección de dependencias ---
+
+// Todos los métodos de los repositorios reciben el contexto de la petición (o del
+// worker) y los clientes reales le aplican además database.OperationTimeout.
+
+type PostgresRepository interface {
+	CreateContent(ctx context.Context, content *models.Content) error
+	FindContents(ctx context.Context, contents *[]models.Content) error
+	GetContent(ctx context.Context, id string, content *models.Content) error
+	SaveContent(ctx context.Context, content *models.Content) error
+	DeleteContent(ctx context.Context, content *models.Content) error
+}
+
+type MongoRepository interface {
+	InsertContentBody(ctx context.Context, contentBody *models.ContentBody) error
+	GetContentBody(ctx context.Context, filter interface{}, contentBody *models.ContentBody) error
+	UpdateContentBody(ctx context.Context, filter interface{}, update interface{}) error
+	// UpsertContentBody crea o reemplaza el cuerpo de contentBody.ContentID
+	UpsertContentBody(ctx context.Context, contentBody *models.ContentBody) error
+	DeleteContentBody(ctx context.Context, filter interface{}) error
+}
+
+type CacheRepository interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, expiration time.Duration) error
+}
+
+// --- Handler con dependencias inyectadas ---
+
+type ContentHandler struct {
+	PG    PostgresRepository
+	Mongo MongoRepository
+	Cache CacheRepository
+	// Search es opcional; si es nil no se indexa ni se puede buscar
+	Search SearchIndex
+	// Taxonomy es opcional; habilita etiquetas, categorías y sus filtros
+	Taxonomy TaxonomyRepository
+	// Slugs es opcional; sin él los slugs no se deduplican ni se guardan redirecciones
+	Slugs SlugRepository
+	// Locales y Translations son opcionales; juntos habilitan las variantes por idioma
+	Locales      *Locales
+	Translations TranslationRepository
+	// Outbox es opcional; con él las escrituras en Mongo pasan por el outbox de Postgres
+	Outbox *OutboxRelay
+	// ContentCache es la caché de lectura sobre Cache; nil la desactiva
+	ContentCache *ContentCache
+	// Events es opcional; recibe los eventos del ciclo de vida (webhooks, etc.)
+	Events EventPublisher
+}
+
+func NewContentHandler(pg PostgresRepository, mongo MongoRepository, cache CacheRepository) *ContentHandler {
+	h := &ContentHandler{
+		PG:    pg,
+		Mongo: mongo,
+		Cache: cache,
+	}
+	if cache != nil {
+		h.ContentCache = NewContentCache(cache)
+	}
+	return h
+}
+
+// CreateContent crea un nuevo contenido
+func (h *ContentHandler) CreateContent(c *fiber.Ctx) error {
+	userID, ok := utils.UserID(c)
+	if !ok {
+		return utils.Problem(c, fiber.StatusUnauthorized, "User not authorized")
+	}
+
+	type CreateContentRequest struct {
+		Title       string   `json:"title"`
+		Slug        string   `json:"slug"`
+		Description string   `json:"description"`
+		Body        string   `json:"body"`
+		Format      string   `json:"format"`
+		Locale      string   `json:"locale"`
+		Tags        []string `json:"tags"`
+		CategoryID  *uint    `json:"category_id"`
+		Published   bool     `json:"published"`
+	}
+
+	var req CreateContentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.InvalidBody(c)
+	}
+	ctx := c.UserContext()
+	// Sin cuerpo explícito se usa la descripción, como antes de existir el campo
+	if req.Body == "" {
+		req.Body = req.Description
+	}
+	if req.Format == "" {
+		req.Format = FormatPlain
+	}
+	if !ValidBodyFormat(req.Format) {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid body format")
+	}
+	rendered, err := RenderBody(req.Format, req.Body)
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Failed to render body")
+	}
+
+	content := models.Content{
+		Title:       req.Title,
+		Description: req.Description,
+		UserID:      userID,
+	}
+	createdEvents := []string{EventContentCreated}
+	if req.Published {
+		now := time.Now().UTC()
+		content.PublishedAt = &now
+		createdEvents = append(createdEvents, EventContentPublished)
+	}
+	if h.localized() {
+		content.Locale = h.Locales.Default
+		if req.Locale != "" {
+			locale, err := normalizeLocale(req.Locale)
+			if err != nil || !h.Locales.Supports(locale) {
+				return utils.Problem(c, fiber.StatusBadRequest, "Unsupported locale")
+			}
+			content.Locale = locale
+		}
+	}
+	slug, err := h.uniqueSlug(ctx, req.Slug, req.Title, 0)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to generate slug", err)
+	}
+	content.Slug = slug
+	if apiErr := h.assignTaxonomy(ctx, &content, req.Tags, req.CategoryID); apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+
+	if apiErr := h.storeNewContent(ctx, &content, req.Body, req.Format, rendered); apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	h.contentChanged(ctx, content, req.Body, createdEvents...)
+
+	return c.Status(fiber.StatusCreated).JSON(content)
+}
+
+// storeNewContent guarda la fila y el cuerpo, por el outbox si está configurado
+func (h *ContentHandler) storeNewContent(ctx context.Context, content *models.Content, body, format string, rendered *models.RenderedBody) *utils.Error {
+	if h.Outbox != nil {
+		event := bodyEvent(body, format)
+		if err := h.Outbox.Repo.CreateContent(ctx, content, event); err != nil {
+			return utils.StoreError(err, "Content", "Failed to create content")
+		}
+		h.Outbox.Dispatch(ctx, *event)
+		return nil
+	}
+
+	if err := h.PG.CreateContent(ctx, content); err != nil {
+		return utils.StoreError(err, "Content", "Failed to create content")
+	}
+	contentBody := models.ContentBody{
+		ContentID: content.ID,
+		Body:      body,
+		Format:    format,
+		Rendered:  rendered,
+	}
+	if err := h.Mongo.InsertContentBody(ctx, &contentBody); err != nil {
+		return utils.StoreError(err, "Content body", "Failed to create content body")
+	}
+	return nil
+}
+
+// ListContents obtiene la lista de contenidos, cacheada por filtro
+func (h *ContentHandler) ListContents(c *fiber.Ctx) error {
+	tag, category := c.Query("tag"), c.Query("category")
+	key := "contents:list?" + url.Values{"tag": {tag}, "category": {category}}.Encode()
+
+	ctx := c.UserContext()
+	var contents []models.Content
+	err := h.ContentCache.Fetch(ctx, key, []string{contentsListTag}, &contents, func() (interface{}, error) {
+		contents := []models.Content{}
+		if tag != "" || category != "" {
+			if apiErr := h.findContentsByTaxonomy(ctx, tag, category, &contents); apiErr != nil {
+				return nil, apiErr
+			}
+			return contents, nil
+		}
+		if err := h.PG.FindContents(ctx, &contents); err != nil {
+			return nil, utils.InternalError("Failed to retrieve contents", err)
+		}
+		return contents, nil
+	})
+	if err != nil {
+		return cachedErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(contents)
+}
+
+// GetContent obtiene un contenido por su id
+func (h *ContentHandler) GetContent(c *fiber.Ctx) error {
+	id := c.Params("id")
+	// Convertir id a entero para el filtro de Mongo
+	contentID, err := strconv.Atoi(id)
+	if err != nil || contentID <= 0 {
+		return utils.InvalidID(c, "Invalid content ID")
+	}
+
+	ctx := c.UserContext()
+	return h.cachedContentResponse(c, uint(contentID), func(locale string) (interface{}, error) {
+		var content models.Content
+		if err := h.PG.GetContent(ctx, id, &content); err != nil {
+			// Solo la ausencia confirmada se cachea; un fallo de la base no
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrCacheNotFound
+			}
+			return nil, utils.StoreError(err, "Content", "Failed to retrieve content")
+		}
+		return h.contentView(ctx, content, contentID, locale)
+	})
+}
+
+// contentResponse responde con un contenido ya cargado (p. ej. buscado por slug)
+func (h *ContentHandler) contentResponse(c *fiber.Ctx, content models.Content, contentID int) error {
+	return h.cachedContentResponse(c, content.ID, func(locale string) (interface{}, error) {
+		return h.contentView(c.UserContext(), content, contentID, locale)
+	})
+}
+
+// cachedContentResponse negocia el locale y sirve la vista cacheada por id y locale
+func (h *ContentHandler) cachedContentResponse(c *fiber.Ctx, contentID uint, load func(locale string) (interface{}, error)) error {
+	locale := ""
+	if h.localized() {
+		var err error
+		if locale, err = h.Locales.Negotiate(c.Query("locale"), c.Get(fiber.HeaderAcceptLanguage)); err != nil {
+			return utils.Problem(c, fiber.StatusBadRequest, "Unsupported locale")
+		}
+		c.Vary(fiber.HeaderAcceptLanguage)
+	}
+
+	response, err := h.cachedContentView(c.UserContext(), contentID, locale, load)
+	if err != nil {
+		return cachedErrorResponse(c, err)
+	}
+	if served, ok := response["locale"].(string); ok {
+		c.Set(fiber.HeaderContentLanguage, served)
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// cachedContentView devuelve la vista de un contenido en locale, cacheada por id y locale
+func (h *ContentHandler) cachedContentView(ctx context.Context, contentID uint, locale string, load func(locale string) (interface{}, error)) (fiber.Map, error) {
+	var view fiber.Map
+	key := fmt.Sprintf("content:%d:view?locale=%s", contentID, locale)
+	err := h.ContentCache.Fetch(ctx, key, []string{contentTag(contentID)}, &view, func() (interface{}, error) {
+		return load(locale)
+	})
+	return view, err
+}
+
+// cachedErrorResponse responde con el error de load que devuelve Fetch
+func cachedErrorResponse(c *fiber.Ctx, err error) error {
+	var apiErr *utils.Error
+	if errors.As(err, &apiErr) {
+		return utils.SendError(c, apiErr)
+	}
+	if errors.Is(err, ErrCacheNotFound) {
+		return utils.Problem(c, fiber.StatusNotFound, "Content not found")
+	}
+	return utils.InternalProblem(c, "Failed to retrieve content", err)
+}
+
+// contentView combina la fila de Postgres con su cuerpo en Mongo. Con
+// localización, usa la primera variante disponible para locale.
+func (h *ContentHandler) contentView(ctx context.Context, content models.Content, contentID int, locale string) (fiber.Map, error) {
+	if h.localized() {
+		if translation := h.findTranslation(ctx, content, locale); translation != nil {
+			return h.translationView(ctx, content, translation)
+		}
+	}
+
+	filter := bson.M{"content_id": contentID}
+	var contentBody models.ContentBody
+	if err := h.Mongo.GetContentBody(ctx, filter, &contentBody); err != nil {
+		return nil, utils.StoreError(err, "Content body", "Failed to retrieve content body")
+	}
+	rendered, err := h.ensureRendered(ctx, filter, &contentBody)
+	if err != nil {
+		return nil, utils.InternalError("Failed to render content body", err)
+	}
+	format := contentBody.Format
+	if format == "" {
+		format = FormatPlain
+	}
+
+	response := fiber.Map{
+		"id":           content.ID,
+		"title":        content.Title,
+		"slug":         content.Slug,
+		"author":       content.UserID,
+		"description":  contentBody.Body,
+		"format":       format,
+		"html":         rendered.HTML,
+		"toc":          rendered.TOC,
+		"word_count":   rendered.WordCount,
+		"reading_time": rendered.ReadingTime,
+		"created_at":   content.CreatedAt,
+		"updated_at":   content.UpdatedAt,
+	}
+	if h.localized() {
+		response["locale"] = h.sourceLocale(content)
+	}
+	if h.Taxonomy != nil {
+		if err := h.Taxonomy.LoadContentTaxonomy(ctx, &content); err == nil {
+			response["tags"] = content.Tags
+			response["category"] = content.Category
+		}
+	}
+	return response, nil
+}
+
+// UpdateContent actualiza un contenido por su id
+func (h *ContentHandler) UpdateContent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("id")
+	contentID, err := strconv.Atoi(id)
+	if err != nil || contentID <= 0 {
+		return utils.InvalidID(c, "Invalid content ID")
+	}
+	var content models.Content
+	if err := h.PG.GetContent(ctx, id, &content); err != nil {
+		return utils.StoreProblem(c, err, "Content", "Failed to retrieve content")
+	}
+
+	type UpdateContentRequest struct {
+		Title       string   `json:"title"`
+		Slug        *string  `json:"slug"`
+		Description string   `json:"description"`
+		Body        string   `json:"body"`
+		Format      string   `json:"format"`
+		Tags        []string `json:"tags"`
+		CategoryID  *uint    `json:"category_id"`
+		// Published nil conserva el estado actual
+		Published *bool `json:"published"`
+	}
+
+	var req UpdateContentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.InvalidBody(c)
+	}
+	if req.Body == "" {
+		req.Body = req.Description
+	}
+	if req.Format == "" {
+		req.Format = FormatPlain
+	}
+	if !ValidBodyFormat(req.Format) {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid body format")
+	}
+	rendered, err := RenderBody(req.Format, req.Body)
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Failed to render body")
+	}
+
+	content.Title = req.Title
+	updatedEvents := []string{EventContentUpdated}
+	if req.Published != nil {
+		switch {
+		case *req.Published && content.PublishedAt == nil:
+			now := time.Now().UTC()
+			content.PublishedAt = &now
+			updatedEvents = append(updatedEvents, EventContentPublished)
+		case !*req.Published:
+			content.PublishedAt = nil
+		}
+	}
+	content.Description = req.Description
+	// El slug solo cambia si se pide explícitamente, para no romper URLs públicas
+	oldSlug := content.Slug
+	if req.Slug != nil || content.Slug == "" {
+		requested := ""
+		if req.Slug != nil {
+			requested = *req.Slug
+		}
+		slug, err := h.uniqueSlug(ctx, requested, req.Title, content.ID)
+		if err != nil {
+			return utils.InternalProblem(c, "Failed to generate slug", err)
+		}
+		content.Slug = slug
+	}
+	if apiErr := h.assignTaxonomy(ctx, &content, req.Tags, req.CategoryID); apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+
+	var event *models.OutboxEvent
+	if h.Outbox != nil {
+		event = bodyEvent(req.Body, req.Format)
+		err = h.Outbox.Repo.SaveContent(ctx, &content, event)
+	} else {
+		err = h.PG.SaveContent(ctx, &content)
+	}
+	if err != nil {
+		return utils.StoreProblem(c, err, "Content slug", "Failed to save content")
+	}
+	// La fila ya cambió (categoría y etiquetas incluidas): aunque falle lo que
+	// sigue, lo cacheado deja de valer
+	if h.Slugs != nil && oldSlug != "" && oldSlug != content.Slug {
+		if err := h.Slugs.RecordRedirect(ctx, oldSlug, content.ID); err != nil {
+			h.ContentCache.InvalidateContent(ctx, content.ID)
+			return utils.StoreProblem(c, err, "Slug redirect", "Failed to record slug redirect")
+		}
+	}
+	// Save solo agrega asociaciones; Replace quita las etiquetas que ya no están
+	if h.Taxonomy != nil && req.Tags != nil {
+		if err := h.Taxonomy.SetContentTags(ctx, &content, content.Tags); err != nil {
+			h.ContentCache.InvalidateContent(ctx, content.ID)
+			return utils.StoreProblem(c, err, "Tag", "Failed to save tags")
+		}
+	}
+
+	if event != nil {
+		h.Outbox.Dispatch(ctx, *event)
+		h.contentChanged(ctx, content, req.Body, updatedEvents...)
+		return c.Status(fiber.StatusOK).JSON(content)
+	}
+
+	filter := bson.M{"content_id": contentID}
+	update := bson.M{
+		"$set": bson.M{
+			"body":     req.Body,
+			"format":   req.Format,
+			"rendered": rendered,
+			"title":    req.Title,
+		},
+	}
+	if err := h.Mongo.UpdateContentBody(ctx, filter, update); err != nil {
+		return utils.StoreProblem(c, err, "Content body", "Failed to update content body")
+	}
+	h.contentChanged(ctx, content, req.Body, updatedEvents...)
+
+	return c.Status(fiber.StatusOK).JSON(content)
+}
+
+// DeleteContent elimina un contenido por su id
+func (h *ContentHandler) DeleteContent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("id")
+	contentID, err := strconv.Atoi(id)
+	if err != nil || contentID <= 0 {
+		return utils.InvalidID(c, "Invalid content ID")
+	}
+	var content models.Content
+	if err := h.PG.GetContent(ctx, id, &content); err != nil {
+		return utils.StoreProblem(c, err, "Content", "Failed to retrieve content")
+	}
+	if h.Outbox != nil {
+		event := &models.OutboxEvent{Operation: OutboxDeleteBody}
+		if err := h.Outbox.Repo.DeleteContent(ctx, &content, event); err != nil {
+			return utils.StoreProblem(c, err, "Content", "Failed to delete content")
+		}
+		h.Outbox.Dispatch(ctx, *event)
+		h.contentDeleted(ctx, content)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Content deleted successfully"})
+	}
+	if err := h.PG.DeleteContent(ctx, &content); err != nil {
+		return utils.StoreProblem(c, err, "Content", "Failed to delete content")
+	}
+
+	filter := bson.M{"content_id": contentID}
+	_ = h.Mongo.DeleteContentBody(ctx, filter)
+	if h.Translations != nil {
+		_ = h.Translations.DeleteTranslations(ctx, content.ID)
+	}
+	h.contentDeleted(ctx, content)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Content deleted successfully"})
+}
+
+type PGClient struct{}
+
+func (p *PGClient) CreateContent(ctx context.Context, content *models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	result := database.PostgresGetDB().WithContext(ctx).Create(content)
+	return result.Error
+}
+
+func (p *PGClient) FindContents(ctx context.Context, contents *[]models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	result := database.PostgresGetDB().WithContext(ctx).Find(contents)
+	return result.Error
+}
+
+func (p *PGClient) GetContent(ctx context.Context, id string, content *models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	result := database.PostgresGetDB().WithContext(ctx).First(content, id)
+	return result.Error
+}
+
+func (p *PGClient) SaveContent(ctx context.Context, content *models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	result := database.PostgresGetDB().WithContext(ctx).Save(content)
+	return result.Error
+}
+
+func (p *PGClient) DeleteContent(ctx context.Context, content *models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	result := database.PostgresGetDB().WithContext(ctx).Delete(content)
+	return result.Error
+}
+
+// MongoClient implementa MongoRepository usando la base de datos Mongo real
+type MongoClient struct{}
+
+func (m *MongoClient) InsertContentBody(ctx context.Context, contentBody *models.ContentBody) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	_, err := database.MongoGetCollection("contents", "contents").InsertOne(ctx, contentBody)
+	return err
+}
+
+func (m *MongoClient) GetContentBody(ctx context.Context, filter interface{}, contentBody *models.ContentBody) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.MongoGetCollection("contents", "contents").FindOne(ctx, filter).Decode(contentBody)
+}
+
+func (m *MongoClient) UpdateContentBody(ctx context.Context, filter interface{}, update interface{}) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	_, err := database.MongoGetCollection("contents", "contents").UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (m *MongoClient) UpsertContentBody(ctx context.Context, contentBody *models.ContentBody) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	filter := bson.M{"content_id": contentBody.ContentID}
+	update := bson.M{"$set": contentBody}
+	_, err := database.MongoGetCollection("contents", "contents").UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (m *MongoClient) DeleteContentBody(ctx context.Context, filter interface{}) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	_, err := database.MongoGetCollection("contents", "contents").DeleteOne(ctx, filter)
+	return err
+}
+
+// RedisClient implementa CacheRepository usando el cliente Redis real
+type RedisClient struct{}
+
+func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.RedisGetClient().Get(ctx, key).Result()
+}
+
+func (r *RedisClient) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.RedisGetClient().Set(ctx, key, value, expiration).Err()
+}
+
+// DeletePrefix borra las claves que empiezan por prefix. Usa SCAN para no
+// bloquear Redis con KEYS y devuelve cuántas borró.
+func (r *RedisClient) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	client := database.RedisGetClient()
+	var deleted int64
+	iter := client.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	batch := make([]string, 0, 500)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := client.Unlink(ctx, batch...).Result()
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// --- Almacenamiento de binarios ---
+
+// BlobStore guarda archivos bajo una clave opaca (p. ej. "ab/abcdef...png")
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get devuelve el contenido y su tamaño en bytes
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix borra todos los blobs cuya clave empieza por prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// NewBlobStore crea el almacenamiento indicado por backend ("local" o "s3")
+func NewBlobStore(backend, dir string, s3 S3Config) (BlobStore, error) {
+	switch backend {
+	case "", "local":
+		if dir == "" {
+			dir = "media"
+		}
+		return NewLocalBlobStore(dir)
+	case "s3":
+		return NewS3BlobStore(s3)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", backend)
+	}
+}
+
+// LocalBlobStore guarda los archivos en un directorio del sistema de archivos
+type LocalBlobStore struct {
+	Root string
+}
+
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{Root: root}, nil
+}
+
+// path valida la clave para que no pueda salir del directorio raíz
+func (l *LocalBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(l.Root, clean), nil
+}
+
+func (l *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Escribir en un temporal y renombrar evita dejar archivos a medias
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+func (l *LocalBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *LocalBlobStore) DeletePrefix(ctx context.Context, prefix string) error {
+	path, err := l.path(prefix)
+	if err != nil {
+		return err
+	}
+	// Solo hace falta recorrer el directorio donde empieza el prefijo
+	dir := filepath.Dir(path)
+	if strings.HasSuffix(prefix, "/") {
+		dir = path
+	}
+	err = filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasPrefix(file, path) && !strings.HasPrefix(entry.Name(), ".upload-") {
+			return os.Remove(file)
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// S3Config configura un almacenamiento compatible con S3 (AWS, MinIO, R2...)
+type S3Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+}
+
+type S3BlobStore struct {
+	Client *minio.Client
+	Bucket string
+}
+
+// NewS3BlobStore conecta con el endpoint y crea el bucket si no existe
+func NewS3BlobStore(cfg S3Config) (*S3BlobStore, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required")
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, err
+		}
+	}
+	return &S3BlobStore{Client: client, Bucket: cfg.Bucket}, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	object, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	// GetObject es perezoso: Stat confirma que el objeto existe antes de devolverlo
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, 0, ErrBlobNotFound
+		}
+		return nil, 0, err
+	}
+	return object, info.Size, nil
+}
+
+func (s *S3BlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.Client.StatObject(ctx, s.Bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	return s.Client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3BlobStore) DeletePrefix(ctx context.Context, prefix string) error {
+	for object := range s.Client.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := s.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// --- Cuerpos de los contenidos en Postgres (JSONB) o SQLite (JSON) ---
+
+// SQLBodyStore implementa MongoRepository sobre la tabla content_bodies, para
+// instalaciones sin Mongo. Cada fila guarda el documento que iría a Mongo; los
+// filtros de igualdad se traducen a SQL con BodyFilterSQL.
+type SQLBodyStore struct {
+	DB      *gorm.DB
+	dialect string
+}
+
+func NewSQLBodyStore(db *gorm.DB) *SQLBodyStore {
+	return &SQLBodyStore{DB: db, dialect: db.Dialector.Name()}
+}
+
+// En SQLite los nombres de campo van dentro de la ruta JSON de la consulta
+var jsonField = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// BodyFilterSQL traduce un filtro de igualdad de Mongo a una condición WHERE
+// para dialect ("postgres" o "sqlite"). content_id usa la clave primaria; en
+// Postgres el resto de campos se comparan por contención JSONB (@>), que para
+// valores escalares equivale a la igualdad, y en SQLite con json_extract, que
+// solo admite valores escalares.
+func BodyFilterSQL(dialect string, filter interface{}) (string, []interface{}, error) {
+	conditions, err := equalityFilter(filter)
+	if err != nil {
+		return "", nil, err
+	}
+	var clauses []string
+	var args []interface{}
+	if id, ok := conditions["content_id"]; ok {
+		n, ok := number(id)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: content_id must be a number", ErrUnsupportedFilter)
+		}
+		clauses = append(clauses, "content_id = ?")
+		args = append(args, int64(n))
+		delete(conditions, "content_id")
+	}
+	if len(conditions) > 0 && dialect == "sqlite" {
+		keys := make([]string, 0, len(conditions))
+		for key := range conditions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !jsonField.MatchString(key) {
+				return "", nil, fmt.Errorf("%w: field %s", ErrUnsupportedFilter, key)
+			}
+			switch value := conditions[key].(type) {
+			case nil:
+				clauses = append(clauses, "json_extract(doc, '$."+key+"') IS NULL")
+			case string, bool, int32, int64, float64:
+				clauses = append(clauses, "json_extract(doc, '$."+key+"') = ?")
+				args = append(args, value)
+			default:
+				return "", nil, fmt.Errorf("%w: %s must be a scalar value", ErrUnsupportedFilter, key)
+			}
+		}
+	} else if len(conditions) > 0 {
+		data, err := bson.MarshalExtJSON(conditions, false, false)
+		if err != nil {
+			return "", nil, err
+		}
+		clauses = append(clauses, "doc @> CAST(? AS jsonb)")
+		args = append(args, string(data))
+	}
+	if len(clauses) == 0 {
+		return "TRUE", nil, nil
+	}
+	return strings.Join(clauses, " AND "), args, nil
+}
+
+func (s *SQLBodyStore) sqlite() bool {
+	return s.dialect == "sqlite"
+}
+
+// jsonParam convierte el parámetro de texto en el tipo JSON del dialecto
+func (s *SQLBodyStore) jsonParam() string {
+	if s.sqlite() {
+		return "json(?)"
+	}
+	return "CAST(? AS jsonb)"
+}
+
+// sqliteSet devuelve la expresión que reemplaza en target los campos de primer
+// nivel de fields, leídos de source (una columna o un parámetro JSON). Es el
+// equivalente al || de JSONB.
+func sqliteSet(target, source string, fields bson.M) (string, error) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if !jsonField.MatchString(key) {
+			return "", fmt.Errorf("%w: field %s", ErrUnsupportedFilter, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	expr := "json_set(" + target
+	for _, key := range keys {
+		expr += ", '$." + key + "', json(" + source + " -> '$." + key + "')"
+	}
+	return expr + ")", nil
+}
+
+func (s *SQLBodyStore) InsertContentBody(ctx context.Context, contentBody *models.ContentBody) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	doc, err := bson.MarshalExtJSON(contentBody, false, false)
+	if err != nil {
+		return err
+	}
+	return s.DB.WithContext(ctx).
+		Exec(`INSERT INTO content_bodies (content_id, doc) VALUES (?, `+s.jsonParam()+`)`, contentBody.ContentID, string(doc)).Error
+}
+
+func (s *SQLBodyStore) GetContentBody(ctx context.Context, filter interface{}, contentBody *models.ContentBody) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	where, args, err := BodyFilterSQL(s.dialect, filter)
+	if err != nil {
+		return err
+	}
+	column := "doc::text"
+	if s.sqlite() {
+		column = "doc"
+	}
+	var doc string
+	err = s.DB.WithContext(ctx).
+		Raw(`SELECT `+column+` FROM content_bodies WHERE `+where+` ORDER BY content_id LIMIT 1`, args...).
+		Row().Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return mongo.ErrNoDocuments
+	}
+	if err != nil {
+		return err
+	}
+	return bson.UnmarshalExtJSON([]byte(doc), false, contentBody)
+}
+
+// UpdateContentBody aplica $set a todas las filas que cumplen el filtro; || de
+// JSONB y json_set de SQLite reemplazan los campos de primer nivel igual que $set
+func (s *SQLBodyStore) UpdateContentBody(ctx context.Context, filter interface{}, update interface{}) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	where, args, err := BodyFilterSQL(s.dialect, filter)
+	if err != nil {
+		return err
+	}
+	set, err := setFields(update)
+	if err != nil {
+		return err
+	}
+	if _, ok := set["content_id"]; ok {
+		return fmt.Errorf("%w: content_id cannot be updated", ErrUnsupportedFilter)
+	}
+	data, err := bson.MarshalExtJSON(set, false, false)
+	if err != nil {
+		return err
+	}
+	expr := "doc || CAST(? AS jsonb)"
+	params := []interface{}{string(data)}
+	if s.sqlite() {
+		if expr, err = sqliteSet("doc", "?", set); err != nil {
+			return err
+		}
+		params = params[:0]
+		for range set {
+			params = append(params, string(data))
+		}
+	}
+	return s.DB.WithContext(ctx).
+		Exec(`UPDATE content_bodies SET doc = `+expr+` WHERE `+where, append(params, args...)...).Error
+}
+
+func (s *SQLBodyStore) UpsertContentBody(ctx context.Context, contentBody *models.ContentBody) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	doc, err := bson.MarshalExtJSON(contentBody, false, false)
+	if err != nil {
+		return err
+	}
+	merge := "content_bodies.doc || EXCLUDED.doc"
+	if s.sqlite() {
+		var fields bson.M
+		if err := bson.UnmarshalExtJSON(doc, false, &fields); err != nil {
+			return err
+		}
+		if merge, err = sqliteSet("content_bodies.doc", "excluded.doc", fields); err != nil {
+			return err
+		}
+	}
+	return s.DB.WithContext(ctx).Exec(`INSERT INTO content_bodies (content_id, doc) VALUES (?, `+s.jsonParam()+`)
+		ON CONFLICT (content_id) DO UPDATE SET doc = `+merge,
+		contentBody.ContentID, string(doc)).Error
+}
+
+// DeleteContentBody borra la primera fila que cumple el filtro, como DeleteOne
+func (s *SQLBodyStore) DeleteContentBody(ctx context.Context, filter interface{}) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	where, args, err := BodyFilterSQL(s.dialect, filter)
+	if err != nil {
+		return err
+	}
+	return s.DB.WithContext(ctx).Exec(`DELETE FROM content_bodies WHERE content_id =
+		(SELECT content_id FROM content_bodies WHERE `+where+` ORDER BY content_id LIMIT 1)`, args...).Error
+}
+
+// BodyContentIDs sustituye a la consulta de Mongo de ConsistencyClient
+func (s *SQLBodyStore) BodyContentIDs(ctx context.Context) ([]uint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	var ids []uint
+	err := s.DB.WithContext(ctx).Raw(`SELECT content_id FROM content_bodies`).Scan(&ids).Error
+	return ids, err
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Verificación de consistencia entre Postgres y Mongo ---
+
+// ConsistencyRepository lee los identificadores de ambos almacenes sin cargar los cuerpos
+type ConsistencyRepository interface {
+	// ContentRows devuelve los ids vivos y los borrados lógicamente
+	ContentRows(ctx context.Context) (live []uint, deleted []uint, err error)
+	// BodyContentIDs devuelve el content_id de cada cuerpo, con repeticiones
+	BodyContentIDs(ctx context.Context) ([]uint, error)
+	// PendingContentIDs son los contenidos con eventos del outbox sin aplicar
+	PendingContentIDs(ctx context.Context) ([]uint, error)
+	// ContentState vuelve a leer una fila justo antes de repararla
+	ContentState(ctx context.Context, id uint) (exists bool, deleted bool, err error)
+}
+
+const (
+	RepairDeleteBody = "delete_body"
+	RepairCreateBody = "create_empty_body"
+)
+
+type ConsistencyRepair struct {
+	ContentID uint   `json:"content_id"`
+	Action    string `json:"action"`
+	// Skipped indica que la fila cambió después de la lectura y no se tocó
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ConsistencyReport describe las diferencias encontradas y, si se pidió, lo reparado
+type ConsistencyReport struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Rows      int       `json:"rows"`
+	Bodies    int       `json:"bodies"`
+	// Cuerpos cuyo contenido no existe en Postgres
+	OrphanBodies []uint `json:"orphan_bodies"`
+	// Contenidos vivos sin cuerpo
+	MissingBodies []uint `json:"missing_bodies"`
+	// Contenidos borrados cuyo cuerpo sigue en Mongo
+	DeletedWithBody []uint `json:"deleted_with_body"`
+	// Contenidos con más de un cuerpo; se informan pero no se reparan
+	DuplicateBodies []uint `json:"duplicate_bodies"`
+	// Contenidos con eventos pendientes, que se omiten porque el relay los resolverá
+	Skipped []uint              `json:"skipped"`
+	Repair  bool                `json:"repair"`
+	DryRun  bool                `json:"dry_run"`
+	Repairs []ConsistencyRepair `json:"repairs"`
+}
+
+// Consistent indica que no se encontraron diferencias
+func (r *ConsistencyReport) Consistent() bool {
+	return len(r.OrphanBodies) == 0 && len(r.MissingBodies) == 0 && len(r.DeletedWithBody) == 0 && len(r.DuplicateBodies) == 0
+}
+
+type ConsistencyChecker struct {
+	Repo         ConsistencyRepository
+	Mongo        MongoRepository
+	Translations TranslationRepository
+	// Cache es opcional; se invalida cada contenido reparado
+	Cache *ContentCache
+}
+
+func NewConsistencyChecker(repo ConsistencyRepository, mongo MongoRepository) *ConsistencyChecker {
+	return &ConsistencyChecker{Repo: repo, Mongo: mongo}
+}
+
+// Check compara ambos almacenes. Con repair borra los cuerpos huérfanos y crea
+// cuerpos vacíos para las filas que no tienen; con dryRun solo lista esas acciones.
+// Las lecturas no son atómicas, así que cada reparación vuelve a comprobar la fila.
+func (cc *ConsistencyChecker) Check(ctx context.Context, repair, dryRun bool) (*ConsistencyReport, error) {
+	live, deleted, err := cc.Repo.ContentRows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bodyIDs, err := cc.Repo.BodyContentIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := cc.Repo.PendingContentIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConsistencyReport{
+		CheckedAt:       time.Now().UTC(),
+		Rows:            len(live),
+		Bodies:          len(bodyIDs),
+		OrphanBodies:    []uint{},
+		MissingBodies:   []uint{},
+		DeletedWithBody: []uint{},
+		DuplicateBodies: []uint{},
+		Skipped:         []uint{},
+		Repair:          repair,
+		DryRun:          dryRun,
+		Repairs:         []ConsistencyRepair{},
+	}
+
+	isLive, isDeleted, isPending := idSet(live), idSet(deleted), idSet(pending)
+	bodies := map[uint]int{}
+	for _, id := range bodyIDs {
+		bodies[id]++
+	}
+	skipped := map[uint]bool{}
+	skip := func(id uint) bool {
+		if isPending[id] {
+			skipped[id] = true
+		}
+		return isPending[id]
+	}
+
+	for id, count := range bodies {
+		if skip(id) {
+			continue
+		}
+		switch {
+		case isDeleted[id]:
+			report.DeletedWithBody = append(report.DeletedWithBody, id)
+		case !isLive[id]:
+			report.OrphanBodies = append(report.OrphanBodies, id)
+		case count > 1:
+			report.DuplicateBodies = append(report.DuplicateBodies, id)
+		}
+	}
+	for _, id := range live {
+		if bodies[id] == 0 && !skip(id) {
+			report.MissingBodies = append(report.MissingBodies, id)
+		}
+	}
+	for id := range skipped {
+		report.Skipped = append(report.Skipped, id)
+	}
+	for _, ids := range [][]uint{report.OrphanBodies, report.MissingBodies, report.DeletedWithBody, report.DuplicateBodies, report.Skipped} {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+
+	if repair {
+		for _, id := range append(append([]uint{}, report.OrphanBodies...), report.DeletedWithBody...) {
+			report.Repairs = append(report.Repairs, cc.repair(ctx, id, RepairDeleteBody, dryRun))
+		}
+		for _, id := range report.MissingBodies {
+			report.Repairs = append(report.Repairs, cc.repair(ctx, id, RepairCreateBody, dryRun))
+		}
+	}
+	return report, nil
+}
+
+func (cc *ConsistencyChecker) repair(ctx context.Context, id uint, action string, dryRun bool) ConsistencyRepair {
+	result := ConsistencyRepair{ContentID: id, Action: action}
+	if dryRun {
+		return result
+	}
+
+	// Un contenido creado o borrado durante la lectura ya no necesita la reparación
+	exists, deleted, err := cc.Repo.ContentState(ctx, id)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	live := exists && !deleted
+	if action == RepairDeleteBody && live || action == RepairCreateBody && !live {
+		result.Skipped = true
+		return result
+	}
+
+	switch action {
+	case RepairDeleteBody:
+		err = cc.Mongo.DeleteContentBody(ctx, bson.M{"content_id": int(id)})
+		if err == nil && cc.Translations != nil {
+			err = cc.Translations.DeleteTranslations(ctx, id)
+		}
+	case RepairCreateBody:
+		// Solo inserta: si el cuerpo llegó después de la lectura no se pisa
+		var rendered *models.RenderedBody
+		if rendered, err = RenderBody(FormatPlain, ""); err == nil {
+			err = cc.Mongo.InsertContentBody(ctx, &models.ContentBody{ContentID: id, Format: FormatPlain, Rendered: rendered})
+			if utils.IsConflict(err) {
+				result.Skipped = true
+				return result
+			}
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	cc.Cache.InvalidateContent(ctx, id)
+	return result
+}
+
+func idSet(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// --- Endpoint de administración ---
+
+type ConsistencyHandler struct {
+	Checker *ConsistencyChecker
+}
+
+func NewConsistencyHandler(checker *ConsistencyChecker) *ConsistencyHandler {
+	return &ConsistencyHandler{Checker: checker}
+}
+
+// CheckConsistency devuelve el informe sin modificar nada
+func (h *ConsistencyHandler) CheckConsistency(c *fiber.Ctx) error {
+	report, err := h.Checker.Check(c.UserContext(), false, false)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to check consistency", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// RepairConsistency repara las diferencias; ?dry_run=true solo lista las acciones
+func (h *ConsistencyHandler) RepairConsistency(c *fiber.Ctx) error {
+	report, err := h.Checker.Check(c.UserContext(), true, c.QueryBool("dry_run", false))
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to repair consistency", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// --- Implementación con GORM y MongoDB ---
+
+type ConsistencyClient struct {
+	// Bodies es opcional; si está, los cuerpos se leen de ahí en lugar de Mongo
+	Bodies interface {
+		BodyContentIDs(ctx context.Context) ([]uint, error)
+	}
+}
+
+func (cc *ConsistencyClient) ContentRows(ctx context.Context) ([]uint, []uint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	var rows []struct {
+		ID        uint
+		IsDeleted bool
+	}
+	err := database.PostgresGetDB().WithContext(ctx).Unscoped().Model(&models.Content{}).
+		Select("id, deleted_at IS NOT NULL AS is_deleted").Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	live, deleted := []uint{}, []uint{}
+	for _, row := range rows {
+		if row.IsDeleted {
+			deleted = append(deleted, row.ID)
+		} else {
+			live = append(live, row.ID)
+		}
+	}
+	return live, deleted, nil
+}
+
+func (cc *ConsistencyClient) BodyContentIDs(ctx context.Context) ([]uint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	if cc.Bodies != nil {
+		return cc.Bodies.BodyContentIDs(ctx)
+	}
+	opts := options.Find().SetProjection(bson.M{"content_id": 1, "_id": 0})
+	cursor, err := database.MongoGetCollection("contents", "contents").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		ContentID uint `bson:"content_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ContentID
+	}
+	return ids, nil
+}
+
+func (cc *ConsistencyClient) ContentState(ctx context.Context, id uint) (bool, bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	var rows []struct {
+		IsDeleted bool
+	}
+	err := database.PostgresGetDB().WithContext(ctx).Unscoped().Model(&models.Content{}).
+		Select("deleted_at IS NOT NULL AS is_deleted").Where("id = ?", id).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return false, false, err
+	}
+	return true, rows[0].IsDeleted, nil
+}
+
+func (cc *ConsistencyClient) PendingContentIDs(ctx context.Context) ([]uint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	var ids []uint
+	err := database.PostgresGetDB().WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("status = ?", models.OutboxPending).Distinct().Pluck("content_id", &ids).Error
+	return ids, err
+}
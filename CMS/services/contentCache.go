@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// --- Caché de lectura con invalidación por etiquetas ---
+
+// ErrCacheNotFound lo devuelve load para que la ausencia también se cachee
+var ErrCacheNotFound = errors.New("not found")
+
+const (
+	cacheNotFoundMarker = "\x00notfound"
+	cacheTagTTL         = 7 * 24 * time.Hour
+)
+
+// ContentCache guarda valores como JSON sobre CacheRepository. Cada clave incluye
+// la versión actual de sus etiquetas: invalidar una etiqueta es cambiarle la
+// versión, y las entradas viejas dejan de alcanzarse y expiran solas. Así no hace
+// falta borrar claves ni recorrerlas.
+type ContentCache struct {
+	Cache       CacheRepository
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	group       singleflight.Group
+}
+
+func NewContentCache(cache CacheRepository) *ContentCache {
+	return &ContentCache{Cache: cache, TTL: time.Minute, NegativeTTL: 10 * time.Second}
+}
+
+func contentTag(id uint) string {
+	return fmt.Sprintf("content:%d", id)
+}
+
+const contentsListTag = "contents"
+
+// CachePrefixes son los prefijos de las claves de Redis que solo son caché y se
+// pueden borrar sin perder datos: las lecturas de ContentCache y las variantes de imagen
+var CachePrefixes = []string{"cache:", "media:variant:"}
+
+// Fetch busca key en la caché y, si no está, llama a load una sola vez aunque
+// haya varias peticiones simultáneas. El resultado se decodifica en dest.
+// Con cc nil se llama a load directamente.
+func (cc *ContentCache) Fetch(ctx context.Context, key string, tags []string, dest interface{}, load func() (interface{}, error)) error {
+	if cc == nil {
+		value, err := load()
+		if err != nil {
+			return err
+		}
+		reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(value))
+		return nil
+	}
+	fullKey := cc.versionedKey(ctx, key, tags)
+	if cached, err := cc.Cache.Get(ctx, fullKey); err == nil && cached != "" {
+		if cached == cacheNotFoundMarker {
+			return ErrCacheNotFound
+		}
+		if err := json.Unmarshal([]byte(cached), dest); err == nil {
+			return nil
+		}
+	}
+
+	data, err, _ := cc.group.Do(fullKey, func() (interface{}, error) {
+		value, err := load()
+		if errors.Is(err, ErrCacheNotFound) {
+			cc.set(ctx, fullKey, cacheNotFoundMarker, cc.NegativeTTL)
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		cc.set(ctx, fullKey, string(data), cc.TTL)
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data.([]byte), dest)
+}
+
+// Invalidate cambia la versión de las etiquetas
+func (cc *ContentCache) Invalidate(ctx context.Context, tags ...string) {
+	if cc == nil {
+		return
+	}
+	for _, tag := range tags {
+		cc.set(ctx, "cache:tag:"+tag, newCacheVersion(), cacheTagTTL)
+	}
+}
+
+// InvalidateContent invalida un contenido y todos los listados
+func (cc *ContentCache) InvalidateContent(ctx context.Context, id uint) {
+	cc.Invalidate(ctx, contentTag(id), contentsListTag)
+}
+
+func (cc *ContentCache) versionedKey(ctx context.Context, key string, tags []string) string {
+	versions := make([]string, len(tags))
+	for i, tag := range tags {
+		tagKey := "cache:tag:" + tag
+		version, err := cc.Cache.Get(ctx, tagKey)
+		if err != nil || version == "" {
+			// Sin versión conocida se crea una nueva: nada anterior puede reutilizarse
+			version = newCacheVersion()
+			cc.set(ctx, tagKey, version, cacheTagTTL)
+		}
+		versions[i] = version
+	}
+	return "cache:" + key + "@" + strings.Join(versions, ".")
+}
+
+func (cc *ContentCache) set(ctx context.Context, key, value string, ttl time.Duration) {
+	if err := cc.Cache.Set(ctx, key, value, ttl); err != nil {
+		log.Printf("cache: failed to set %s: %v", key, err)
+	}
+}
+
+func newCacheVersion() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,559 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Tipos de contenido dinámicos ---
+
+type ContentTypeRepository interface {
+	ListContentTypes(ctx context.Context, types *[]models.ContentType) error
+	GetContentType(ctx context.Context, slug string, contentType *models.ContentType) error
+	SaveContentType(ctx context.Context, contentType *models.ContentType) error
+	DeleteContentType(ctx context.Context, contentType *models.ContentType) error
+}
+
+type EntryRepository interface {
+	InsertEntry(ctx context.Context, entry *models.Entry) error
+	FindEntries(ctx context.Context, typeSlug string, limit, offset int, entries *[]models.Entry) error
+	GetEntry(ctx context.Context, typeSlug string, id primitive.ObjectID, entry *models.Entry) error
+	ReplaceEntry(ctx context.Context, entry *models.Entry) error
+	DeleteEntry(ctx context.Context, typeSlug string, id primitive.ObjectID) error
+	CountEntries(ctx context.Context, typeSlug string) (int64, error)
+}
+
+var (
+	ErrEntryNotFound = errors.New("entry not found")
+	fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	// Segmentos de /collection que ya usan otras rutas
+	reservedTypeSlugs = map[string]bool{"search": true, "by-slug": true, "stream": true}
+	// /collection/:id<int> tiene prioridad, así que un slug numérico no se alcanzaría
+	numericSlugPattern = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// ValidateContentType revisa que las definiciones de campos sean coherentes
+func ValidateContentType(contentType *models.ContentType) map[string]string {
+	problems := make(map[string]string)
+	if strings.TrimSpace(contentType.Name) == "" {
+		problems["name"] = "is required"
+	}
+	if contentType.Slug == "" {
+		problems["slug"] = "is required"
+	} else if numericSlugPattern.MatchString(contentType.Slug) {
+		problems["slug"] = "must not be only digits"
+	}
+
+	seen := make(map[string]bool)
+	for i, field := range contentType.Fields {
+		key := fmt.Sprintf("fields[%d]", i)
+		if !fieldNamePattern.MatchString(field.Name) {
+			problems[key] = "name must be lowercase letters, digits or underscores"
+			continue
+		}
+		if seen[field.Name] {
+			problems[key] = "duplicated field name " + field.Name
+			continue
+		}
+		seen[field.Name] = true
+
+		switch field.Type {
+		case models.FieldText, models.FieldRichText:
+			if field.Pattern != "" {
+				if _, err := regexp.Compile(field.Pattern); err != nil {
+					problems[key] = "invalid pattern"
+				}
+			}
+		case models.FieldEnum:
+			if len(field.Options) == 0 {
+				problems[key] = "enum fields need options"
+			}
+		case models.FieldReference:
+			if field.RefType == "" {
+				problems[key] = "reference fields need ref_type"
+			}
+		case models.FieldNumber, models.FieldBoolean, models.FieldDate, models.FieldMedia, models.FieldJSON:
+		default:
+			problems[key] = fmt.Sprintf("unknown field type %q", field.Type)
+		}
+	}
+	return problems
+}
+
+// ValidateEntry valida data contra el esquema y devuelve los valores normalizados
+// (las fechas se convierten a time.Time y los enteros a int64)
+func ValidateEntry(contentType models.ContentType, data map[string]interface{}) (map[string]interface{}, map[string]string) {
+	problems := make(map[string]string)
+	clean := make(map[string]interface{})
+
+	known := make(map[string]bool)
+	for _, field := range contentType.Fields {
+		known[field.Name] = true
+		value, present := data[field.Name]
+		if !present || value == nil {
+			if field.Required {
+				problems[field.Name] = "is required"
+			}
+			continue
+		}
+
+		normalized, problem := validateField(field, value)
+		if problem != "" {
+			problems[field.Name] = problem
+			continue
+		}
+		clean[field.Name] = normalized
+	}
+	for name := range data {
+		if !known[name] {
+			problems[name] = "is not defined in " + contentType.Slug
+		}
+	}
+	return clean, problems
+}
+
+func validateField(field models.FieldDefinition, value interface{}) (interface{}, string) {
+	switch field.Type {
+	case models.FieldText, models.FieldRichText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, "must be a string"
+		}
+		if field.Required && strings.TrimSpace(s) == "" {
+			return nil, "is required"
+		}
+		length := utf8.RuneCountInString(s)
+		if field.MinLength != nil && length < *field.MinLength {
+			return nil, fmt.Sprintf("must have at least %d characters", *field.MinLength)
+		}
+		if field.MaxLength != nil && length > *field.MaxLength {
+			return nil, fmt.Sprintf("must have at most %d characters", *field.MaxLength)
+		}
+		if field.Pattern != "" {
+			re, err := regexp.Compile(field.Pattern)
+			if err != nil || !re.MatchString(s) {
+				return nil, "does not match pattern"
+			}
+		}
+		return s, ""
+
+	case models.FieldNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, "must be a number"
+		}
+		if field.Min != nil && n < *field.Min {
+			return nil, fmt.Sprintf("must be >= %v", *field.Min)
+		}
+		if field.Max != nil && n > *field.Max {
+			return nil, fmt.Sprintf("must be <= %v", *field.Max)
+		}
+		if field.Integer {
+			if n != math.Trunc(n) {
+				return nil, "must be an integer"
+			}
+			return int64(n), ""
+		}
+		return n, ""
+
+	case models.FieldBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, "must be a boolean"
+		}
+		return b, ""
+
+	case models.FieldDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, "must be a date string"
+		}
+		t, err := parseDateParam(s)
+		if err != nil || t == nil {
+			return nil, "must be a RFC3339 date or YYYY-MM-DD"
+		}
+		return t.UTC(), ""
+
+	case models.FieldEnum:
+		s, ok := value.(string)
+		if !ok {
+			return nil, "must be a string"
+		}
+		for _, option := range field.Options {
+			if s == option {
+				return s, ""
+			}
+		}
+		return nil, "must be one of " + strings.Join(field.Options, ", ")
+
+	case models.FieldReference:
+		s, ok := value.(string)
+		if !ok {
+			return nil, "must be an entry id"
+		}
+		if _, err := primitive.ObjectIDFromHex(s); err != nil {
+			return nil, "must be an entry id"
+		}
+		return s, ""
+
+	case models.FieldMedia:
+		s, ok := value.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, "must be a media reference"
+		}
+		return s, ""
+
+	case models.FieldJSON:
+		return value, ""
+	}
+	return nil, "unknown field type"
+}
+
+// --- Handlers ---
+
+type ContentTypeHandler struct {
+	Types   ContentTypeRepository
+	Entries EntryRepository
+	// Stream es opcional; si está configurado, publica los cambios de las entradas
+	Stream *ContentStream
+}
+
+func NewContentTypeHandler(types ContentTypeRepository, entries EntryRepository) *ContentTypeHandler {
+	return &ContentTypeHandler{Types: types, Entries: entries}
+}
+
+// ListContentTypes obtiene todos los tipos de contenido
+func (h *ContentTypeHandler) ListContentTypes(c *fiber.Ctx) error {
+	var types []models.ContentType
+	if err := h.Types.ListContentTypes(c.UserContext(), &types); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve content types", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(types)
+}
+
+// GetContentType obtiene un tipo de contenido por su slug
+func (h *ContentTypeHandler) GetContentType(c *fiber.Ctx) error {
+	var contentType models.ContentType
+	if err := h.Types.GetContentType(c.UserContext(), c.Params("type"), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to retrieve content type")
+	}
+	return c.Status(fiber.StatusOK).JSON(contentType)
+}
+
+// CreateContentType define un nuevo tipo de contenido
+func (h *ContentTypeHandler) CreateContentType(c *fiber.Ctx) error {
+	var contentType models.ContentType
+	if err := c.BodyParser(&contentType); err != nil {
+		return utils.InvalidBody(c)
+	}
+	contentType.ID = 0
+	if contentType.Slug = Slugify(contentType.Slug); contentType.Slug == "" {
+		contentType.Slug = Slugify(contentType.Name)
+	}
+	if problems := ValidateContentType(&contentType); len(problems) > 0 {
+		return utils.SendError(c, utils.ValidationError("Invalid content type", problems))
+	}
+	if reservedTypeSlugs[contentType.Slug] {
+		return utils.Problem(c, fiber.StatusConflict, "Content type slug is reserved")
+	}
+
+	var existing models.ContentType
+	if err := h.Types.GetContentType(c.UserContext(), contentType.Slug, &existing); err == nil {
+		return utils.Problem(c, fiber.StatusConflict, "Content type already exists")
+	} else if !utils.IsNotFound(err) {
+		return utils.InternalProblem(c, "Failed to retrieve content type", err)
+	}
+	if err := h.Types.SaveContentType(c.UserContext(), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to create content type")
+	}
+	return c.Status(fiber.StatusCreated).JSON(contentType)
+}
+
+// UpdateContentType reemplaza nombre, descripción y campos; el slug no cambia
+func (h *ContentTypeHandler) UpdateContentType(c *fiber.Ctx) error {
+	var contentType models.ContentType
+	if err := h.Types.GetContentType(c.UserContext(), c.Params("type"), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to retrieve content type")
+	}
+
+	var req models.ContentType
+	if err := c.BodyParser(&req); err != nil {
+		return utils.InvalidBody(c)
+	}
+	contentType.Name = req.Name
+	contentType.Description = req.Description
+	contentType.Fields = req.Fields
+	if problems := ValidateContentType(&contentType); len(problems) > 0 {
+		return utils.SendError(c, utils.ValidationError("Invalid content type", problems))
+	}
+	if err := h.Types.SaveContentType(c.UserContext(), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to save content type")
+	}
+	return c.Status(fiber.StatusOK).JSON(contentType)
+}
+
+// DeleteContentType elimina un tipo de contenido que no tenga entradas
+func (h *ContentTypeHandler) DeleteContentType(c *fiber.Ctx) error {
+	var contentType models.ContentType
+	if err := h.Types.GetContentType(c.UserContext(), c.Params("type"), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to retrieve content type")
+	}
+	count, err := h.Entries.CountEntries(c.UserContext(), contentType.Slug)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to count entries", err)
+	}
+	if count > 0 {
+		return utils.Problem(c, fiber.StatusConflict, "Content type has entries")
+	}
+	if err := h.Types.DeleteContentType(c.UserContext(), &contentType); err != nil {
+		return utils.InternalProblem(c, "Failed to delete content type", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Content type deleted successfully"})
+}
+
+// ListEntries obtiene las entradas de un tipo
+func (h *ContentTypeHandler) ListEntries(c *fiber.Ctx) error {
+	var contentType models.ContentType
+	if err := h.Types.GetContentType(c.UserContext(), c.Params("type"), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to retrieve content type")
+	}
+
+	limit := c.QueryInt("limit", 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries := []models.Entry{}
+	if err := h.Entries.FindEntries(c.UserContext(), contentType.Slug, limit, offset, &entries); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve entries", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
+
+// GetEntry obtiene una entrada por su id
+func (h *ContentTypeHandler) GetEntry(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.InvalidID(c, "Invalid entry ID")
+	}
+	var entry models.Entry
+	if err := h.Entries.GetEntry(c.UserContext(), c.Params("type"), id, &entry); err != nil {
+		return utils.StoreProblem(c, err, "Entry", "Failed to retrieve entry")
+	}
+	return c.Status(fiber.StatusOK).JSON(entry)
+}
+
+// CreateEntry valida y guarda una nueva entrada
+func (h *ContentTypeHandler) CreateEntry(c *fiber.Ctx) error {
+	userID, ok := utils.UserID(c)
+	if !ok {
+		return utils.Problem(c, fiber.StatusUnauthorized, "User not authorized")
+	}
+	var contentType models.ContentType
+	if err := h.Types.GetContentType(c.UserContext(), c.Params("type"), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to retrieve content type")
+	}
+
+	data, apiErr := h.parseEntry(c, contentType)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+
+	now := time.Now().UTC()
+	entry := models.Entry{
+		Type:      contentType.Slug,
+		Data:      data,
+		UserID:    userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.Entries.InsertEntry(c.UserContext(), &entry); err != nil {
+		return utils.InternalProblem(c, "Failed to create entry", err)
+	}
+	h.Stream.PublishEntry(c.UserContext(), EventContentCreated, entry)
+	return c.Status(fiber.StatusCreated).JSON(entry)
+}
+
+// UpdateEntry reemplaza los datos de una entrada, validándolos de nuevo
+func (h *ContentTypeHandler) UpdateEntry(c *fiber.Ctx) error {
+	var contentType models.ContentType
+	if err := h.Types.GetContentType(c.UserContext(), c.Params("type"), &contentType); err != nil {
+		return utils.StoreProblem(c, err, "Content type", "Failed to retrieve content type")
+	}
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.InvalidID(c, "Invalid entry ID")
+	}
+	var entry models.Entry
+	if err := h.Entries.GetEntry(c.UserContext(), contentType.Slug, id, &entry); err != nil {
+		return utils.StoreProblem(c, err, "Entry", "Failed to retrieve entry")
+	}
+
+	data, apiErr := h.parseEntry(c, contentType)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	entry.Data = data
+	entry.UpdatedAt = time.Now().UTC()
+	if err := h.Entries.ReplaceEntry(c.UserContext(), &entry); err != nil {
+		return utils.InternalProblem(c, "Failed to save entry", err)
+	}
+	h.Stream.PublishEntry(c.UserContext(), EventContentUpdated, entry)
+	return c.Status(fiber.StatusOK).JSON(entry)
+}
+
+// DeleteEntry elimina una entrada
+func (h *ContentTypeHandler) DeleteEntry(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.InvalidID(c, "Invalid entry ID")
+	}
+	err = h.Entries.DeleteEntry(c.UserContext(), c.Params("type"), id)
+	if errors.Is(err, ErrEntryNotFound) {
+		return utils.Problem(c, fiber.StatusNotFound, "Entry not found")
+	}
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to delete entry", err)
+	}
+	h.Stream.PublishEntry(c.UserContext(), EventContentDeleted, models.Entry{ID: id, Type: c.Params("type")})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Entry deleted successfully"})
+}
+
+// parseEntry valida el cuerpo contra el esquema y comprueba que las referencias existan.
+// Si algo falla devuelve el error para responder.
+func (h *ContentTypeHandler) parseEntry(c *fiber.Ctx, contentType models.ContentType) (map[string]interface{}, *utils.Error) {
+	var data map[string]interface{}
+	if err := c.BodyParser(&data); err != nil {
+		return nil, utils.InvalidBodyError()
+	}
+
+	clean, problems := ValidateEntry(contentType, data)
+	for _, field := range contentType.Fields {
+		ref, ok := clean[field.Name].(string)
+		if field.Type != models.FieldReference || !ok {
+			continue
+		}
+		id, _ := primitive.ObjectIDFromHex(ref)
+		var target models.Entry
+		if err := h.Entries.GetEntry(c.UserContext(), field.RefType, id, &target); utils.IsNotFound(err) {
+			problems[field.Name] = "references a missing " + field.RefType
+		} else if err != nil {
+			return nil, utils.InternalError("Failed to retrieve entry", err)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, utils.ValidationError("Validation failed", problems)
+	}
+	return clean, nil
+}
+
+// --- Implementaciones ---
+
+type ContentTypeClient struct{}
+
+func (t *ContentTypeClient) ListContentTypes(ctx context.Context, types *[]models.ContentType) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Order("slug").Find(types).Error
+}
+
+func (t *ContentTypeClient) GetContentType(ctx context.Context, slug string, contentType *models.ContentType) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Where("slug = ?", slug).First(contentType).Error
+}
+
+func (t *ContentTypeClient) SaveContentType(ctx context.Context, contentType *models.ContentType) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Save(contentType).Error
+}
+
+func (t *ContentTypeClient) DeleteContentType(ctx context.Context, contentType *models.ContentType) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Delete(contentType).Error
+}
+
+// EntryClient implementa EntryRepository sobre la colección "entries" de Mongo
+type EntryClient struct{}
+
+func (e *EntryClient) collection() *mongo.Collection {
+	// Los documentos anidados (campos json) se decodifican como mapas y no como bson.D
+	opts := options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
+	return database.MongoGetClient().Database("contents").Collection("entries", opts)
+}
+
+func (e *EntryClient) InsertEntry(ctx context.Context, entry *models.Entry) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	entry.ID = primitive.NewObjectID()
+	_, err := e.collection().InsertOne(ctx, entry)
+	return err
+}
+
+func (e *EntryClient) FindEntries(ctx context.Context, typeSlug string, limit, offset int, entries *[]models.Entry) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+	cursor, err := e.collection().Find(ctx, bson.M{"type": typeSlug}, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, entries)
+}
+
+func (e *EntryClient) GetEntry(ctx context.Context, typeSlug string, id primitive.ObjectID, entry *models.Entry) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return e.collection().FindOne(ctx, bson.M{"_id": id, "type": typeSlug}).Decode(entry)
+}
+
+func (e *EntryClient) ReplaceEntry(ctx context.Context, entry *models.Entry) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	_, err := e.collection().ReplaceOne(ctx, bson.M{"_id": entry.ID, "type": entry.Type}, entry)
+	return err
+}
+
+func (e *EntryClient) DeleteEntry(ctx context.Context, typeSlug string, id primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	result, err := e.collection().DeleteOne(ctx, bson.M{"_id": id, "type": typeSlug})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+func (e *EntryClient) CountEntries(ctx context.Context, typeSlug string) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return e.collection().CountDocuments(ctx, bson.M{"type": typeSlug})
+}
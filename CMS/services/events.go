@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"JSanches/CMD/models"
+)
+
+// --- Eventos del ciclo de vida de los contenidos ---
+
+const (
+	EventContentCreated   = "content.created"
+	EventContentUpdated   = "content.updated"
+	EventContentPublished = "content.published"
+	EventContentDeleted   = "content.deleted"
+)
+
+var ContentEventTypes = []string{EventContentCreated, EventContentUpdated, EventContentPublished, EventContentDeleted}
+
+// ContentEvent es lo que reciben los suscriptores cuando cambia un contenido
+type ContentEvent struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	ContentID  uint            `json:"content_id"`
+	Content    *models.Content `json:"content,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// EventPublisher recibe los eventos emitidos por ContentHandler. Publish se llama
+// dentro de la petición, así que las entregas lentas deben hacerse en segundo plano.
+type EventPublisher interface {
+	Publish(ctx context.Context, event ContentEvent)
+}
+
+func NewContentEvent(eventType string, content models.Content) ContentEvent {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return ContentEvent{
+		ID:         hex.EncodeToString(b),
+		Type:       eventType,
+		ContentID:  content.ID,
+		Content:    &content,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// contentChanged actualiza el índice y la caché tras una escritura y emite los
+// eventos. La escritura ya está hecha, así que no se cancela con la petición.
+func (h *ContentHandler) contentChanged(ctx context.Context, content models.Content, body string, eventTypes ...string) {
+	ctx = context.WithoutCancel(ctx)
+	h.indexContent(ctx, content, body)
+	h.ContentCache.InvalidateContent(ctx, content.ID)
+	h.emit(ctx, content, eventTypes...)
+}
+
+func (h *ContentHandler) contentDeleted(ctx context.Context, content models.Content) {
+	ctx = context.WithoutCancel(ctx)
+	h.unindexContent(ctx, content.ID)
+	h.ContentCache.InvalidateContent(ctx, content.ID)
+	h.emit(ctx, content, EventContentDeleted)
+}
+
+// emit publica los eventos si hay un EventPublisher configurado
+func (h *ContentHandler) emit(ctx context.Context, content models.Content, eventTypes ...string) {
+	if h.Events == nil {
+		return
+	}
+	for _, eventType := range eventTypes {
+		h.Events.Publish(ctx, NewContentEvent(eventType, content))
+	}
+}
+
+// EventPublishers reparte cada evento entre varios publicadores, en orden
+type EventPublishers []EventPublisher
+
+func (p EventPublishers) Publish(ctx context.Context, event ContentEvent) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, event)
+	}
+}
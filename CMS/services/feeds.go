@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// --- Feeds RSS, Atom y JSON Feed de los contenidos publicados ---
+
+// FeedFilter limita los contenidos de un feed; los campos vacíos no filtran
+type FeedFilter struct {
+	TagSlug     string
+	CategoryIDs []uint
+	AuthorID    int
+}
+
+// FeedState resume los contenidos de un feed para responder a peticiones condicionales
+// sin cargarlos: cambia cuando se publica, edita o borra alguno.
+type FeedState struct {
+	Count        int64
+	LastModified time.Time
+}
+
+type FeedRepository interface {
+	// FindPublishedContents devuelve los más recientes primero, con etiquetas y categoría
+	FindPublishedContents(ctx context.Context, filter FeedFilter, limit int, contents *[]models.Content) error
+	FeedState(ctx context.Context, filter FeedFilter) (FeedState, error)
+}
+
+const (
+	FeedRSS  = "rss"
+	FeedAtom = "atom"
+	FeedJSON = "json"
+)
+
+type FeedHandler struct {
+	Repo     FeedRepository
+	Contents *ContentHandler
+	// Title y BaseURL identifican el sitio; los enlaces de cada entrada son BaseURL/slug.
+	// Sin BaseURL se usa la del servidor que atiende la petición.
+	Title   string
+	BaseURL string
+	Limit   int
+	MaxAge  time.Duration
+}
+
+func NewFeedHandler(repo FeedRepository, contents *ContentHandler, title, baseURL string) *FeedHandler {
+	if title == "" {
+		title = "CMS"
+	}
+	return &FeedHandler{
+		Repo:     repo,
+		Contents: contents,
+		Title:    title,
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Limit:    50,
+		MaxAge:   5 * time.Minute,
+	}
+}
+
+func (h *FeedHandler) RSS(c *fiber.Ctx) error {
+	return h.serveFeed(c, FeedRSS)
+}
+
+func (h *FeedHandler) Atom(c *fiber.Ctx) error {
+	return h.serveFeed(c, FeedAtom)
+}
+
+func (h *FeedHandler) JSONFeed(c *fiber.Ctx) error {
+	return h.serveFeed(c, FeedJSON)
+}
+
+// feedItem es una entrada ya resuelta, común a los tres formatos
+type feedItem struct {
+	ID        string
+	Title     string
+	Link      string
+	Summary   string
+	HTML      string
+	AuthorID  int
+	Tags      []string
+	Published time.Time
+	Updated   time.Time
+}
+
+// serveFeed responde 304 si el feed no cambió desde la última petición del lector
+func (h *FeedHandler) serveFeed(c *fiber.Ctx, format string) error {
+	filter, apiErr := h.parseFilter(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	state, err := h.Repo.FeedState(c.UserContext(), filter)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve feed", err)
+	}
+
+	lastModified := state.LastModified
+	if lastModified.IsZero() {
+		lastModified = time.Unix(0, 0)
+	}
+	c.Set(fiber.HeaderETag, feedETag(format, c.Context().QueryArgs().String(), state))
+	c.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(h.MaxAge.Seconds())))
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	var contents []models.Content
+	if err := h.Repo.FindPublishedContents(c.UserContext(), filter, h.Limit, &contents); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve feed", err)
+	}
+	base := h.BaseURL
+	if base == "" {
+		base = c.BaseURL()
+	}
+	items := make([]feedItem, 0, len(contents))
+	for _, content := range contents {
+		item, err := h.item(c.UserContext(), base, content)
+		if err != nil {
+			// Un contenido sin cuerpo no debe tumbar todo el feed
+			continue
+		}
+		items = append(items, item)
+	}
+
+	self := base + c.OriginalURL()
+	switch format {
+	case FeedRSS:
+		return h.writeRSS(c, base, self, lastModified, items)
+	case FeedAtom:
+		return h.writeAtom(c, base, self, lastModified, items)
+	default:
+		return h.writeJSON(c, base, self, items)
+	}
+}
+
+// parseFilter lee ?tag=slug&category=id&author=id; la categoría incluye sus descendientes
+func (h *FeedHandler) parseFilter(c *fiber.Ctx) (FeedFilter, *utils.Error) {
+	filter := FeedFilter{TagSlug: Slugify(c.Query("tag"))}
+	if author := c.Query("author"); author != "" {
+		id, err := strconv.Atoi(author)
+		if err != nil || id <= 0 {
+			return filter, utils.NewError(fiber.StatusBadRequest, "Invalid author")
+		}
+		filter.AuthorID = id
+	}
+	if category := c.Query("category"); category != "" {
+		id, err := strconv.ParseUint(category, 10, 64)
+		if err != nil {
+			return filter, utils.InvalidIDError("Invalid category ID")
+		}
+		filter.CategoryIDs = []uint{uint(id)}
+		if h.Contents.Taxonomy != nil {
+			var categories []models.Category
+			if err := h.Contents.Taxonomy.ListCategories(c.UserContext(), &categories); err != nil {
+				return filter, utils.InternalError("Failed to retrieve categories", err)
+			}
+			filter.CategoryIDs = CategoryDescendants(categories, uint(id))
+		}
+	}
+	return filter, nil
+}
+
+func feedETag(format, query string, state FeedState) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s?%s|%d|%d", format, query, state.Count, state.LastModified.UnixNano())))
+	return `W/"` + hex.EncodeToString(sum[:])[:32] + `"`
+}
+
+// item usa la vista cacheada del contenido para no renderizar el cuerpo en cada petición
+func (h *FeedHandler) item(ctx context.Context, base string, content models.Content) (feedItem, error) {
+	view, err := h.Contents.cachedContentView(ctx, content.ID, "", func(locale string) (interface{}, error) {
+		return h.Contents.contentView(ctx, content, int(content.ID), locale)
+	})
+	if err != nil {
+		return feedItem{}, err
+	}
+	html, _ := view["html"].(string)
+
+	path := content.Slug
+	if path == "" {
+		path = strconv.FormatUint(uint64(content.ID), 10)
+	}
+	item := feedItem{
+		ID:       base + "/" + url.PathEscape(path),
+		Title:    content.Title,
+		Summary:  content.Description,
+		HTML:     html,
+		AuthorID: content.UserID,
+		Updated:  content.UpdatedAt.UTC(),
+	}
+	item.Link = item.ID
+	if content.PublishedAt != nil {
+		item.Published = content.PublishedAt.UTC()
+	}
+	for _, tag := range content.Tags {
+		item.Tags = append(item.Tags, tag.Name)
+	}
+	if content.Category != nil {
+		item.Tags = append(item.Tags, content.Category.Name)
+	}
+	return item, nil
+}
+
+// --- RSS 2.0 ---
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (h *FeedHandler) writeRSS(c *fiber.Ctx, base, self string, updated time.Time, items []feedItem) error {
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         h.Title,
+			Link:          base + "/",
+			Description:   h.Title,
+			SelfLink:      atomLink{Href: self, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, item := range items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.ID},
+			Description: item.HTML,
+			Categories:  item.Tags,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+		})
+	}
+	return writeXML(c, "application/rss+xml; charset=utf-8", feed)
+}
+
+// --- Atom 1.0 ---
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    atomText       `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+func (h *FeedHandler) writeAtom(c *fiber.Ctx, base, self string, updated time.Time, items []feedItem) error {
+	feed := atomFeed{
+		Title:   h.Title,
+		ID:      self,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: base + "/", Rel: "alternate", Type: "text/html"},
+		},
+		Author: atomAuthor{Name: h.Title},
+	}
+	for _, item := range items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Link:      atomLink{Href: item.Link, Rel: "alternate", Type: "text/html"},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+			Content:   atomText{Type: "html", Value: item.HTML},
+		}
+		if item.Summary != "" {
+			entry.Summary = &atomText{Type: "text", Value: item.Summary}
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return writeXML(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+func writeXML(c *fiber.Ctx, contentType string, feed interface{}) error {
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to generate feed", err)
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(fiber.StatusOK).Send(append([]byte(xml.Header), data...))
+}
+
+// --- JSON Feed 1.1 ---
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Tags          []string         `json:"tags,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func (h *FeedHandler) writeJSON(c *fiber.Ctx, base, self string, items []feedItem) error {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       h.Title,
+		HomePageURL: base + "/",
+		FeedURL:     self,
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range items {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.HTML,
+			Summary:       item.Summary,
+			DatePublished: item.Published.Format(time.RFC3339),
+			DateModified:  item.Updated.Format(time.RFC3339),
+			Tags:          item.Tags,
+			Authors:       []jsonFeedAuthor{{Name: strconv.Itoa(item.AuthorID)}},
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(feed, "application/feed+json; charset=utf-8")
+}
+
+// --- Implementación con GORM ---
+
+type FeedClient struct{}
+
+// filtered aplica el filtro a una consulta sobre contents ya publicados
+func (f *FeedClient) filtered(query *gorm.DB, filter FeedFilter) *gorm.DB {
+	query = query.Where("contents.published_at IS NOT NULL")
+	if filter.TagSlug != "" {
+		query = query.Where("contents.id IN (?)", database.PostgresGetDB().Table("content_tags").
+			Select("content_tags.content_id").
+			Joins("JOIN tags ON tags.id = content_tags.tag_id").
+			Where("tags.slug = ?", filter.TagSlug))
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("contents.category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.AuthorID != 0 {
+		query = query.Where("contents.user_id = ?", filter.AuthorID)
+	}
+	return query
+}
+
+func (f *FeedClient) FindPublishedContents(ctx context.Context, filter FeedFilter, limit int, contents *[]models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	query := database.PostgresGetDB().WithContext(ctx).Model(&models.Content{}).Preload("Tags").Preload("Category")
+	return f.filtered(query, filter).Order("published_at DESC").Limit(limit).Find(contents).Error
+}
+
+// FeedState cuenta los publicados vivos y toma el último cambio incluyendo los
+// borrados, para que borrar un contenido también invalide el ETag
+func (f *FeedClient) FeedState(ctx context.Context, filter FeedFilter) (FeedState, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	var state FeedState
+	if err := f.filtered(database.PostgresGetDB().WithContext(ctx).Model(&models.Content{}), filter).Count(&state.Count).Error; err != nil {
+		return state, err
+	}
+	// SQLite no tiene GREATEST, pero su MAX con varios argumentos hace lo mismo.
+	// Tampoco conoce el tipo del resultado, así que se lee como texto: el que
+	// guarda el driver de SQLite o RFC 3339, que es como database/sql convierte
+	// a string el time.Time de Postgres.
+	db := database.PostgresGetDB().WithContext(ctx)
+	greatest := "GREATEST"
+	if isSQLite(db) {
+		greatest = "MAX"
+	}
+	var lastModified sql.NullString
+	err := f.filtered(db.Unscoped().Model(&models.Content{}), filter).
+		Select("MAX(" + greatest + "(contents.updated_at, contents.published_at, COALESCE(contents.deleted_at, contents.updated_at)))").
+		Scan(&lastModified).Error
+	if err != nil {
+		return state, err
+	}
+	if lastModified.Valid {
+		if state.LastModified, err = parseDBTime(lastModified.String); err != nil {
+			return state, fmt.Errorf("parsing last modified time: %w", err)
+		}
+	}
+	return state, nil
+}
+
+func parseDBTime(value string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02 15:04:05.999999999-07:00", value)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"JSanches/CMD/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// --- Endpoints de salud para el orquestador ---
+
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthCheck comprueba una dependencia. Si no es crítica (la caché), su fallo
+// deja el servicio degradado pero listo para recibir tráfico.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+type HealthHandler struct {
+	Checks []HealthCheck
+	// Timeout se aplica a cada comprobación por separado
+	Timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{Checks: checks, Timeout: 2 * time.Second}
+}
+
+// SetDraining hace que /readyz falle para que el orquestador deje de enviar
+// tráfico mientras se drenan las peticiones en curso
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness solo indica que el proceso responde
+func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": HealthOK})
+}
+
+// Readiness comprueba todas las dependencias en paralelo. Responde 503 si falla
+// alguna crítica o el servidor se está apagando.
+func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	if h.draining.Load() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(HealthReport{Status: HealthUnavailable, Checks: map[string]HealthCheckResult{}})
+	}
+	report := h.Check(c.UserContext())
+	status := fiber.StatusOK
+	if report.Status == HealthUnavailable {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
+
+// Check ejecuta las comprobaciones y agrega el estado
+func (h *HealthHandler) Check(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthOK, Checks: make(map[string]HealthCheckResult, len(h.Checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.Checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			result := h.run(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status == "up" {
+				return
+			}
+			if check.Critical {
+				report.Status = HealthUnavailable
+			} else if report.Status == HealthOK {
+				report.Status = HealthDegraded
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+func (h *HealthHandler) run(ctx context.Context, check HealthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	// Un driver que ignore el contexto no debe bloquear la respuesta
+	go func() {
+		done <- check.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := HealthCheckResult{
+		Status:    "up",
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "down"
+		result.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = "timeout after " + h.Timeout.String()
+		}
+	}
+	return result
+}
+
+// DatabaseHealthChecks comprueba la base SQL (sql es "postgres" o "sqlite") y, si
+// se usan, Mongo, críticos, y Redis, que solo es caché
+func DatabaseHealthChecks(sql string, mongo, redis bool) []HealthCheck {
+	checks := []HealthCheck{
+		{Name: sql, Critical: true, Check: func(ctx context.Context) error {
+			db := database.PostgresGetDB()
+			if db == nil {
+				return errors.New("not initialized")
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		}},
+	}
+	if mongo {
+		checks = append(checks, HealthCheck{Name: "mongo", Critical: true, Check: func(ctx context.Context) error {
+			return database.MongoGetClient().Ping(ctx, nil)
+		}})
+	}
+	if redis {
+		checks = append(checks, HealthCheck{Name: "redis", Critical: false, Check: func(ctx context.Context) error {
+			return database.RedisGetClient().Ping(ctx).Err()
+		}})
+	}
+	return checks
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/singleflight"
+)
+
+// --- Redimensionado y conversión de imágenes ---
+
+const (
+	maxImageDimension = 4000
+	defaultQuality    = 82
+	variantCacheTTL   = 24 * time.Hour
+	// DefaultMaxImagePixels equivale a unos 160 MB decodificada en RGBA
+	DefaultMaxImagePixels = 40_000_000
+)
+
+// ErrImageTooLarge indica una imagen que supera el límite de píxeles. Un PNG o
+// WebP de pocos KB puede declarar dimensiones enormes y agotar la memoria al
+// decodificarse.
+var ErrImageTooLarge = errors.New("image is too large to transform")
+
+var imageFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+var formatAliases = map[string]string{"jpg": "jpeg", "image/jpeg": "jpeg", "image/png": "png", "image/gif": "gif", "image/webp": "webp"}
+
+// ImageParams describe una variante de imagen. Width o Height en cero
+// significan "proporcional al otro"; Format vacío conserva el original.
+type ImageParams struct {
+	Width   int
+	Height  int
+	Fit     string
+	Format  string
+	Quality int
+}
+
+// ParseImageParams lee w, h, fit, fmt y q de la query y normaliza sus valores
+func ParseImageParams(query func(key string, defaultValue ...string) string) (ImageParams, error) {
+	params := ImageParams{Fit: "contain", Quality: defaultQuality}
+	var err error
+	if params.Width, err = dimensionParam(query("w")); err != nil {
+		return params, err
+	}
+	if params.Height, err = dimensionParam(query("h")); err != nil {
+		return params, err
+	}
+	if fit := query("fit"); fit != "" {
+		if fit != "cover" && fit != "contain" && fit != "fill" {
+			return params, errors.New("fit must be cover, contain or fill")
+		}
+		params.Fit = fit
+	}
+	if format := strings.ToLower(query("fmt")); format != "" {
+		if alias, ok := formatAliases[format]; ok {
+			format = alias
+		}
+		if _, ok := imageFormats[format]; !ok {
+			return params, errors.New("fmt must be jpeg, png, gif or webp")
+		}
+		params.Format = format
+	}
+	if q := query("q"); q != "" {
+		quality, err := strconv.Atoi(q)
+		if err != nil || quality < 1 || quality > 100 {
+			return params, errors.New("q must be between 1 and 100")
+		}
+		params.Quality = quality
+	}
+	return params, nil
+}
+
+func dimensionParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxImageDimension {
+		return 0, fmt.Errorf("dimensions must be between 1 and %d", maxImageDimension)
+	}
+	return n, nil
+}
+
+// IsZero indica que no se pidió ninguna transformación
+func (p ImageParams) IsZero() bool {
+	return p.Width == 0 && p.Height == 0 && p.Format == ""
+}
+
+// Canonical es la forma estable de los parámetros; es lo que se firma y lo que
+// identifica la variante en el caché
+func (p ImageParams) Canonical() string {
+	values := url.Values{}
+	if p.Width > 0 {
+		values.Set("w", strconv.Itoa(p.Width))
+	}
+	if p.Height > 0 {
+		values.Set("h", strconv.Itoa(p.Height))
+	}
+	values.Set("fit", p.Fit)
+	if p.Format != "" {
+		values.Set("fmt", p.Format)
+	}
+	values.Set("q", strconv.Itoa(p.Quality))
+	return values.Encode()
+}
+
+// SignImageParams firma el id del medio junto con los parámetros canónicos
+func SignImageParams(key []byte, mediaID uint, params ImageParams) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d?%s", mediaID, params.Canonical())
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// SignedImageURL arma la URL pública de una variante
+func SignedImageURL(key []byte, mediaID uint, params ImageParams) string {
+	return fmt.Sprintf("%s%d?%s&s=%s", MediaURLPrefix, mediaID, params.Canonical(), SignImageParams(key, mediaID, params))
+}
+
+// TransformImage decodifica, redimensiona según Fit y codifica en el formato
+// pedido. Las dimensiones se leen de la cabecera antes de decodificar, y una
+// imagen de más de maxPixels devuelve ErrImageTooLarge.
+func TransformImage(r io.Reader, params ImageParams, sourceMime string, maxPixels int64) ([]byte, string, error) {
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, "", ErrImageTooLarge
+	}
+	src, decodedFormat, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, "", err
+	}
+
+	format := params.Format
+	if format == "" {
+		format = decodedFormat
+		if alias, ok := formatAliases[sourceMime]; ok {
+			format = alias
+		}
+	}
+	if _, ok := imageFormats[format]; !ok {
+		format = "png"
+	}
+
+	dst := resizeImage(src, params)
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, flatten(dst), &jpeg.Options{Quality: params.Quality})
+	case "png":
+		err = png.Encode(&buf, dst)
+	case "gif":
+		err = gif.Encode(&buf, dst, nil)
+	case "webp":
+		// El codificador puro en Go solo genera WebP sin pérdida
+		err = nativewebp.Encode(&buf, dst, nil)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), imageFormats[format], nil
+}
+
+func resizeImage(src image.Image, params ImageParams) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	w, h := params.Width, params.Height
+
+	switch {
+	case w == 0 && h == 0:
+		return src
+	case w == 0:
+		w = max(1, srcW*h/srcH)
+	case h == 0:
+		h = max(1, srcH*w/srcW)
+	}
+
+	switch params.Fit {
+	case "fill":
+		return scale(src, bounds, w, h)
+
+	case "cover":
+		// Escalar para cubrir w x h y recortar el centro
+		ratio := maxFloat(float64(w)/float64(srcW), float64(h)/float64(srcH))
+		cropW := min(srcW, int(float64(w)/ratio+0.5))
+		cropH := min(srcH, int(float64(h)/ratio+0.5))
+		x0 := bounds.Min.X + (srcW-cropW)/2
+		y0 := bounds.Min.Y + (srcH-cropH)/2
+		return scale(src, image.Rect(x0, y0, x0+cropW, y0+cropH), w, h)
+
+	default:
+		// contain: cabe dentro de w x h sin deformar y sin agrandar el original
+		ratio := minFloat(float64(w)/float64(srcW), float64(h)/float64(srcH))
+		if ratio >= 1 {
+			return src
+		}
+		return scale(src, bounds, max(1, int(float64(srcW)*ratio+0.5)), max(1, int(float64(srcH)*ratio+0.5)))
+	}
+}
+
+func scale(src image.Image, from image.Rectangle, w, h int) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, from, draw.Over, nil)
+	return dst
+}
+
+// flatten pone la imagen sobre fondo blanco porque JPEG no admite transparencia
+func flatten(img image.Image) image.Image {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// --- Variantes cacheadas ---
+
+var variantGroup singleflight.Group
+
+const variantCachePrefix = "media:variant:"
+
+type imageVariant struct {
+	Key  string
+	Mime string
+}
+
+// variantPrefix es el comienzo de las claves de todas las variantes de media
+func variantPrefix(media models.Media) string {
+	return fmt.Sprintf("variants/%s/%s-", media.SHA256[:2], media.SHA256)
+}
+
+// variantKey deriva la clave del blob a partir del hash del original y los parámetros
+func variantKey(media models.Media, params ImageParams) (string, string) {
+	sum := sha256.Sum256([]byte(params.Canonical()))
+	format := params.Format
+	if format == "" {
+		format = formatAliases[media.MimeType]
+	}
+	if _, ok := imageFormats[format]; !ok {
+		format = "png"
+	}
+	key := fmt.Sprintf("%s%s.%s", variantPrefix(media), hex.EncodeToString(sum[:8]), format)
+	return key, format
+}
+
+// variant devuelve la clave de la variante, calculándola solo si no existe todavía.
+// Redis evita consultar el BlobStore en cada petición y singleflight evita que
+// varias peticiones simultáneas generen la misma variante; como la comparten, no
+// se cancela con la petición que la empezó.
+func (h *MediaHandler) variant(ctx context.Context, media models.Media, params ImageParams) (imageVariant, error) {
+	key, format := variantKey(media, params)
+	mime := imageFormats[format]
+	result := imageVariant{Key: key, Mime: mime}
+	cacheKey := variantCachePrefix + key
+
+	if h.Cache != nil {
+		if cached, err := h.Cache.Get(ctx, cacheKey); err == nil && cached != "" {
+			return result, nil
+		}
+	}
+
+	_, err, _ := variantGroup.Do(key, func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		exists, err := h.Store.Exists(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			original, _, err := h.Store.Get(ctx, media.StorageKey)
+			if err != nil {
+				return nil, err
+			}
+			defer original.Close()
+
+			data, _, err := TransformImage(original, params, media.MimeType, h.MaxPixels)
+			if err != nil {
+				return nil, err
+			}
+			if err := h.Store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), mime); err != nil {
+				return nil, err
+			}
+		}
+		if h.Cache != nil {
+			if err := h.Cache.Set(ctx, cacheKey, "1", variantCacheTTL); err != nil {
+				log.Printf("media: failed to cache variant %s: %v", key, err)
+			}
+		}
+		return nil, nil
+	})
+	return result, err
+}
+
+// serveVariant valida la firma y sirve la variante pedida
+func (h *MediaHandler) serveVariant(c *fiber.Ctx, media models.Media, params ImageParams) error {
+	if len(h.SigningKey) == 0 {
+		return utils.Problem(c, fiber.StatusForbidden, "Image transformations are disabled")
+	}
+	expected := SignImageParams(h.SigningKey, media.ID, params)
+	if !hmac.Equal([]byte(expected), []byte(c.Query("s"))) {
+		return utils.Problem(c, fiber.StatusForbidden, "Invalid signature")
+	}
+	if !strings.HasPrefix(media.MimeType, "image/") {
+		return utils.Problem(c, fiber.StatusBadRequest, "Media is not an image")
+	}
+	// Las dimensiones guardadas al subir evitan leer el original; TransformImage
+	// vuelve a comprobarlas en la cabecera
+	if int64(media.Width)*int64(media.Height) > h.MaxPixels {
+		return utils.Problem(c, fiber.StatusUnprocessableEntity, "Image is too large to transform")
+	}
+
+	v, err := h.variant(c.UserContext(), media, params)
+	if errors.Is(err, ErrImageTooLarge) {
+		return utils.Problem(c, fiber.StatusUnprocessableEntity, "Image is too large to transform")
+	}
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to transform image", err)
+	}
+	return h.sendBlob(c, v.Key, v.Mime, media.SHA256+"-"+expected[:16])
+}
+
+// SignMediaURL devuelve una URL firmada para una variante (uso de los frontends)
+func (h *MediaHandler) SignMediaURL(c *fiber.Ctx) error {
+	if len(h.SigningKey) == 0 {
+		return utils.Problem(c, fiber.StatusForbidden, "Image transformations are disabled")
+	}
+	media, apiErr := h.findMedia(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	params, err := ParseImageParams(c.Query)
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"url": SignedImageURL(h.SigningKey, media.ID, params)})
+}
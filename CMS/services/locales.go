@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/language"
+)
+
+// --- Localización ---
+
+type TranslationRepository interface {
+	GetTranslation(ctx context.Context, contentID uint, locale string, translation *models.ContentTranslation) error
+	ListTranslations(ctx context.Context, contentID uint, translations *[]models.ContentTranslation) error
+	// SaveTranslation crea o reemplaza la traducción de ese contenido y locale
+	SaveTranslation(ctx context.Context, translation *models.ContentTranslation) error
+	DeleteTranslation(ctx context.Context, contentID uint, locale string) error
+	DeleteTranslations(ctx context.Context, contentID uint) error
+}
+
+// Locales es la lista de idiomas publicados. El primero es el idioma por defecto
+// y el último recurso de cualquier cadena de respaldo.
+type Locales struct {
+	Supported []string
+	Default   string
+	Fallbacks map[string][]string
+}
+
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// NewLocales lee la lista ("es,en") y los respaldos explícitos ("ca:es,pt:es")
+func NewLocales(list, fallbacks string) (*Locales, error) {
+	l := &Locales{Fallbacks: map[string][]string{}}
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		locale, err := normalizeLocale(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid locale %q", item)
+		}
+		l.Supported = append(l.Supported, locale)
+	}
+	if len(l.Supported) == 0 {
+		return nil, errors.New("at least one locale is required")
+	}
+	l.Default = l.Supported[0]
+
+	for _, item := range strings.Split(fallbacks, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid locale fallback %q", item)
+		}
+		fromLocale, err1 := normalizeLocale(from)
+		toLocale, err2 := normalizeLocale(to)
+		if err1 != nil || err2 != nil || !l.Supports(fromLocale) || !l.Supports(toLocale) {
+			return nil, fmt.Errorf("invalid locale fallback %q", item)
+		}
+		l.Fallbacks[fromLocale] = append(l.Fallbacks[fromLocale], toLocale)
+	}
+	return l, nil
+}
+
+func normalizeLocale(value string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return "", err
+	}
+	return tag.String(), nil
+}
+
+func (l *Locales) Supports(locale string) bool {
+	for _, s := range l.Supported {
+		if s == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Chain devuelve el orden en que se buscan las variantes: el locale pedido, sus
+// respaldos explícitos, su idioma base (es-MX -> es) y por último el de defecto
+func (l *Locales) Chain(locale string) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+	var add func(string)
+	add = func(candidate string) {
+		if seen[candidate] || !l.Supports(candidate) {
+			return
+		}
+		seen[candidate] = true
+		chain = append(chain, candidate)
+		for _, fallback := range l.Fallbacks[candidate] {
+			add(fallback)
+		}
+	}
+	add(locale)
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		add(base)
+	}
+	add(l.Default)
+	return chain
+}
+
+// Negotiate elige el locale de la petición: ?locale= tiene prioridad y debe ser
+// soportado; Accept-Language se recorre por peso y, si nada coincide, se usa el de defecto
+func (l *Locales) Negotiate(query, acceptLanguage string) (string, error) {
+	if query != "" {
+		locale, err := normalizeLocale(query)
+		if err != nil || !l.Supports(locale) {
+			return "", ErrUnsupportedLocale
+		}
+		return locale, nil
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return l.Default, nil
+	}
+	for _, tag := range tags {
+		locale := tag.String()
+		if l.Supports(locale) {
+			return locale, nil
+		}
+		base, _ := tag.Base()
+		if l.Supports(base.String()) {
+			return base.String(), nil
+		}
+	}
+	return l.Default, nil
+}
+
+func (h *ContentHandler) localized() bool {
+	return h.Locales != nil && h.Translations != nil
+}
+
+// sourceLocale es el idioma del contenido original; los contenidos previos a la
+// localización no lo tienen y se consideran en el idioma por defecto
+func (h *ContentHandler) sourceLocale(content models.Content) string {
+	if content.Locale == "" {
+		return h.Locales.Default
+	}
+	return content.Locale
+}
+
+// findTranslation recorre la cadena de respaldo hasta encontrar una variante.
+// Devuelve nil cuando lo que corresponde es el contenido original.
+func (h *ContentHandler) findTranslation(ctx context.Context, content models.Content, locale string) *models.ContentTranslation {
+	source := h.sourceLocale(content)
+	for _, candidate := range h.Locales.Chain(locale) {
+		if candidate == source {
+			return nil
+		}
+		var translation models.ContentTranslation
+		if err := h.Translations.GetTranslation(ctx, content.ID, candidate, &translation); err == nil {
+			return &translation
+		}
+	}
+	return nil
+}
+
+// translationView arma la vista de lectura con una variante traducida
+func (h *ContentHandler) translationView(ctx context.Context, content models.Content, translation *models.ContentTranslation) (fiber.Map, error) {
+	format := translation.Format
+	if format == "" {
+		format = FormatPlain
+	}
+	rendered := translation.Rendered
+	if rendered == nil || rendered.SourceHash != BodySourceHash(format, translation.Body) {
+		var err error
+		if rendered, err = RenderBody(format, translation.Body); err != nil {
+			return nil, utils.InternalError("Failed to render content body", err)
+		}
+		translation.Rendered = rendered
+		if err := h.Translations.SaveTranslation(ctx, translation); err != nil {
+			log.Printf("render: failed to cache rendered translation: %v", err)
+		}
+	}
+
+	title := translation.Title
+	if title == "" {
+		title = content.Title
+	}
+	response := fiber.Map{
+		"id":           content.ID,
+		"title":        title,
+		"slug":         content.Slug,
+		"author":       content.UserID,
+		"locale":       translation.Locale,
+		"description":  translation.Body,
+		"format":       format,
+		"html":         rendered.HTML,
+		"toc":          rendered.TOC,
+		"word_count":   rendered.WordCount,
+		"reading_time": rendered.ReadingTime,
+		"created_at":   content.CreatedAt,
+		"updated_at":   translation.UpdatedAt,
+	}
+	if h.Taxonomy != nil {
+		if err := h.Taxonomy.LoadContentTaxonomy(ctx, &content); err == nil {
+			response["tags"] = content.Tags
+			response["category"] = content.Category
+		}
+	}
+	return response, nil
+}
+
+// SaveTranslation crea o reemplaza la variante de un contenido en /:id/translations/:locale
+func (h *ContentHandler) SaveTranslation(c *fiber.Ctx) error {
+	content, locale, apiErr := h.translationTarget(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+
+	type TranslationRequest struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Body        string `json:"body"`
+		Format      string `json:"format"`
+	}
+	var req TranslationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.InvalidBody(c)
+	}
+	if req.Body == "" {
+		req.Body = req.Description
+	}
+	if req.Format == "" {
+		req.Format = FormatPlain
+	}
+	if !ValidBodyFormat(req.Format) {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid body format")
+	}
+	rendered, err := RenderBody(req.Format, req.Body)
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Failed to render body")
+	}
+
+	translation := models.ContentTranslation{
+		ContentID:   content.ID,
+		Locale:      locale,
+		Title:       req.Title,
+		Description: req.Description,
+		Body:        req.Body,
+		Format:      req.Format,
+		Rendered:    rendered,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if err := h.Translations.SaveTranslation(c.UserContext(), &translation); err != nil {
+		return utils.InternalProblem(c, "Failed to save translation", err)
+	}
+	h.ContentCache.Invalidate(c.UserContext(), contentTag(content.ID))
+	return c.Status(fiber.StatusOK).JSON(translation)
+}
+
+// ListTranslations devuelve todas las variantes de un contenido
+func (h *ContentHandler) ListTranslations(c *fiber.Ctx) error {
+	content, apiErr := h.localizedContent(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	translations := []models.ContentTranslation{}
+	if err := h.Translations.ListTranslations(c.UserContext(), content.ID, &translations); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve translations", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(translations)
+}
+
+// DeleteTranslation elimina una variante; las lecturas vuelven a usar la cadena de respaldo
+func (h *ContentHandler) DeleteTranslation(c *fiber.Ctx) error {
+	content, locale, apiErr := h.translationTarget(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	if err := h.Translations.DeleteTranslation(c.UserContext(), content.ID, locale); err != nil {
+		return utils.InternalProblem(c, "Failed to delete translation", err)
+	}
+	h.ContentCache.Invalidate(c.UserContext(), contentTag(content.ID))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Translation deleted successfully"})
+}
+
+// MissingTranslations informa qué locales faltan y cuáles quedaron desactualizados
+// respecto al original
+func (h *ContentHandler) MissingTranslations(c *fiber.Ctx) error {
+	content, apiErr := h.localizedContent(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	var translations []models.ContentTranslation
+	if err := h.Translations.ListTranslations(c.UserContext(), content.ID, &translations); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve translations", err)
+	}
+
+	source := h.sourceLocale(content)
+	existing := map[string]models.ContentTranslation{}
+	for _, t := range translations {
+		existing[t.Locale] = t
+	}
+	available, missing, outdated := []string{source}, []string{}, []string{}
+	for _, locale := range h.Locales.Supported {
+		if locale == source {
+			continue
+		}
+		t, ok := existing[locale]
+		switch {
+		case !ok:
+			missing = append(missing, locale)
+		case t.UpdatedAt.Before(content.UpdatedAt):
+			available = append(available, locale)
+			outdated = append(outdated, locale)
+		default:
+			available = append(available, locale)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"content_id":    content.ID,
+		"source_locale": source,
+		"available":     available,
+		"missing":       missing,
+		"outdated":      outdated,
+	})
+}
+
+func (h *ContentHandler) localizedContent(c *fiber.Ctx) (models.Content, *utils.Error) {
+	var content models.Content
+	if !h.localized() {
+		return content, utils.NewError(fiber.StatusServiceUnavailable, "Localization is not available")
+	}
+	if id, err := strconv.Atoi(c.Params("id")); err != nil || id <= 0 {
+		return content, utils.InvalidIDError("Invalid content ID")
+	}
+	if err := h.PG.GetContent(c.UserContext(), c.Params("id"), &content); err != nil {
+		return content, utils.StoreError(err, "Content", "Failed to retrieve content")
+	}
+	return content, nil
+}
+
+// translationTarget valida el contenido y el locale de la ruta; el idioma original
+// se edita en el propio contenido, no como traducción
+func (h *ContentHandler) translationTarget(c *fiber.Ctx) (models.Content, string, *utils.Error) {
+	content, apiErr := h.localizedContent(c)
+	if apiErr != nil {
+		return content, "", apiErr
+	}
+	locale, err := normalizeLocale(c.Params("locale"))
+	if err != nil || !h.Locales.Supports(locale) {
+		return content, "", utils.NewError(fiber.StatusBadRequest, "Unsupported locale")
+	}
+	if locale == h.sourceLocale(content) {
+		return content, "", utils.NewError(fiber.StatusBadRequest, "Locale is the content source locale")
+	}
+	return content, locale, nil
+}
+
+// --- Implementación con MongoDB ---
+
+// TranslationClient implementa TranslationRepository sobre la colección "translations" de Mongo
+type TranslationClient struct{}
+
+func (t *TranslationClient) collection() *mongo.Collection {
+	return database.MongoGetCollection("contents", "translations")
+}
+
+func (t *TranslationClient) GetTranslation(ctx context.Context, contentID uint, locale string, translation *models.ContentTranslation) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	filter := bson.M{"content_id": contentID, "locale": locale}
+	return t.collection().FindOne(ctx, filter).Decode(translation)
+}
+
+func (t *TranslationClient) ListTranslations(ctx context.Context, contentID uint, translations *[]models.ContentTranslation) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	opts := options.Find().SetSort(bson.D{{Key: "locale", Value: 1}})
+	cursor, err := t.collection().Find(ctx, bson.M{"content_id": contentID}, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, translations)
+}
+
+func (t *TranslationClient) SaveTranslation(ctx context.Context, translation *models.ContentTranslation) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	filter := bson.M{"content_id": translation.ContentID, "locale": translation.Locale}
+	_, err := t.collection().ReplaceOne(ctx, filter, translation, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (t *TranslationClient) DeleteTranslation(ctx context.Context, contentID uint, locale string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	_, err := t.collection().DeleteOne(ctx, bson.M{"content_id": contentID, "locale": locale})
+	return err
+}
+
+func (t *TranslationClient) DeleteTranslations(ctx context.Context, contentID uint) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	_, err := t.collection().DeleteMany(ctx, bson.M{"content_id": contentID})
+	return err
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// --- Biblioteca de medios ---
+
+type MediaRepository interface {
+	CreateMedia(ctx context.Context, media *models.Media) error
+	GetMedia(ctx context.Context, id uint, media *models.Media) error
+	FindMediaByHash(ctx context.Context, sha string, media *models.Media) error
+	ListMedia(ctx context.Context, limit, offset int, media *[]models.Media) error
+	DeleteMedia(ctx context.Context, media *models.Media) error
+}
+
+const DefaultMaxMediaSize = 10 << 20
+
+// Tipos aceptados según lo que detecta http.DetectContentType, no lo que declara el cliente
+var allowedMediaTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+}
+
+// MediaURLPrefix es la ruta pública con la que se sirven los archivos
+var MediaURLPrefix = "/media/"
+
+var mediaReference = regexp.MustCompile(`(src|href)="media:(\d+)"`)
+
+// ResolveMediaReferences convierte las referencias media:ID de los cuerpos en URLs
+// (p. ej. ![logo](media:12) en markdown) antes de sanear el HTML
+func ResolveMediaReferences(html string) string {
+	return mediaReference.ReplaceAllString(html, `$1="`+MediaURLPrefix+`$2"`)
+}
+
+type MediaHandler struct {
+	Repo    MediaRepository
+	Store   BlobStore
+	MaxSize int64
+	// MaxPixels limita las imágenes que se decodifican para generar variantes
+	MaxPixels int64
+	// Opcionales: sin clave de firma no se sirven variantes; Cache recuerda las ya generadas
+	SigningKey []byte
+	Cache      MediaCache
+}
+
+// MediaCache recuerda las variantes generadas y permite olvidarlas al borrar el original
+type MediaCache interface {
+	CacheRepository
+	DeletePrefix(ctx context.Context, prefix string) (int64, error)
+}
+
+func NewMediaHandler(repo MediaRepository, store BlobStore, maxSize int64) *MediaHandler {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMediaSize
+	}
+	return &MediaHandler{Repo: repo, Store: store, MaxSize: maxSize, MaxPixels: DefaultMaxImagePixels}
+}
+
+// UploadBodyLimit es el cuerpo máximo de una subida: el archivo más el resto del multipart
+func (h *MediaHandler) UploadBodyLimit() int {
+	return int(h.MaxSize) + 1<<20
+}
+
+// UploadMedia recibe un archivo multipart en el campo "file"
+func (h *MediaHandler) UploadMedia(c *fiber.Ctx) error {
+	userID, ok := utils.UserID(c)
+	if !ok {
+		return utils.Problem(c, fiber.StatusUnauthorized, "User not authorized")
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Missing file")
+	}
+	if fileHeader.Size > h.MaxSize {
+		return utils.Problem(c, fiber.StatusRequestEntityTooLarge, "File is too large")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid file")
+	}
+	defer file.Close()
+
+	// Leer como máximo MaxSize+1 bytes por si el tamaño declarado no es fiable
+	data, err := io.ReadAll(io.LimitReader(file, h.MaxSize+1))
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid file")
+	}
+	if int64(len(data)) > h.MaxSize {
+		return utils.Problem(c, fiber.StatusRequestEntityTooLarge, "File is too large")
+	}
+	if len(data) == 0 {
+		return utils.Problem(c, fiber.StatusBadRequest, "Empty file")
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	ext, ok := allowedMediaTypes[mimeType]
+	if !ok {
+		return utils.Problem(c, fiber.StatusUnsupportedMediaType, "Unsupported file type")
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var existing models.Media
+	if err := h.Repo.FindMediaByHash(c.UserContext(), hash, &existing); err == nil {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"duplicate": true, "media": existing})
+	} else if !utils.IsNotFound(err) {
+		return utils.InternalProblem(c, "Failed to retrieve media", err)
+	}
+
+	media := models.Media{
+		Filename:   filepath.Base(fileHeader.Filename),
+		MimeType:   mimeType,
+		Size:       int64(len(data)),
+		SHA256:     hash,
+		StorageKey: hash[:2] + "/" + hash + ext,
+		UserID:     userID,
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		media.Width = config.Width
+		media.Height = config.Height
+	}
+
+	if err := h.Store.Put(c.UserContext(), media.StorageKey, bytes.NewReader(data), media.Size, mimeType); err != nil {
+		return utils.InternalProblem(c, "Failed to store file", err)
+	}
+	if err := h.Repo.CreateMedia(c.UserContext(), &media); err != nil {
+		// Si otra subida del mismo archivo ganó la carrera, el blob es también el suyo
+		if !utils.IsConflict(err) {
+			_ = h.Store.Delete(c.UserContext(), media.StorageKey)
+		}
+		return utils.StoreProblem(c, err, "Media", "Failed to save media")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"duplicate": false, "media": media})
+}
+
+// ListMedia obtiene la biblioteca paginada
+func (h *MediaHandler) ListMedia(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	media := []models.Media{}
+	if err := h.Repo.ListMedia(c.UserContext(), limit, offset, &media); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve media", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(media)
+}
+
+// GetMediaInfo obtiene los metadatos de un archivo
+func (h *MediaHandler) GetMediaInfo(c *fiber.Ctx) error {
+	media, apiErr := h.findMedia(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	return c.Status(fiber.StatusOK).JSON(media)
+}
+
+// ServeMedia devuelve el archivo original, o una variante firmada si se piden w, h, fit o fmt
+func (h *MediaHandler) ServeMedia(c *fiber.Ctx) error {
+	media, apiErr := h.findMedia(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	if c.Query("w") != "" || c.Query("h") != "" || c.Query("fit") != "" || c.Query("fmt") != "" {
+		params, err := ParseImageParams(c.Query)
+		if err != nil {
+			return utils.Problem(c, fiber.StatusBadRequest, err.Error())
+		}
+		return h.serveVariant(c, media, params)
+	}
+	return h.sendBlob(c, media.StorageKey, media.MimeType, media.SHA256)
+}
+
+// DeleteMedia elimina el archivo, sus variantes y sus metadatos
+func (h *MediaHandler) DeleteMedia(c *fiber.Ctx) error {
+	media, apiErr := h.findMedia(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	if err := h.Repo.DeleteMedia(c.UserContext(), &media); err != nil {
+		return utils.InternalProblem(c, "Failed to delete media", err)
+	}
+	// La clave deriva del hash: si el mismo archivo se volvió a subir mientras se
+	// borraba, el blob y sus variantes son ahora de la nueva fila
+	var reused models.Media
+	if err := h.Repo.FindMediaByHash(c.UserContext(), media.SHA256, &reused); err == nil {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Media deleted successfully"})
+	} else if !utils.IsNotFound(err) {
+		return utils.InternalProblem(c, "Failed to delete file", err)
+	}
+	if err := h.Store.Delete(c.UserContext(), media.StorageKey); err != nil {
+		return utils.InternalProblem(c, "Failed to delete file", err)
+	}
+	prefix := variantPrefix(media)
+	if err := h.Store.DeletePrefix(c.UserContext(), prefix); err != nil {
+		return utils.InternalProblem(c, "Failed to delete image variants", err)
+	}
+	// Si quedara la marca, una nueva subida del mismo archivo serviría variantes que ya no existen
+	if h.Cache != nil {
+		if _, err := h.Cache.DeletePrefix(c.UserContext(), variantCachePrefix+prefix); err != nil {
+			log.Printf("media: failed to forget variants of %s: %v", media.SHA256, err)
+		}
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Media deleted successfully"})
+}
+
+func (h *MediaHandler) findMedia(c *fiber.Ctx) (models.Media, *utils.Error) {
+	var media models.Media
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return media, utils.InvalidIDError("Invalid media ID")
+	}
+	if err := h.Repo.GetMedia(c.UserContext(), uint(id), &media); err != nil {
+		return media, utils.StoreError(err, "Media", "Failed to retrieve media")
+	}
+	return media, nil
+}
+
+// sendBlob envía el blob sin cargarlo en memoria; el contenido es inmutable porque
+// la clave deriva del hash
+func (h *MediaHandler) sendBlob(c *fiber.Ctx, key, mimeType, etag string) error {
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" && strings.Trim(match, `"`) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	blob, size, err := h.Store.Get(c.UserContext(), key)
+	if errors.Is(err, ErrBlobNotFound) {
+		return utils.Problem(c, fiber.StatusNotFound, "File not found")
+	}
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to read file", err)
+	}
+	c.Set(fiber.HeaderContentType, mimeType)
+	c.Set(fiber.HeaderETag, `"`+etag+`"`)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	// fasthttp cierra blob cuando termina de enviarlo
+	return c.Status(fiber.StatusOK).SendStream(blob, int(size))
+}
+
+// --- Implementación con GORM ---
+
+type MediaClient struct{}
+
+func (m *MediaClient) CreateMedia(ctx context.Context, media *models.Media) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Create(media).Error
+}
+
+func (m *MediaClient) GetMedia(ctx context.Context, id uint, media *models.Media) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).First(media, id).Error
+}
+
+func (m *MediaClient) FindMediaByHash(ctx context.Context, sha string, media *models.Media) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Where("sha256 = ?", sha).First(media).Error
+}
+
+func (m *MediaClient) ListMedia(ctx context.Context, limit, offset int, media *[]models.Media) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Order("id DESC").Limit(limit).Offset(offset).Find(media).Error
+}
+
+func (m *MediaClient) DeleteMedia(ctx context.Context, media *models.Media) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Delete(media).Error
+}
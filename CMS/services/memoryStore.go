@@ -0,0 +1,691 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"JSanches/CMD/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// --- Almacenes en memoria para demos y pruebas de extremo a extremo ---
+//
+// Reproducen lo que el resto del código espera de Postgres, Mongo y Redis: los
+// mismos errores de "no encontrado", borrado lógico y unicidad. Los datos se
+// pierden al terminar el proceso.
+
+// MemoryContentStore implementa PostgresRepository y SlugRepository
+type MemoryContentStore struct {
+	mu        sync.RWMutex
+	contents  map[uint]models.Content
+	redirects map[string]models.SlugRedirect
+	nextID    uint
+}
+
+func NewMemoryContentStore() *MemoryContentStore {
+	return &MemoryContentStore{contents: map[uint]models.Content{}, redirects: map[string]models.SlugRedirect{}}
+}
+
+func (s *MemoryContentStore) CreateContent(ctx context.Context, content *models.Content) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if content.ID == 0 {
+		s.nextID++
+		content.ID = s.nextID
+	} else if _, ok := s.contents[content.ID]; ok {
+		return fmt.Errorf("%w: content %d", gorm.ErrDuplicatedKey, content.ID)
+	}
+	s.nextID = max(s.nextID, content.ID)
+	if content.Slug != "" && s.slugOwner(content.Slug) != 0 {
+		return fmt.Errorf("%w: slug %q", gorm.ErrDuplicatedKey, content.Slug)
+	}
+	now := time.Now()
+	if content.CreatedAt.IsZero() {
+		content.CreatedAt = now
+	}
+	if content.UpdatedAt.IsZero() {
+		content.UpdatedAt = now
+	}
+	s.contents[content.ID] = *content
+	return nil
+}
+
+func (s *MemoryContentStore) FindContents(ctx context.Context, contents *[]models.Content) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := make([]models.Content, 0, len(s.contents))
+	for _, content := range s.contents {
+		if !content.DeletedAt.Valid {
+			found = append(found, content)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	*contents = found
+	return nil
+}
+
+func (s *MemoryContentStore) GetContent(ctx context.Context, id string, content *models.Content) error {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return gorm.ErrRecordNotFound
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, ok := s.contents[uint(n)]
+	if !ok || found.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	*content = found
+	return nil
+}
+
+func (s *MemoryContentStore) SaveContent(ctx context.Context, content *models.Content) error {
+	if content.ID == 0 {
+		return s.CreateContent(ctx, content)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if owner := s.slugOwner(content.Slug); content.Slug != "" && owner != 0 && owner != content.ID {
+		return fmt.Errorf("%w: slug %q", gorm.ErrDuplicatedKey, content.Slug)
+	}
+	content.UpdatedAt = time.Now()
+	s.contents[content.ID] = *content
+	return nil
+}
+
+// DeleteContent hace un borrado lógico, como GORM con gorm.Model
+func (s *MemoryContentStore) DeleteContent(ctx context.Context, content *models.Content) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.contents[content.ID]
+	if !ok {
+		return nil
+	}
+	stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	s.contents[content.ID] = stored
+	content.DeletedAt = stored.DeletedAt
+	return nil
+}
+
+// slugOwner devuelve el contenido que usa slug, borrado o no; 0 si está libre
+func (s *MemoryContentStore) slugOwner(slug string) uint {
+	for id, content := range s.contents {
+		if content.Slug == slug {
+			return id
+		}
+	}
+	return 0
+}
+
+func (s *MemoryContentStore) SlugTaken(ctx context.Context, slug string, exceptContentID uint) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if owner := s.slugOwner(slug); owner != 0 && owner != exceptContentID {
+		return true, nil
+	}
+	redirect, ok := s.redirects[slug]
+	return ok && redirect.ContentID != exceptContentID, nil
+}
+
+func (s *MemoryContentStore) FindContentBySlug(ctx context.Context, slug string, content *models.Content) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if owner := s.slugOwner(slug); owner != 0 && !s.contents[owner].DeletedAt.Valid {
+		*content = s.contents[owner]
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (s *MemoryContentStore) FindRedirect(ctx context.Context, slug string, redirect *models.SlugRedirect) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, ok := s.redirects[slug]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	*redirect = found
+	return nil
+}
+
+func (s *MemoryContentStore) RecordRedirect(ctx context.Context, oldSlug string, contentID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redirects[oldSlug] = models.SlugRedirect{
+		ID:        uint(len(s.redirects) + 1),
+		OldSlug:   oldSlug,
+		ContentID: contentID,
+		CreatedAt: time.Now(),
+	}
+	return nil
+}
+
+// FindPublishedContents implementa FeedRepository sobre los contenidos en memoria
+func (s *MemoryContentStore) FindPublishedContents(ctx context.Context, filter FeedFilter, limit int, contents *[]models.Content) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := []models.Content{}
+	for _, content := range s.contents {
+		if !content.DeletedAt.Valid && inFeed(content, filter) {
+			found = append(found, content)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].PublishedAt.Equal(*found[j].PublishedAt) {
+			return found[i].PublishedAt.After(*found[j].PublishedAt)
+		}
+		return found[i].ID > found[j].ID
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	*contents = found
+	return nil
+}
+
+// FeedState sigue a FeedClient: cuenta los vivos y toma el último cambio incluyendo los borrados
+func (s *MemoryContentStore) FeedState(ctx context.Context, filter FeedFilter) (FeedState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var state FeedState
+	for _, content := range s.contents {
+		if !inFeed(content, filter) {
+			continue
+		}
+		if !content.DeletedAt.Valid {
+			state.Count++
+		}
+		for _, t := range []time.Time{content.UpdatedAt, *content.PublishedAt, content.DeletedAt.Time} {
+			if t.After(state.LastModified) {
+				state.LastModified = t
+			}
+		}
+	}
+	return state, nil
+}
+
+// inFeed aplica los filtros de FeedFilter a un contenido publicado
+func inFeed(content models.Content, filter FeedFilter) bool {
+	if content.PublishedAt == nil {
+		return false
+	}
+	if filter.AuthorID != 0 && content.UserID != filter.AuthorID {
+		return false
+	}
+	if len(filter.CategoryIDs) > 0 {
+		if content.CategoryID == nil || !slices.Contains(filter.CategoryIDs, *content.CategoryID) {
+			return false
+		}
+	}
+	if filter.TagSlug != "" {
+		return slices.ContainsFunc(content.Tags, func(tag models.Tag) bool { return tag.Slug == filter.TagSlug })
+	}
+	return true
+}
+
+// MemoryBodyStore implementa MongoRepository. Guarda los cuerpos como documentos
+// BSON y entiende los filtros de igualdad y las actualizaciones con $set que usa
+// el resto del código; cualquier otro operador devuelve ErrUnsupportedFilter.
+type MemoryBodyStore struct {
+	mu   sync.RWMutex
+	docs []bson.M
+}
+
+var ErrUnsupportedFilter = errors.New("unsupported filter")
+
+func NewMemoryBodyStore() *MemoryBodyStore {
+	return &MemoryBodyStore{}
+}
+
+func (s *MemoryBodyStore) InsertContentBody(ctx context.Context, contentBody *models.ContentBody) error {
+	doc, err := toDocument(contentBody)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Igual que el índice único content_id_unique de Mongo
+	if i, err := s.find(bson.M{"content_id": contentBody.ContentID}); err != nil || i >= 0 {
+		return fmt.Errorf("%w: body of content %d", gorm.ErrDuplicatedKey, contentBody.ContentID)
+	}
+	s.docs = append(s.docs, doc)
+	return nil
+}
+
+func (s *MemoryBodyStore) GetContentBody(ctx context.Context, filter interface{}, contentBody *models.ContentBody) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, err := s.find(filter)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		return mongo.ErrNoDocuments
+	}
+	return fromDocument(s.docs[i], contentBody)
+}
+
+// UpdateContentBody aplica $set a todos los documentos que cumplen el filtro, como UpdateMany
+func (s *MemoryBodyStore) UpdateContentBody(ctx context.Context, filter interface{}, update interface{}) error {
+	set, err := setFields(update)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, doc := range s.docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		for key, value := range set {
+			doc[key] = value
+		}
+		// Normaliza los tipos con un viaje por BSON, como si viniera de Mongo
+		if s.docs[i], err = toDocument(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryBodyStore) UpsertContentBody(ctx context.Context, contentBody *models.ContentBody) error {
+	doc, err := toDocument(contentBody)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.find(bson.M{"content_id": contentBody.ContentID})
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		s.docs = append(s.docs, doc)
+		return nil
+	}
+	// $set conserva los campos que contentBody no tiene, como title
+	for key, value := range doc {
+		s.docs[i][key] = value
+	}
+	return nil
+}
+
+// DeleteContentBody borra el primer documento que cumple el filtro, como DeleteOne
+func (s *MemoryBodyStore) DeleteContentBody(ctx context.Context, filter interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.find(filter)
+	if err != nil || i < 0 {
+		return err
+	}
+	s.docs = append(s.docs[:i], s.docs[i+1:]...)
+	return nil
+}
+
+func (s *MemoryBodyStore) find(filter interface{}) (int, error) {
+	for i, doc := range s.docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return -1, err
+		}
+		if ok {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+func toDocument(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	return doc, bson.Unmarshal(data, &doc)
+}
+
+func fromDocument(doc bson.M, v interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, v)
+}
+
+// matches evalúa un filtro de igualdad campo a campo
+func matches(doc bson.M, filter interface{}) (bool, error) {
+	conditions, err := equalityFilter(filter)
+	if err != nil {
+		return false, err
+	}
+	for key, want := range conditions {
+		if !sameValue(doc[key], want) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// equalityFilter convierte el filtro en documento y rechaza los operadores de
+// Mongo: solo se admiten igualdades campo a campo
+func equalityFilter(filter interface{}) (bson.M, error) {
+	conditions, err := toDocument(filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFilter, err)
+	}
+	for key, want := range conditions {
+		if strings.HasPrefix(key, "$") || strings.Contains(key, ".") {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFilter, key)
+		}
+		if nested, ok := want.(bson.M); ok {
+			for op := range nested {
+				if strings.HasPrefix(op, "$") {
+					return nil, fmt.Errorf("%w: operator %s", ErrUnsupportedFilter, op)
+				}
+			}
+		}
+	}
+	return conditions, nil
+}
+
+// sameValue compara como Mongo: los números son iguales aunque cambie el tipo
+func sameValue(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// setFields extrae los campos de una actualización {"$set": {...}}
+func setFields(update interface{}) (bson.M, error) {
+	doc, err := toDocument(update)
+	if err != nil {
+		return nil, err
+	}
+	set, ok := doc["$set"].(bson.M)
+	if !ok || len(doc) != 1 {
+		return nil, fmt.Errorf("%w: only $set updates are supported", ErrUnsupportedFilter)
+	}
+	for key := range set {
+		if strings.Contains(key, ".") {
+			return nil, fmt.Errorf("%w: nested field %s", ErrUnsupportedFilter, key)
+		}
+	}
+	return set, nil
+}
+
+// MemoryCache implementa CacheRepository con expiración
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// ErrCacheMiss es lo que devuelve Get para una clave ausente o expirada, como redis.Nil
+var ErrCacheMiss = errors.New("cache miss")
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]memoryCacheEntry{}}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return "", ErrCacheMiss
+	}
+	return entry.value, nil
+}
+
+// Set guarda value; expiration 0 significa sin expiración, como en Redis
+func (c *MemoryCache) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := memoryCacheEntry{value: value}
+	if expiration > 0 {
+		entry.expires = time.Now().Add(expiration)
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+// DeletePrefix borra las claves que empiezan por prefix, como RedisClient.DeletePrefix
+func (c *MemoryCache) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var deleted int64
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// MemoryUserStore implementa UserAdminRepository
+type MemoryUserStore struct {
+	mu     sync.RWMutex
+	users  map[uint]models.User
+	nextID uint
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: map[uint]models.User{}}
+}
+
+func (s *MemoryUserStore) Create(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.users {
+		if existing.Username == user.Username || existing.Email == user.Email {
+			return fmt.Errorf("%w: username or email", gorm.ErrDuplicatedKey)
+		}
+	}
+	if user.Role == "" {
+		user.Role = RoleAuthor
+	}
+	s.nextID++
+	user.ID = s.nextID
+	s.users[user.ID] = *user
+	return nil
+}
+
+// FindByUsernameOrEmail sigue la consulta de Postgres: coincide cualquiera de los dos
+func (s *MemoryUserStore) FindByUsernameOrEmail(ctx context.Context, username, email string, user *models.User) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]uint, 0, len(s.users))
+	for id := range s.users {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if existing := s.users[id]; existing.Username == username || existing.Email == email {
+			*user = existing
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (s *MemoryUserStore) Save(ctx context.Context, user *models.User) error {
+	if user.ID == 0 {
+		return s.Create(ctx, user)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user.ID] = *user
+	return nil
+}
+
+// MemoryMediaStore implementa MediaRepository; los archivos siguen en el BlobStore
+type MemoryMediaStore struct {
+	mu     sync.RWMutex
+	media  map[uint]models.Media
+	nextID uint
+}
+
+func NewMemoryMediaStore() *MemoryMediaStore {
+	return &MemoryMediaStore{media: map[uint]models.Media{}}
+}
+
+// CreateMedia respeta el índice único de sha256
+func (s *MemoryMediaStore) CreateMedia(ctx context.Context, media *models.Media) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.media {
+		if existing.SHA256 == media.SHA256 {
+			return fmt.Errorf("%w: media %s", gorm.ErrDuplicatedKey, media.SHA256)
+		}
+	}
+	s.nextID++
+	media.ID = s.nextID
+	if media.CreatedAt.IsZero() {
+		media.CreatedAt = time.Now()
+	}
+	s.media[media.ID] = *media
+	return nil
+}
+
+func (s *MemoryMediaStore) GetMedia(ctx context.Context, id uint, media *models.Media) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, ok := s.media[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	*media = found
+	return nil
+}
+
+func (s *MemoryMediaStore) FindMediaByHash(ctx context.Context, sha string, media *models.Media) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, found := range s.media {
+		if found.SHA256 == sha {
+			*media = found
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// ListMedia devuelve los más recientes primero, como MediaClient
+func (s *MemoryMediaStore) ListMedia(ctx context.Context, limit, offset int, media *[]models.Media) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := make([]models.Media, 0, len(s.media))
+	for _, m := range s.media {
+		found = append(found, m)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID > found[j].ID })
+	found = found[min(offset, len(found)):]
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	*media = found
+	return nil
+}
+
+func (s *MemoryMediaStore) DeleteMedia(ctx context.Context, media *models.Media) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.media, media.ID)
+	return nil
+}
+
+// MemoryTranslationStore implementa TranslationRepository
+type MemoryTranslationStore struct {
+	mu           sync.RWMutex
+	translations map[uint]map[string]models.ContentTranslation
+}
+
+func NewMemoryTranslationStore() *MemoryTranslationStore {
+	return &MemoryTranslationStore{translations: map[uint]map[string]models.ContentTranslation{}}
+}
+
+func (s *MemoryTranslationStore) GetTranslation(ctx context.Context, contentID uint, locale string, translation *models.ContentTranslation) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, ok := s.translations[contentID][locale]
+	if !ok {
+		return mongo.ErrNoDocuments
+	}
+	*translation = found
+	return nil
+}
+
+// ListTranslations ordena por locale, como TranslationClient
+func (s *MemoryTranslationStore) ListTranslations(ctx context.Context, contentID uint, translations *[]models.ContentTranslation) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := make([]models.ContentTranslation, 0, len(s.translations[contentID]))
+	for _, translation := range s.translations[contentID] {
+		found = append(found, translation)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Locale < found[j].Locale })
+	*translations = found
+	return nil
+}
+
+func (s *MemoryTranslationStore) SaveTranslation(ctx context.Context, translation *models.ContentTranslation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.translations[translation.ContentID] == nil {
+		s.translations[translation.ContentID] = map[string]models.ContentTranslation{}
+	}
+	s.translations[translation.ContentID][translation.Locale] = *translation
+	return nil
+}
+
+func (s *MemoryTranslationStore) DeleteTranslation(ctx context.Context, contentID uint, locale string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.translations[contentID], locale)
+	return nil
+}
+
+func (s *MemoryTranslationStore) DeleteTranslations(ctx context.Context, contentID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.translations, contentID)
+	return nil
+}
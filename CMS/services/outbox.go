@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+// --- Outbox transaccional entre Postgres y Mongo ---
+
+const (
+	OutboxUpsertBody = "body.upsert"
+	OutboxDeleteBody = "body.delete"
+)
+
+// OutboxRepository escribe la fila de Postgres y su evento en una sola transacción
+type OutboxRepository interface {
+	CreateContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error
+	SaveContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error
+	DeleteContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error
+	// ClaimEvents reserva hasta limit eventos listos durante lease. Un evento no se
+	// entrega mientras haya otro anterior pendiente para el mismo contenido.
+	ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	ClaimEvent(ctx context.Context, id uint, lease time.Duration) (bool, error)
+	CompleteEvent(ctx context.Context, id uint) error
+	FailEvent(ctx context.Context, event *models.OutboxEvent) error
+	ListEvents(ctx context.Context, status string, limit, offset int, events *[]models.OutboxEvent) error
+	// RetryEvent reencola un evento muerto. Devuelve ErrOutboxEventSuperseded si el
+	// contenido tiene un evento posterior, cuyo payload es más reciente.
+	RetryEvent(ctx context.Context, id uint) (bool, error)
+}
+
+var ErrOutboxEventSuperseded = errors.New("a newer event exists for the same content")
+
+// OutboxRelay aplica en Mongo los eventos del outbox. Aplicar un evento dos veces
+// deja el mismo resultado, así que un reintento tras un fallo parcial es seguro.
+type OutboxRelay struct {
+	Repo         OutboxRepository
+	Mongo        MongoRepository
+	Translations TranslationRepository
+	// Cache es opcional; se invalida cuando el relay termina de aplicar un evento
+	Cache       *ContentCache
+	BatchSize   int
+	Interval    time.Duration
+	Lease       time.Duration
+	MaxAttempts int
+}
+
+func NewOutboxRelay(repo OutboxRepository, mongo MongoRepository) *OutboxRelay {
+	return &OutboxRelay{
+		Repo:        repo,
+		Mongo:       mongo,
+		BatchSize:   50,
+		Interval:    2 * time.Second,
+		Lease:       time.Minute,
+		MaxAttempts: 10,
+	}
+}
+
+// Backoff es la espera antes del intento número attempts (1s, 2s, 4s... hasta 15 min)
+func (r *OutboxRelay) Backoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return 15 * time.Minute
+	}
+	return min(time.Second<<(attempts-1), 15*time.Minute)
+}
+
+// Apply ejecuta el lado Mongo de un evento
+func (r *OutboxRelay) Apply(ctx context.Context, event models.OutboxEvent) error {
+	switch event.Operation {
+	case OutboxUpsertBody:
+		format := event.Payload.Format
+		if format == "" {
+			format = FormatPlain
+		}
+		rendered, err := RenderBody(format, event.Payload.Body)
+		if err != nil {
+			return err
+		}
+		return r.Mongo.UpsertContentBody(ctx, &models.ContentBody{
+			ContentID: event.ContentID,
+			Body:      event.Payload.Body,
+			Format:    format,
+			Rendered:  rendered,
+		})
+	case OutboxDeleteBody:
+		if err := r.Mongo.DeleteContentBody(ctx, bson.M{"content_id": int(event.ContentID)}); err != nil {
+			return err
+		}
+		if r.Translations != nil {
+			return r.Translations.DeleteTranslations(ctx, event.ContentID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown outbox operation %q", event.Operation)
+	}
+}
+
+// Process aplica un evento ya reservado y registra el resultado
+func (r *OutboxRelay) Process(ctx context.Context, event models.OutboxEvent) error {
+	err := r.Apply(ctx, event)
+	if err == nil {
+		r.Cache.InvalidateContent(ctx, event.ContentID)
+		return r.Repo.CompleteEvent(ctx, event.ID)
+	}
+
+	event.Attempts++
+	event.LastError = err.Error()
+	if event.Attempts >= r.MaxAttempts {
+		event.Status = models.OutboxDead
+		log.Printf("outbox: event %d moved to dead letter after %d attempts: %v", event.ID, event.Attempts, err)
+	} else {
+		event.NextAttemptAt = time.Now().Add(r.Backoff(event.Attempts))
+	}
+	if failErr := r.Repo.FailEvent(ctx, &event); failErr != nil {
+		return errors.Join(err, failErr)
+	}
+	return err
+}
+
+// Dispatch intenta aplicar el evento enseguida para que la escritura se vea en la
+// misma petición; si falla o lo tiene otro proceso, lo termina el relay. No se
+// cancela con la petición para no dejar el evento reservado hasta que venza el lease.
+func (r *OutboxRelay) Dispatch(ctx context.Context, event models.OutboxEvent) {
+	ctx = context.WithoutCancel(ctx)
+	claimed, err := r.Repo.ClaimEvent(ctx, event.ID, r.Lease)
+	if err != nil || !claimed {
+		return
+	}
+	if err := r.Process(ctx, event); err != nil {
+		log.Printf("outbox: event %d deferred to relay: %v", event.ID, err)
+	}
+}
+
+// ProcessBatch procesa un lote y devuelve cuántos eventos se reservaron
+func (r *OutboxRelay) ProcessBatch(ctx context.Context) (int, error) {
+	events, err := r.Repo.ClaimEvents(ctx, r.BatchSize, r.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		_ = r.Process(ctx, event)
+	}
+	return len(events), nil
+}
+
+// Run procesa el outbox hasta que se cancele ctx
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		// Vaciar lo acumulado antes de volver a esperar
+		for {
+			n, err := r.ProcessBatch(ctx)
+			if err != nil {
+				log.Printf("outbox: failed to claim events: %v", err)
+			}
+			if err != nil || n < r.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func bodyEvent(body, format string) *models.OutboxEvent {
+	return &models.OutboxEvent{
+		Operation: OutboxUpsertBody,
+		Payload:   models.OutboxPayload{Body: body, Format: format},
+	}
+}
+
+// --- Dead letter ---
+
+type OutboxHandler struct {
+	Relay *OutboxRelay
+}
+
+func NewOutboxHandler(relay *OutboxRelay) *OutboxHandler {
+	return &OutboxHandler{Relay: relay}
+}
+
+// ListEvents lista los eventos por estado; por defecto los que agotaron los reintentos
+func (h *OutboxHandler) ListEvents(c *fiber.Ctx) error {
+	status := c.Query("status", models.OutboxDead)
+	if status != models.OutboxDead && status != models.OutboxPending && status != models.OutboxDone {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid status")
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	events := []models.OutboxEvent{}
+	if err := h.Relay.Repo.ListEvents(c.UserContext(), status, limit, offset, &events); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve outbox events", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(events)
+}
+
+// RetryEvent devuelve un evento muerto a la cola con los intentos a cero
+func (h *OutboxHandler) RetryEvent(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.InvalidID(c, "Invalid event ID")
+	}
+	retried, err := h.Relay.Repo.RetryEvent(c.UserContext(), uint(id))
+	if errors.Is(err, ErrOutboxEventSuperseded) {
+		return utils.Problem(c, fiber.StatusConflict, "A newer event for this content exists; retrying would overwrite it")
+	}
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to retry event", err)
+	}
+	if !retried {
+		return utils.Problem(c, fiber.StatusNotFound, "Dead event not found")
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Event scheduled for retry"})
+}
+
+// --- Implementación con GORM ---
+
+type OutboxClient struct{}
+
+// withEvent ejecuta write y guarda el evento en la misma transacción
+func (o *OutboxClient) withEvent(ctx context.Context, content *models.Content, event *models.OutboxEvent, write func(tx *gorm.DB) error) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		event.ContentID = content.ID
+		event.Status = models.OutboxPending
+		event.NextAttemptAt = time.Now()
+		return tx.Create(event).Error
+	})
+}
+
+func (o *OutboxClient) CreateContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error {
+	return o.withEvent(ctx, content, event, func(tx *gorm.DB) error { return tx.Create(content).Error })
+}
+
+func (o *OutboxClient) SaveContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error {
+	return o.withEvent(ctx, content, event, func(tx *gorm.DB) error { return tx.Save(content).Error })
+}
+
+func (o *OutboxClient) DeleteContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error {
+	return o.withEvent(ctx, content, event, func(tx *gorm.DB) error { return tx.Delete(content).Error })
+}
+
+// claimQuery reserva eventos moviendo next_attempt_at al fin del lease; SKIP LOCKED
+// permite varias instancias del relay sin entregar el mismo evento dos veces
+const claimQuery = `
+UPDATE outbox_events SET next_attempt_at = ?
+WHERE id IN (
+	SELECT e.id FROM outbox_events e
+	WHERE e.status = 'pending' AND e.next_attempt_at <= ? %s
+	AND NOT EXISTS (
+		SELECT 1 FROM outbox_events prev
+		WHERE prev.content_id = e.content_id AND prev.status = 'pending' AND prev.id < e.id
+	)
+	ORDER BY e.id LIMIT ? %s
+)
+RETURNING *`
+
+// skipLocked devuelve la cláusula de bloqueo de las reservas. SQLite no la tiene ni
+// la necesita: admite un solo escritor, así que el UPDATE ya es atómico.
+func skipLocked(db *gorm.DB) string {
+	if isSQLite(db) {
+		return ""
+	}
+	return "FOR UPDATE SKIP LOCKED"
+}
+
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+func (o *OutboxClient) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	now := time.Now()
+	var events []models.OutboxEvent
+	db := database.PostgresGetDB()
+	err := db.WithContext(ctx).Raw(fmt.Sprintf(claimQuery, "", skipLocked(db)), now.Add(lease), now, limit).Scan(&events).Error
+	return events, err
+}
+
+func (o *OutboxClient) ClaimEvent(ctx context.Context, id uint, lease time.Duration) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	now := time.Now()
+	var events []models.OutboxEvent
+	db := database.PostgresGetDB()
+	err := db.WithContext(ctx).Raw(fmt.Sprintf(claimQuery, "AND e.id = ?", skipLocked(db)), now.Add(lease), now, id, 1).Scan(&events).Error
+	return len(events) == 1, err
+}
+
+func (o *OutboxClient) CompleteEvent(ctx context.Context, id uint) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.OutboxDone, "processed_at": time.Now(), "last_error": ""}).Error
+}
+
+func (o *OutboxClient) FailEvent(ctx context.Context, event *models.OutboxEvent) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", event.ID).
+		Updates(map[string]interface{}{
+			"status":          event.Status,
+			"attempts":        event.Attempts,
+			"next_attempt_at": event.NextAttemptAt,
+			"last_error":      event.LastError,
+		}).Error
+}
+
+func (o *OutboxClient) ListEvents(ctx context.Context, status string, limit, offset int, events *[]models.OutboxEvent) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Where("status = ?", status).Order("id DESC").Limit(limit).Offset(offset).Find(events).Error
+}
+
+func (o *OutboxClient) RetryEvent(ctx context.Context, id uint) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	db := database.PostgresGetDB().WithContext(ctx)
+	// Un evento posterior del mismo contenido ya aplicó (o aplicará) un estado más
+	// nuevo: reintentar el muerto devolvería el cuerpo viejo o uno ya borrado
+	result := db.Model(&models.OutboxEvent{}).
+		Where("id = ? AND status = ?", id, models.OutboxDead).
+		Where("NOT EXISTS (SELECT 1 FROM outbox_events newer WHERE newer.content_id = outbox_events.content_id AND newer.id > outbox_events.id)").
+		Updates(map[string]interface{}{"status": models.OutboxPending, "attempts": 0, "next_attempt_at": time.Now()})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.RowsAffected > 0, result.Error
+	}
+	var dead int64
+	if err := db.Model(&models.OutboxEvent{}).Where("id = ? AND status = ?", id, models.OutboxDead).Count(&dead).Error; err != nil {
+		return false, err
+	}
+	if dead > 0 {
+		return false, ErrOutboxEventSuperseded
+	}
+	return false, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"JSanches/CMD/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Exportación e importación de contenidos ---
+
+// ContentRecord es un contenido con su cuerpo, una línea del formato JSON Lines
+// de export e import. Las etiquetas van por nombre para no depender de los ids.
+type ContentRecord struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Description string     `json:"description"`
+	Locale      string     `json:"locale,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	UserID      int        `json:"user_id"`
+	CategoryID  *uint      `json:"category_id,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Body        string     `json:"body"`
+	Format      string     `json:"format,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ExportContents escribe un ContentRecord por línea y devuelve cuántos escribió
+func (h *ContentHandler) ExportContents(ctx context.Context, w io.Writer) (int, error) {
+	var contents []models.Content
+	if err := h.PG.FindContents(ctx, &contents); err != nil {
+		return 0, err
+	}
+	encoder := json.NewEncoder(w)
+	for i, content := range contents {
+		if h.Taxonomy != nil {
+			if err := h.Taxonomy.LoadContentTaxonomy(ctx, &content); err != nil {
+				return i, fmt.Errorf("loading tags of content %d: %w", content.ID, err)
+			}
+		}
+		var body models.ContentBody
+		err := h.Mongo.GetContentBody(ctx, bson.M{"content_id": int(content.ID)}, &body)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return i, fmt.Errorf("loading body of content %d: %w", content.ID, err)
+		}
+		record := ContentRecord{
+			ID:          content.ID,
+			Title:       content.Title,
+			Slug:        content.Slug,
+			Description: content.Description,
+			Locale:      content.Locale,
+			PublishedAt: content.PublishedAt,
+			UserID:      content.UserID,
+			CategoryID:  content.CategoryID,
+			Body:        body.Body,
+			Format:      body.Format,
+			CreatedAt:   content.CreatedAt,
+			UpdatedAt:   content.UpdatedAt,
+		}
+		for _, tag := range content.Tags {
+			record.Tags = append(record.Tags, tag.Name)
+		}
+		if err := encoder.Encode(record); err != nil {
+			return i, err
+		}
+	}
+	return len(contents), nil
+}
+
+type ImportResult struct {
+	Created int `json:"created"`
+	// Skipped cuenta los registros cuyo slug ya existe; así importar dos veces no duplica
+	Skipped int `json:"skipped"`
+}
+
+// ImportContents crea un contenido por cada línea de r. Los ids se reasignan;
+// se conservan slug, fechas y autor.
+func (h *ContentHandler) ImportContents(ctx context.Context, r io.Reader) (ImportResult, error) {
+	var result ImportResult
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record ContentRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+		created, err := h.ImportContent(ctx, record)
+		if err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Skipped++
+		}
+	}
+	return result, scanner.Err()
+}
+
+// ImportContent crea el contenido salvo que su slug ya exista
+func (h *ContentHandler) ImportContent(ctx context.Context, record ContentRecord) (bool, error) {
+	if record.Slug != "" && h.Slugs != nil {
+		var existing models.Content
+		if err := h.Slugs.FindContentBySlug(ctx, record.Slug, &existing); err == nil {
+			return false, nil
+		}
+	}
+	if record.Format == "" {
+		record.Format = FormatPlain
+	}
+	if !ValidBodyFormat(record.Format) {
+		return false, fmt.Errorf("invalid body format %q", record.Format)
+	}
+	rendered, err := RenderBody(record.Format, record.Body)
+	if err != nil {
+		return false, err
+	}
+
+	content := models.Content{
+		Title:       record.Title,
+		Description: record.Description,
+		Locale:      record.Locale,
+		PublishedAt: record.PublishedAt,
+		UserID:      record.UserID,
+	}
+	content.CreatedAt = record.CreatedAt
+	content.UpdatedAt = record.UpdatedAt
+	if h.localized() && content.Locale == "" {
+		content.Locale = h.Locales.Default
+	}
+	slug, err := h.uniqueSlug(ctx, record.Slug, record.Title, 0)
+	if err != nil {
+		return false, err
+	}
+	content.Slug = slug
+	if apiErr := h.assignTaxonomy(ctx, &content, record.Tags, record.CategoryID); apiErr != nil {
+		return false, apiErr
+	}
+	if apiErr := h.storeNewContent(ctx, &content, record.Body, record.Format, rendered); apiErr != nil {
+		return false, apiErr
+	}
+
+	events := []string{EventContentCreated}
+	if content.PublishedAt != nil {
+		events = append(events, EventContentPublished)
+	}
+	h.contentChanged(ctx, content, record.Body, events...)
+	return true, nil
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+
+	"JSanches/CMD/models"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"go.mongodb.org/mongo-driver/bson"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// --- Renderizado de cuerpos a HTML saneado ---
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPlain    = "plain"
+
+	// rendererVersion forma parte del hash: al cambiarla se invalidan los renderizados guardados
+	rendererVersion = "2"
+	wordsPerMinute  = 200
+)
+
+var (
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// El HTML crudo se permite aquí porque después pasa por la política de saneado
+		goldmark.WithRendererOptions(goldhtml.WithUnsafe()),
+	)
+	anchorID  = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+	sanitizer = newSanitizerPolicy()
+	stripper  = bluemonday.StrictPolicy()
+)
+
+func newSanitizerPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").Matching(anchorID).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code", "pre")
+	policy.RequireNoReferrerOnLinks(true)
+	return policy
+}
+
+// ValidBodyFormat indica si el formato declarado es soportado
+func ValidBodyFormat(format string) bool {
+	return format == FormatMarkdown || format == FormatHTML || format == FormatPlain
+}
+
+// BodySourceHash identifica un cuerpo y su formato para el caché del renderizado
+func BodySourceHash(format, body string) string {
+	sum := sha256.Sum256([]byte(rendererVersion + "\x00" + format + "\x00" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+// RenderBody convierte el cuerpo a HTML saneado con índice, anclas en los
+// encabezados, número de palabras y tiempo de lectura en minutos
+func RenderBody(format, body string) (*models.RenderedBody, error) {
+	if format == "" {
+		format = FormatPlain
+	}
+
+	var raw string
+	var toc []models.TOCEntry
+	var err error
+	switch format {
+	case FormatMarkdown:
+		raw, toc, err = renderMarkdown(body)
+	case FormatHTML:
+		raw, toc, err = renderHTML(body)
+	case FormatPlain:
+		raw = renderPlain(body)
+	default:
+		return nil, fmt.Errorf("unknown body format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	safe := sanitizer.Sanitize(ResolveMediaReferences(raw))
+	// El espacio tras cada etiqueta evita unir palabras de bloques contiguos al quitarlas
+	words := len(strings.Fields(html.UnescapeString(stripper.Sanitize(strings.ReplaceAll(safe, ">", "> ")))))
+	reading := 0
+	if words > 0 {
+		reading = (words + wordsPerMinute - 1) / wordsPerMinute
+	}
+	if toc == nil {
+		toc = []models.TOCEntry{}
+	}
+
+	return &models.RenderedBody{
+		HTML:        safe,
+		TOC:         toc,
+		WordCount:   words,
+		ReadingTime: reading,
+		SourceHash:  BodySourceHash(format, body),
+	}, nil
+}
+
+func renderMarkdown(body string) (string, []models.TOCEntry, error) {
+	source := []byte(body)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	var toc []models.TOCEntry
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		id, _ := heading.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		toc = append(toc, models.TOCEntry{
+			Level: heading.Level,
+			Text:  markdownText(heading, source),
+			ID:    string(idBytes),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		return "", nil, err
+	}
+	return buf.String(), toc, nil
+}
+
+func markdownText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if t, ok := child.(*ast.Text); ok {
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		b.WriteString(markdownText(child, source))
+	}
+	return b.String()
+}
+
+// renderHTML agrega ids a los encabezados que no lo tengan y arma el índice
+func renderHTML(body string) (string, []models.TOCEntry, error) {
+	nodes, err := xhtml.ParseFragment(strings.NewReader(body), &xhtml.Node{Type: xhtml.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var toc []models.TOCEntry
+	used := make(map[string]int)
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode && len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6' {
+			title := strings.TrimSpace(htmlText(n))
+			id := ""
+			for _, attr := range n.Attr {
+				if attr.Key == "id" {
+					id = attr.Val
+				}
+			}
+			if id == "" {
+				id = uniqueAnchor(Slugify(title), used)
+				n.Attr = append(n.Attr, xhtml.Attribute{Key: "id", Val: id})
+			}
+			toc = append(toc, models.TOCEntry{Level: int(n.Data[1] - '0'), Text: title, ID: id})
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		walk(n)
+		if err := xhtml.Render(&buf, n); err != nil {
+			return "", nil, err
+		}
+	}
+	return buf.String(), toc, nil
+}
+
+func htmlText(n *xhtml.Node) string {
+	if n.Type == xhtml.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(htmlText(child))
+	}
+	return b.String()
+}
+
+func uniqueAnchor(base string, used map[string]int) string {
+	if base == "" {
+		base = "section"
+	}
+	used[base]++
+	if used[base] == 1 {
+		return base
+	}
+	return fmt.Sprintf("%s-%d", base, used[base]-1)
+}
+
+// renderPlain escapa el texto: las líneas en blanco separan párrafos
+func renderPlain(body string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		lines := strings.Split(paragraph, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+		b.WriteString("<p>" + strings.Join(lines, "<br>") + "</p>")
+	}
+	return b.String()
+}
+
+// ensureRendered devuelve el renderizado guardado o lo recalcula si el cuerpo cambió
+func (h *ContentHandler) ensureRendered(ctx context.Context, filter interface{}, body *models.ContentBody) (*models.RenderedBody, error) {
+	format := body.Format
+	if format == "" {
+		format = FormatPlain
+	}
+	if body.Rendered != nil && body.Rendered.SourceHash == BodySourceHash(format, body.Body) {
+		return body.Rendered, nil
+	}
+
+	rendered, err := RenderBody(format, body.Body)
+	if err != nil {
+		return nil, err
+	}
+	// Guardar el resultado es solo una optimización; si falla se recalcula en la próxima lectura
+	update := bson.M{"$set": bson.M{"rendered": rendered}}
+	if err := h.Mongo.UpdateContentBody(ctx, filter, update); err != nil {
+		log.Printf("render: failed to cache rendered body: %v", err)
+	}
+	body.Rendered = rendered
+	return rendered, nil
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// --- Búsqueda de texto completo ---
+
+// SearchDocument es la representación de un contenido dentro del índice
+type SearchDocument struct {
+	ContentID uint      `json:"content_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SearchQuery agrupa el texto a buscar y los filtros opcionales
+type SearchQuery struct {
+	Text     string
+	AuthorID int
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+// SearchHit es un resultado de búsqueda ordenado por relevancia
+type SearchHit struct {
+	ContentID uint      `json:"content_id"`
+	Title     string    `json:"title"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Score     float64   `json:"score"`
+	Snippet   string    `json:"snippet"`
+}
+
+// SearchIndex es la interfaz que implementa cada motor de búsqueda
+type SearchIndex interface {
+	Index(ctx context.Context, doc SearchDocument) error
+	Delete(ctx context.Context, contentID uint) error
+	Search(ctx context.Context, query SearchQuery) ([]SearchHit, int, error)
+	Reset(ctx context.Context) error
+}
+
+var ErrEmptySearchQuery = errors.New("empty search query")
+
+// NewSearchIndex crea el índice indicado por backend ("postgres" o "memory")
+func NewSearchIndex(backend string, db *gorm.DB, path, language string) (SearchIndex, error) {
+	switch backend {
+	case "postgres":
+		if db == nil || db.Dialector.Name() != "postgres" {
+			return nil, errors.New("search backend postgres requires postgres storage")
+		}
+		return NewPostgresSearchIndex(db, language), nil
+	case "", "memory":
+		return NewMemoryIndex(path)
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", backend)
+	}
+}
+
+// queryClause es una palabra, una frase entre comillas o un prefijo (palabra*)
+type queryClause struct {
+	Terms  []string
+	Prefix bool
+}
+
+// parseSearchQuery separa la consulta en cláusulas que deben cumplirse todas
+func parseSearchQuery(q string) []queryClause {
+	var clauses []queryClause
+	parts := strings.Split(q, `"`)
+	for i, part := range parts {
+		// Los segmentos impares están entre comillas
+		if i%2 == 1 {
+			terms := tokenTerms(part)
+			if len(terms) > 0 {
+				clauses = append(clauses, queryClause{Terms: terms})
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			prefix := strings.HasSuffix(field, "*")
+			terms := tokenTerms(field)
+			for j, term := range terms {
+				clauses = append(clauses, queryClause{
+					Terms:  []string{term},
+					Prefix: prefix && j == len(terms)-1,
+				})
+			}
+		}
+	}
+	return clauses
+}
+
+type searchToken struct {
+	Term       string
+	Start, End int
+}
+
+// tokenize divide el texto en palabras normalizadas (minúsculas y sin acentos)
+// conservando la posición original para poder resaltar fragmentos
+func tokenize(s string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, searchToken{Term: normalizeTerm(s[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, searchToken{Term: normalizeTerm(s[start:]), Start: start, End: len(s)})
+	}
+	return tokens
+}
+
+// normalizeText une los términos de s con espacios, para indexar en Postgres el
+// mismo texto sin acentos con el que se comparan las consultas
+func normalizeText(s string) string {
+	return strings.Join(tokenTerms(s), " ")
+}
+
+func tokenTerms(s string) []string {
+	tokens := tokenize(s)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+func normalizeTerm(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(word)) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// searchBatcher lo implementan los índices que agrupan el guardado de varias escrituras
+type searchBatcher interface {
+	Batch(ctx context.Context, fn func() error) error
+}
+
+// ReindexAll vacía el índice y lo reconstruye a partir de Postgres y Mongo
+func ReindexAll(ctx context.Context, pg PostgresRepository, mongo MongoRepository, index SearchIndex) (int, error) {
+	var contents []models.Content
+	reindex := func() error {
+		if err := index.Reset(ctx); err != nil {
+			return err
+		}
+		if err := pg.FindContents(ctx, &contents); err != nil {
+			return err
+		}
+		for _, content := range contents {
+			var body models.ContentBody
+			if err := mongo.GetContentBody(ctx, bson.M{"content_id": int(content.ID)}, &body); err != nil {
+				log.Printf("reindex: content %d has no body: %v", content.ID, err)
+			}
+			if err := index.Index(ctx, searchDocumentFor(content, body.Body)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if batcher, ok := index.(searchBatcher); ok {
+		err = batcher.Batch(ctx, reindex)
+	} else {
+		err = reindex()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(contents), nil
+}
+
+func searchDocumentFor(content models.Content, body string) SearchDocument {
+	return SearchDocument{
+		ContentID: content.ID,
+		Title:     content.Title,
+		Body:      body,
+		UserID:    content.UserID,
+		CreatedAt: content.CreatedAt,
+	}
+}
+
+// indexContent mantiene el índice sincronizado; un fallo no debe romper la escritura
+func (h *ContentHandler) indexContent(ctx context.Context, content models.Content, body string) {
+	if h.Search == nil {
+		return
+	}
+	if err := h.Search.Index(ctx, searchDocumentFor(content, body)); err != nil {
+		log.Printf("search: failed to index content %d: %v", content.ID, err)
+	}
+}
+
+func (h *ContentHandler) unindexContent(ctx context.Context, contentID uint) {
+	if h.Search == nil {
+		return
+	}
+	if err := h.Search.Delete(ctx, contentID); err != nil {
+		log.Printf("search: failed to remove content %d: %v", contentID, err)
+	}
+}
+
+// SearchContents busca contenidos por título y cuerpo
+func (h *ContentHandler) SearchContents(c *fiber.Ctx) error {
+	if h.Search == nil {
+		return utils.Problem(c, fiber.StatusServiceUnavailable, "Search is not available")
+	}
+
+	query := SearchQuery{
+		Text:   c.Query("q"),
+		Limit:  c.QueryInt("limit", 20),
+		Offset: c.QueryInt("offset", 0),
+	}
+	if strings.TrimSpace(query.Text) == "" {
+		return utils.Problem(c, fiber.StatusBadRequest, "Missing search query")
+	}
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	if author := c.Query("author"); author != "" {
+		authorID, err := strconv.Atoi(author)
+		if err != nil {
+			return utils.Problem(c, fiber.StatusBadRequest, "Invalid author")
+		}
+		query.AuthorID = authorID
+	}
+
+	var err error
+	if query.From, err = parseDateParam(c.Query("from")); err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid from date")
+	}
+	if query.To, err = parseDateParam(c.Query("to")); err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid to date")
+	}
+
+	hits, total, err := h.Search.Search(c.UserContext(), query)
+	if errors.Is(err, ErrEmptySearchQuery) {
+		return utils.Problem(c, fiber.StatusBadRequest, "Missing search query")
+	}
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to search contents", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"query":   query.Text,
+		"total":   total,
+		"results": hits,
+	})
+}
+
+// parseDateParam acepta RFC3339 o solo la fecha (2006-01-02)
+func parseDateParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"html"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryIndex es un índice invertido embebido en el proceso. Si tiene una ruta
+// configurada, guarda los documentos en disco para sobrevivir reinicios.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	path     string
+	batching int
+	docs     map[uint]*memoryDoc
+	postings map[string]map[uint]struct{}
+}
+
+type memoryDoc struct {
+	Doc   SearchDocument
+	Title []searchToken
+	Body  []searchToken
+}
+
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleBoost  = 2.0
+	snippetSize = 30
+)
+
+func NewMemoryIndex(path string) (*MemoryIndex, error) {
+	idx := &MemoryIndex{
+		path:     path,
+		docs:     make(map[uint]*memoryDoc),
+		postings: make(map[string]map[uint]struct{}),
+	}
+	if path == "" {
+		return idx, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var docs []SearchDocument
+	if err := gob.NewDecoder(file).Decode(&docs); err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		idx.add(doc)
+	}
+	return idx, nil
+}
+
+func (m *MemoryIndex) Index(ctx context.Context, doc SearchDocument) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(doc.ContentID)
+	m.add(doc)
+	return m.save()
+}
+
+func (m *MemoryIndex) Delete(ctx context.Context, contentID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(contentID)
+	return m.save()
+}
+
+func (m *MemoryIndex) Reset(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.docs = make(map[uint]*memoryDoc)
+	m.postings = make(map[string]map[uint]struct{})
+	return m.save()
+}
+
+// Batch ejecuta fn y guarda el índice una sola vez al terminar. Cada escritura
+// reescribe el archivo entero, así que sin Batch reindexar N documentos
+// escribiría N veces el índice completo.
+func (m *MemoryIndex) Batch(ctx context.Context, fn func() error) error {
+	m.mu.Lock()
+	m.batching++
+	m.mu.Unlock()
+
+	err := fn()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batching--
+	if saveErr := m.save(); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, query SearchQuery) ([]SearchHit, int, error) {
+	clauses := parseSearchQuery(query.Text)
+	if len(clauses) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Candidatos: documentos que contienen algún término de cada cláusula
+	candidates := m.candidates(clauses[0])
+	for _, clause := range clauses[1:] {
+		next := m.candidates(clause)
+		for id := range candidates {
+			if _, ok := next[id]; !ok {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	avgTitle, avgBody := m.averageLengths()
+	idf := make([]float64, len(clauses))
+	for i, clause := range clauses {
+		df := 0
+		for _, doc := range m.docs {
+			if clauseFrequency(doc.Title, clause) > 0 || clauseFrequency(doc.Body, clause) > 0 {
+				df++
+			}
+		}
+		n := float64(len(m.docs))
+		idf[i] = math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+	}
+
+	var hits []SearchHit
+	for id := range candidates {
+		doc := m.docs[id]
+		if !matchesFilters(doc.Doc, query) {
+			continue
+		}
+
+		score := 0.0
+		matched := true
+		for i, clause := range clauses {
+			titleTF := clauseFrequency(doc.Title, clause)
+			bodyTF := clauseFrequency(doc.Body, clause)
+			if titleTF == 0 && bodyTF == 0 {
+				// Las frases necesitan los términos consecutivos, no solo presentes
+				matched = false
+				break
+			}
+			score += idf[i] * (titleBoost*bm25(titleTF, len(doc.Title), avgTitle) + bm25(bodyTF, len(doc.Body), avgBody))
+		}
+		if !matched {
+			continue
+		}
+
+		hits = append(hits, SearchHit{
+			ContentID: doc.Doc.ContentID,
+			Title:     doc.Doc.Title,
+			UserID:    doc.Doc.UserID,
+			CreatedAt: doc.Doc.CreatedAt,
+			Score:     score,
+			Snippet:   highlight(doc.Doc.Body, doc.Body, clauses),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ContentID < hits[j].ContentID
+	})
+
+	total := len(hits)
+	if query.Offset >= total {
+		return []SearchHit{}, total, nil
+	}
+	hits = hits[query.Offset:]
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, total, nil
+}
+
+func (m *MemoryIndex) add(doc SearchDocument) {
+	entry := &memoryDoc{Doc: doc, Title: tokenize(doc.Title), Body: tokenize(doc.Body)}
+	m.docs[doc.ContentID] = entry
+	for _, tokens := range [][]searchToken{entry.Title, entry.Body} {
+		for _, t := range tokens {
+			if m.postings[t.Term] == nil {
+				m.postings[t.Term] = make(map[uint]struct{})
+			}
+			m.postings[t.Term][doc.ContentID] = struct{}{}
+		}
+	}
+}
+
+func (m *MemoryIndex) remove(contentID uint) {
+	entry, ok := m.docs[contentID]
+	if !ok {
+		return
+	}
+	for _, tokens := range [][]searchToken{entry.Title, entry.Body} {
+		for _, t := range tokens {
+			delete(m.postings[t.Term], contentID)
+			if len(m.postings[t.Term]) == 0 {
+				delete(m.postings, t.Term)
+			}
+		}
+	}
+	delete(m.docs, contentID)
+}
+
+// save persiste salvo dentro de un Batch, que lo hará al final
+func (m *MemoryIndex) save() error {
+	if m.batching > 0 {
+		return nil
+	}
+	return m.persist()
+}
+
+// persist escribe los documentos en disco de forma atómica (archivo temporal + rename)
+func (m *MemoryIndex) persist() error {
+	if m.path == "" {
+		return nil
+	}
+	docs := make([]SearchDocument, 0, len(m.docs))
+	for _, entry := range m.docs {
+		docs = append(docs, entry.Doc)
+	}
+
+	tmp := m.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(docs); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+func (m *MemoryIndex) candidates(clause queryClause) map[uint]struct{} {
+	result := make(map[uint]struct{})
+	first := clause.Terms[0]
+	if clause.Prefix {
+		for term, ids := range m.postings {
+			if strings.HasPrefix(term, first) {
+				for id := range ids {
+					result[id] = struct{}{}
+				}
+			}
+		}
+		return result
+	}
+	for id := range m.postings[first] {
+		result[id] = struct{}{}
+	}
+	return result
+}
+
+func (m *MemoryIndex) averageLengths() (float64, float64) {
+	if len(m.docs) == 0 {
+		return 0, 0
+	}
+	var title, body int
+	for _, doc := range m.docs {
+		title += len(doc.Title)
+		body += len(doc.Body)
+	}
+	n := float64(len(m.docs))
+	return float64(title) / n, float64(body) / n
+}
+
+func matchesFilters(doc SearchDocument, query SearchQuery) bool {
+	if query.AuthorID != 0 && doc.UserID != query.AuthorID {
+		return false
+	}
+	if query.From != nil && doc.CreatedAt.Before(*query.From) {
+		return false
+	}
+	if query.To != nil && doc.CreatedAt.After(*query.To) {
+		return false
+	}
+	return true
+}
+
+func bm25(tf, length int, avg float64) float64 {
+	if tf == 0 {
+		return 0
+	}
+	norm := 1.0
+	if avg > 0 {
+		norm = 1 - bm25B + bm25B*float64(length)/avg
+	}
+	f := float64(tf)
+	return f * (bm25K1 + 1) / (f + bm25K1*norm)
+}
+
+// clauseFrequency cuenta cuántas veces aparece la cláusula en la lista de tokens
+func clauseFrequency(tokens []searchToken, clause queryClause) int {
+	count := 0
+	for i := range tokens {
+		if clauseMatchesAt(tokens, i, clause) {
+			count++
+		}
+	}
+	return count
+}
+
+func clauseMatchesAt(tokens []searchToken, i int, clause queryClause) bool {
+	if i+len(clause.Terms) > len(tokens) {
+		return false
+	}
+	for j, term := range clause.Terms {
+		got := tokens[i+j].Term
+		if clause.Prefix && j == len(clause.Terms)-1 {
+			if !strings.HasPrefix(got, term) {
+				return false
+			}
+		} else if got != term {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight devuelve un fragmento del cuerpo alrededor de la primera coincidencia
+// con los términos marcados con <mark>. El resto del texto se escapa.
+func highlight(text string, tokens []searchToken, clauses []queryClause) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	marked := make([]bool, len(tokens))
+	first := -1
+	for i := range tokens {
+		for _, clause := range clauses {
+			if clauseMatchesAt(tokens, i, clause) {
+				for j := range clause.Terms {
+					marked[i+j] = true
+				}
+				if first < 0 {
+					first = i
+				}
+			}
+		}
+	}
+
+	start := 0
+	if first > snippetSize/3 {
+		start = first - snippetSize/3
+	}
+	end := start + snippetSize
+	if end > len(tokens) {
+		end = len(tokens)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	pos := tokens[start].Start
+	for i := start; i < end; i++ {
+		b.WriteString(html.EscapeString(text[pos:tokens[i].Start]))
+		word := html.EscapeString(text[tokens[i].Start:tokens[i].End])
+		if marked[i] {
+			b.WriteString("<mark>" + word + "</mark>")
+		} else {
+			b.WriteString(word)
+		}
+		pos = tokens[i].End
+	}
+	if end < len(tokens) {
+		b.WriteString(" …")
+	}
+	return b.String()
+}
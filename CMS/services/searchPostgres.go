@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"JSanches/CMD/database"
+
+	"gorm.io/gorm"
+)
+
+// PostgresSearchIndex implementa SearchIndex con tsvector y un índice GIN
+type PostgresSearchIndex struct {
+	DB       *gorm.DB
+	Language string
+}
+
+// NewPostgresSearchIndex usa la tabla search_documents de la migración 0004.
+// language es la configuración de text search de Postgres ("simple" por defecto).
+func NewPostgresSearchIndex(db *gorm.DB, language string) *PostgresSearchIndex {
+	if language == "" {
+		language = "simple"
+	}
+	return &PostgresSearchIndex{DB: db, Language: language}
+}
+
+func (p *PostgresSearchIndex) Index(ctx context.Context, doc SearchDocument) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	// El título pesa más (A) que el cuerpo (B) en el ranking. tsv se calcula sobre
+	// el texto normalizado porque los términos de la consulta llegan sin acentos.
+	return p.DB.WithContext(ctx).Exec(`INSERT INTO search_documents (content_id, title, body, user_id, created_at, tsv)
+		VALUES (@id, @title, @body, @user, @created,
+			setweight(to_tsvector(CAST(@lang AS regconfig), @titleTerms), 'A') || setweight(to_tsvector(CAST(@lang AS regconfig), @bodyTerms), 'B'))
+		ON CONFLICT (content_id) DO UPDATE SET
+			title = EXCLUDED.title, body = EXCLUDED.body, user_id = EXCLUDED.user_id,
+			created_at = EXCLUDED.created_at, tsv = EXCLUDED.tsv`,
+		map[string]interface{}{
+			"id":         doc.ContentID,
+			"title":      doc.Title,
+			"body":       doc.Body,
+			"titleTerms": normalizeText(doc.Title),
+			"bodyTerms":  normalizeText(doc.Body),
+			"user":       doc.UserID,
+			"created":    doc.CreatedAt,
+			"lang":       p.Language,
+		}).Error
+}
+
+func (p *PostgresSearchIndex) Delete(ctx context.Context, contentID uint) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return p.DB.WithContext(ctx).Exec(`DELETE FROM search_documents WHERE content_id = ?`, contentID).Error
+}
+
+func (p *PostgresSearchIndex) Reset(ctx context.Context) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return p.DB.WithContext(ctx).Exec(`TRUNCATE search_documents`).Error
+}
+
+func (p *PostgresSearchIndex) Search(ctx context.Context, query SearchQuery) ([]SearchHit, int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	clauses := parseSearchQuery(query.Text)
+	tsquery := buildTSQuery(clauses)
+	if tsquery == "" {
+		return nil, 0, ErrEmptySearchQuery
+	}
+
+	// El fragmento se arma con highlight, como en MemoryIndex: ts_headline
+	// devolvería sin escapar el HTML que tenga el cuerpo
+	sql := `SELECT content_id, title, body, user_id, created_at,
+			ts_rank_cd(tsv, q) AS score,
+			count(*) OVER() AS total
+		FROM search_documents, to_tsquery(CAST(@lang AS regconfig), @query) q
+		WHERE tsv @@ q`
+	args := map[string]interface{}{
+		"lang":   p.Language,
+		"query":  tsquery,
+		"limit":  query.Limit,
+		"offset": query.Offset,
+	}
+	if query.AuthorID != 0 {
+		sql += ` AND user_id = @author`
+		args["author"] = query.AuthorID
+	}
+	if query.From != nil {
+		sql += ` AND created_at >= @from`
+		args["from"] = *query.From
+	}
+	if query.To != nil {
+		sql += ` AND created_at <= @to`
+		args["to"] = *query.To
+	}
+	sql += ` ORDER BY score DESC, content_id LIMIT @limit OFFSET @offset`
+
+	var rows []struct {
+		ContentID uint
+		Title     string
+		Body      string
+		UserID    int
+		CreatedAt time.Time
+		Score     float64
+		Total     int
+	}
+	if err := p.DB.WithContext(ctx).Raw(sql, args).Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]SearchHit, len(rows))
+	total := 0
+	for i, row := range rows {
+		hits[i] = SearchHit{
+			ContentID: row.ContentID,
+			Title:     row.Title,
+			UserID:    row.UserID,
+			CreatedAt: row.CreatedAt,
+			Score:     row.Score,
+			Snippet:   highlight(row.Body, tokenize(row.Body), clauses),
+		}
+		total = row.Total
+	}
+	return hits, total, nil
+}
+
+// buildTSQuery traduce las cláusulas a la sintaxis de to_tsquery. Los términos
+// ya vienen normalizados por tokenize, así que no contienen operadores.
+func buildTSQuery(clauses []queryClause) string {
+	parts := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		part := strings.Join(clause.Terms, " <-> ")
+		if clause.Prefix {
+			part += ":*"
+		}
+		if len(clause.Terms) > 1 {
+			part = "(" + part + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " & ")
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// --- Slugs únicos e historial de redirecciones ---
+
+type SlugRepository interface {
+	// SlugTaken indica si el slug lo usa otro contenido (incluidos borrados y slugs antiguos)
+	SlugTaken(ctx context.Context, slug string, exceptContentID uint) (bool, error)
+	FindContentBySlug(ctx context.Context, slug string, content *models.Content) error
+	FindRedirect(ctx context.Context, slug string, redirect *models.SlugRedirect) error
+	RecordRedirect(ctx context.Context, oldSlug string, contentID uint) error
+}
+
+const maxSlugLength = 80
+
+// Letras que NFD no descompone y que conviene transliterar a mano
+var slugTransliterations = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+	"ø", "o", "Ø", "O", "ł", "l", "Ł", "L", "đ", "d", "Đ", "D",
+	"þ", "th", "Þ", "TH", "&", " y ",
+)
+
+// ContentSlug genera el slug base de un contenido, limitado a maxSlugLength
+func ContentSlug(text string) string {
+	slug := Slugify(slugTransliterations.Replace(text))
+	if len(slug) > maxSlugLength {
+		// maxSlugLength cuenta bytes: el corte retrocede al inicio de una letra
+		cut := maxSlugLength
+		for cut > 0 && !utf8.RuneStart(slug[cut]) {
+			cut--
+		}
+		slug = slug[:cut]
+		// Cortar en el último guion para no dejar palabras a medias
+		if i := strings.LastIndex(slug, "-"); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	return slug
+}
+
+// UniqueSlug agrega sufijos -2, -3... hasta encontrar un slug libre
+func UniqueSlug(base string, taken func(string) (bool, error)) (string, error) {
+	for i := 1; i < 1000; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+		used, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("could not find a free slug for " + base)
+}
+
+// uniqueSlug usa el slug pedido o, si está vacío, el título
+func (h *ContentHandler) uniqueSlug(ctx context.Context, requested, title string, contentID uint) (string, error) {
+	base := ContentSlug(requested)
+	if base == "" {
+		base = ContentSlug(title)
+	}
+	if base == "" {
+		base = "content"
+	}
+	if h.Slugs == nil {
+		return base, nil
+	}
+	return UniqueSlug(base, func(slug string) (bool, error) {
+		return h.Slugs.SlugTaken(ctx, slug, contentID)
+	})
+}
+
+// GetContentBySlug obtiene un contenido por su slug. Un slug antiguo responde 301 al actual.
+func (h *ContentHandler) GetContentBySlug(c *fiber.Ctx) error {
+	if h.Slugs == nil {
+		return utils.Problem(c, fiber.StatusServiceUnavailable, "Slug lookup is not available")
+	}
+
+	ctx := c.UserContext()
+	slug := c.Params("slug")
+	var content models.Content
+	err := h.Slugs.FindContentBySlug(ctx, slug, &content)
+	if err == nil {
+		return h.contentResponse(c, content, int(content.ID))
+	}
+	if !utils.IsNotFound(err) {
+		return utils.InternalProblem(c, "Failed to retrieve content", err)
+	}
+
+	var redirect models.SlugRedirect
+	if err := h.Slugs.FindRedirect(ctx, slug, &redirect); err != nil {
+		return utils.StoreProblem(c, err, "Content", "Failed to retrieve content")
+	}
+	if err := h.PG.GetContent(ctx, fmt.Sprint(redirect.ContentID), &content); err != nil {
+		return utils.StoreProblem(c, err, "Content", "Failed to retrieve content")
+	}
+	if content.Slug == "" {
+		return utils.Problem(c, fiber.StatusNotFound, "Content not found")
+	}
+
+	// Reemplazar solo el último segmento conserva el prefijo con que se montó la ruta
+	path := strings.TrimSuffix(c.Path(), slug) + url.PathEscape(content.Slug)
+	// Conservar la query (p. ej. ?locale=en) en la redirección
+	if query := string(c.Request().URI().QueryString()); query != "" {
+		path += "?" + query
+	}
+	return c.Redirect(path, fiber.StatusMovedPermanently)
+}
+
+// --- Implementación con GORM ---
+
+type SlugClient struct{}
+
+func (s *SlugClient) SlugTaken(ctx context.Context, slug string, exceptContentID uint) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	db := database.PostgresGetDB().WithContext(ctx)
+	var count int64
+	err := db.Unscoped().Model(&models.Content{}).
+		Where("slug = ? AND id <> ?", slug, exceptContentID).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err = db.Model(&models.SlugRedirect{}).
+		Where("old_slug = ? AND content_id <> ?", slug, exceptContentID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *SlugClient) FindContentBySlug(ctx context.Context, slug string, content *models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Where("slug = ?", slug).First(content).Error
+}
+
+func (s *SlugClient) FindRedirect(ctx context.Context, slug string, redirect *models.SlugRedirect) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Where("old_slug = ?", slug).First(redirect).Error
+}
+
+func (s *SlugClient) RecordRedirect(ctx context.Context, oldSlug string, contentID uint) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Si el contenido vuelve a un slug anterior, esa redirección ya no aplica
+		var content models.Content
+		if err := tx.First(&content, contentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("old_slug = ?", content.Slug).Delete(&models.SlugRedirect{}).Error; err != nil {
+			return err
+		}
+
+		redirect := models.SlugRedirect{OldSlug: oldSlug, ContentID: contentID}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "old_slug"}},
+			DoUpdates: clause.AssignmentColumns([]string{"content_id"}),
+		}).Create(&redirect).Error
+	})
+}
@@ -0,0 +1,626 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// --- Flujo de cambios en tiempo real ---
+
+// StreamContentType es el tipo con el que se publican los contenidos de /collection
+const StreamContentType = "content"
+
+// StreamEvent es lo que reciben los clientes de /collection/stream. ContentType
+// es "content" o el slug del tipo dinámico de la entrada.
+type StreamEvent struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	ContentType string          `json:"content_type"`
+	ContentID   string          `json:"content_id"`
+	AuthorID    int             `json:"author_id"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+// ErrStreamClosed lo devuelve Open cuando el servidor se está apagando
+var ErrStreamClosed = errors.New("stream closed")
+
+// ErrInvalidStreamID lo devuelve el broker cuando Last-Event-ID no tiene el formato esperado
+var ErrInvalidStreamID = errors.New("invalid stream event id")
+
+// StreamBroker reparte los eventos entre instancias y guarda un historial corto
+// para que los clientes puedan reanudar. Los IDs tienen la forma "<ms>-<seq>".
+type StreamBroker interface {
+	// Publish asigna el ID y devuelve el evento publicado
+	Publish(ctx context.Context, event StreamEvent) (StreamEvent, error)
+	// Subscribe entrega los eventos nuevos hasta que se cancela ctx
+	Subscribe(ctx context.Context) (<-chan StreamEvent, error)
+	// Since devuelve los eventos posteriores a lastID. complete es false si el
+	// historial ya no llega hasta lastID y pudo perderse algo.
+	Since(ctx context.Context, lastID string) (events []StreamEvent, complete bool, err error)
+}
+
+func NewStreamBroker(backend string) (StreamBroker, error) {
+	switch backend {
+	case "", "redis":
+		return NewRedisStreamBroker(database.RedisGetClient(), "cms:stream", 1000), nil
+	case "memory":
+		return NewMemoryStreamBroker(1000), nil
+	default:
+		return nil, fmt.Errorf("unknown stream backend %q", backend)
+	}
+}
+
+// parseStreamID separa un ID "<ms>-<seq>"
+func parseStreamID(id string) (ms, seq uint64, err error) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if ms, err = strconv.ParseUint(msPart, 10, 64); err != nil {
+		return 0, 0, ErrInvalidStreamID
+	}
+	if found {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return 0, 0, ErrInvalidStreamID
+		}
+	}
+	return ms, seq, nil
+}
+
+// CompareStreamIDs devuelve -1, 0 o 1; los IDs inválidos se consideran menores
+func CompareStreamIDs(a, b string) int {
+	aMs, aSeq, _ := parseStreamID(a)
+	bMs, bSeq, _ := parseStreamID(b)
+	switch {
+	case aMs < bMs || (aMs == bMs && aSeq < bSeq):
+		return -1
+	case aMs == bMs && aSeq == bSeq:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// StreamFilter limita los eventos que recibe un cliente; los campos vacíos no filtran
+type StreamFilter struct {
+	ContentTypes map[string]bool
+	AuthorID     int
+}
+
+func (f StreamFilter) Match(event StreamEvent) bool {
+	if len(f.ContentTypes) > 0 && !f.ContentTypes[event.ContentType] {
+		return false
+	}
+	return f.AuthorID == 0 || f.AuthorID == event.AuthorID
+}
+
+// ParseStreamFilter lee ?content_type=a,b&author=1
+func ParseStreamFilter(contentTypes, author string) (StreamFilter, error) {
+	filter := StreamFilter{}
+	for _, contentType := range strings.Split(contentTypes, ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			if filter.ContentTypes == nil {
+				filter.ContentTypes = map[string]bool{}
+			}
+			filter.ContentTypes[contentType] = true
+		}
+	}
+	if author != "" {
+		id, err := strconv.Atoi(author)
+		if err != nil || id <= 0 {
+			return filter, errors.New("invalid author")
+		}
+		filter.AuthorID = id
+	}
+	return filter, nil
+}
+
+// --- Reparto local ---
+
+const streamBufferSize = 64
+
+type streamSubscriber struct {
+	filter StreamFilter
+	events chan StreamEvent
+}
+
+// ContentStream mantiene una única suscripción al broker por instancia y reparte
+// los eventos entre las conexiones abiertas. Un cliente que no consume a tiempo
+// se desconecta; al reconectar con Last-Event-ID recupera lo perdido.
+type ContentStream struct {
+	Broker    StreamBroker
+	Heartbeat time.Duration
+	mu        sync.Mutex
+	clients   map[*streamSubscriber]struct{}
+	closed    bool
+}
+
+func NewContentStream(broker StreamBroker) *ContentStream {
+	return &ContentStream{
+		Broker:    broker,
+		Heartbeat: 15 * time.Second,
+		clients:   map[*streamSubscriber]struct{}{},
+	}
+}
+
+// Publish implementa EventPublisher para los contenidos de ContentHandler
+func (s *ContentStream) Publish(ctx context.Context, event ContentEvent) {
+	data, _ := json.Marshal(event.Content)
+	s.publish(ctx, StreamEvent{
+		Type:        event.Type,
+		ContentType: StreamContentType,
+		ContentID:   strconv.FormatUint(uint64(event.ContentID), 10),
+		AuthorID:    event.Content.UserID,
+		Data:        data,
+		OccurredAt:  event.OccurredAt,
+	})
+}
+
+// PublishEntry publica el cambio de una entrada de un tipo dinámico. Con s nil no hace nada.
+func (s *ContentStream) PublishEntry(ctx context.Context, eventType string, entry models.Entry) {
+	if s == nil {
+		return
+	}
+	var data []byte
+	if eventType != EventContentDeleted {
+		data, _ = json.Marshal(entry)
+	}
+	s.publish(ctx, StreamEvent{
+		Type:        eventType,
+		ContentType: entry.Type,
+		ContentID:   entry.ID.Hex(),
+		AuthorID:    entry.UserID,
+		Data:        data,
+		OccurredAt:  time.Now().UTC(),
+	})
+}
+
+func (s *ContentStream) publish(ctx context.Context, event StreamEvent) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if _, err := s.Broker.Publish(ctx, event); err != nil {
+		log.Printf("stream: failed to publish %s for %s %s: %v", event.Type, event.ContentType, event.ContentID, err)
+	}
+}
+
+// Run reparte los eventos del broker hasta que se cancela ctx
+func (s *ContentStream) Run(ctx context.Context) {
+	for {
+		events, err := s.Broker.Subscribe(ctx)
+		if err != nil {
+			log.Printf("stream: failed to subscribe: %v", err)
+		} else {
+			for event := range events {
+				s.broadcast(event)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (s *ContentStream) broadcast(event StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		if !client.filter.Match(event) {
+			continue
+		}
+		select {
+		case client.events <- event:
+		default:
+			// Cliente lento: se cierra su canal para que reconecte
+			close(client.events)
+			delete(s.clients, client)
+		}
+	}
+}
+
+// Close termina todas las conexiones abiertas y rechaza las nuevas. Se llama al
+// apagar el servidor, porque las conexiones largas impedirían el drenado.
+func (s *ContentStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for client := range s.clients {
+		close(client.events)
+		delete(s.clients, client)
+	}
+}
+
+// StreamSubscription es una conexión abierta: primero Backlog y después Events
+type StreamSubscription struct {
+	// Reset indica que el historial no alcanzaba Last-Event-ID y el cliente debe recargar
+	Reset   bool
+	Backlog []StreamEvent
+	Events  <-chan StreamEvent
+	after   string
+	stream  *ContentStream
+	client  *streamSubscriber
+}
+
+// Open registra un cliente. Con lastID recupera del historial los eventos posteriores.
+func (s *ContentStream) Open(ctx context.Context, filter StreamFilter, lastID string) (*StreamSubscription, error) {
+	if lastID != "" {
+		if _, _, err := parseStreamID(lastID); err != nil {
+			return nil, err
+		}
+	}
+	client := &streamSubscriber{filter: filter, events: make(chan StreamEvent, streamBufferSize)}
+	// El cliente se registra antes de leer el historial para no perder nada entre medias
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrStreamClosed
+	}
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+	sub := &StreamSubscription{Events: client.events, stream: s, client: client}
+	if lastID == "" {
+		return sub, nil
+	}
+
+	events, complete, err := s.Broker.Since(ctx, lastID)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	sub.Reset = !complete
+	sub.after = lastID
+	for _, event := range events {
+		if filter.Match(event) {
+			sub.Backlog = append(sub.Backlog, event)
+		}
+		sub.after = event.ID
+	}
+	return sub, nil
+}
+
+// Duplicate indica si un evento en vivo ya se envió como parte del historial
+func (sub *StreamSubscription) Duplicate(event StreamEvent) bool {
+	if sub.after == "" {
+		return false
+	}
+	if CompareStreamIDs(event.ID, sub.after) <= 0 {
+		return true
+	}
+	sub.after = ""
+	return false
+}
+
+func (sub *StreamSubscription) Close() {
+	s := sub.stream
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[sub.client]; ok {
+		delete(s.clients, sub.client)
+		close(sub.client.events)
+	}
+}
+
+// serve envía el historial y los eventos en vivo con send, y llama a ping cuando
+// no hay actividad. Termina cuando falla un envío, se cancela ctx o se cierra la suscripción.
+func (sub *StreamSubscription) serve(ctx context.Context, heartbeat time.Duration, send func(*StreamEvent) error, ping func() error) {
+	defer sub.Close()
+	if sub.Reset {
+		if send(nil) != nil {
+			return
+		}
+	}
+	for i := range sub.Backlog {
+		if send(&sub.Backlog[i]) != nil {
+			return
+		}
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if sub.Duplicate(event) {
+				continue
+			}
+			if send(&event) != nil {
+				return
+			}
+		case <-ticker.C:
+			if ping() != nil {
+				return
+			}
+		}
+	}
+}
+
+// --- Endpoint ---
+
+type StreamHandler struct {
+	Stream *ContentStream
+}
+
+func NewStreamHandler(stream *ContentStream) *StreamHandler {
+	return &StreamHandler{Stream: stream}
+}
+
+// StreamContents abre un flujo SSE, o WebSocket si la petición lo solicita.
+// Acepta ?content_type=a,b, ?author=id y Last-Event-ID (o ?last_event_id).
+func (h *StreamHandler) StreamContents(c *fiber.Ctx) error {
+	filter, err := ParseStreamFilter(c.Query("content_type"), c.Query("author"))
+	if err != nil {
+		return utils.Problem(c, fiber.StatusBadRequest, "Invalid author")
+	}
+	lastID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	if lastID != "" {
+		if _, _, err := parseStreamID(lastID); err != nil {
+			return utils.Problem(c, fiber.StatusBadRequest, "Invalid Last-Event-ID")
+		}
+	}
+
+	if websocket.IsWebSocketUpgrade(c) {
+		return websocket.New(func(conn *websocket.Conn) {
+			h.serveWebSocket(conn, filter, lastID)
+		})(c)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := h.Stream.Open(ctx, filter, lastID)
+	if errors.Is(err, ErrStreamClosed) {
+		cancel()
+		return utils.Problem(c, fiber.StatusServiceUnavailable, "Server is shutting down")
+	}
+	if err != nil {
+		cancel()
+		return utils.InternalProblem(c, "Failed to open stream", err)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		// Se escribe y se vacía en cada evento; un error indica que el cliente se fue
+		write := func(format string, args ...interface{}) error {
+			if _, err := fmt.Fprintf(w, format, args...); err != nil {
+				return err
+			}
+			return w.Flush()
+		}
+		if write("retry: 3000\n\n") != nil {
+			sub.Close()
+			return
+		}
+		sub.serve(ctx, h.Stream.Heartbeat, func(event *StreamEvent) error {
+			if event == nil {
+				return write("event: reset\ndata: {}\n\n")
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			return write("id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		}, func() error {
+			return write(": ping\n\n")
+		})
+	})
+	return nil
+}
+
+func (h *StreamHandler) serveWebSocket(conn *websocket.Conn, filter StreamFilter, lastID string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := h.Stream.Open(ctx, filter, lastID)
+	if err != nil {
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to open stream"))
+		return
+	}
+	// El cliente no envía nada; leer sirve para detectar el cierre
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	sub.serve(ctx, h.Stream.Heartbeat, func(event *StreamEvent) error {
+		if event == nil {
+			return conn.WriteJSON(fiber.Map{"type": "reset"})
+		}
+		return conn.WriteJSON(event)
+	}, func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
+	})
+}
+
+// --- Broker en memoria, para una sola instancia ---
+
+type MemoryStreamBroker struct {
+	mu          sync.Mutex
+	history     []StreamEvent
+	size        int
+	lastMs      uint64
+	seq         uint64
+	subscribers map[chan StreamEvent]struct{}
+}
+
+func NewMemoryStreamBroker(size int) *MemoryStreamBroker {
+	return &MemoryStreamBroker{size: size, subscribers: map[chan StreamEvent]struct{}{}}
+}
+
+func (b *MemoryStreamBroker) Publish(ctx context.Context, event StreamEvent) (StreamEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms > b.lastMs {
+		b.lastMs, b.seq = ms, 0
+	} else {
+		b.seq++
+	}
+	event.ID = fmt.Sprintf("%d-%d", b.lastMs, b.seq)
+	b.history = append(b.history, event)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("stream: memory subscriber full, dropping event %s", event.ID)
+		}
+	}
+	return event, nil
+}
+
+func (b *MemoryStreamBroker) Subscribe(ctx context.Context) (<-chan StreamEvent, error) {
+	ch := make(chan StreamEvent, 1024)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+func (b *MemoryStreamBroker) Since(ctx context.Context, lastID string) ([]StreamEvent, bool, error) {
+	if _, _, err := parseStreamID(lastID); err != nil {
+		return nil, false, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := []StreamEvent{}
+	for _, event := range b.history {
+		if CompareStreamIDs(event.ID, lastID) > 0 {
+			events = append(events, event)
+		}
+	}
+	complete := len(b.history) == 0 || CompareStreamIDs(b.history[0].ID, lastID) <= 0
+	return events, complete, nil
+}
+
+// --- Broker con Redis: historial en un stream y reparto con pub/sub ---
+
+// El XADD y el PUBLISH van en el mismo script para que el orden de los IDs y el
+// de los mensajes coincidan en todas las instancias
+var streamPublishScript = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[2], '*', 'event', ARGV[1])
+redis.call('PUBLISH', KEYS[1], id .. ' ' .. ARGV[1])
+return id
+`)
+
+type RedisStreamBroker struct {
+	Client *redis.Client
+	Key    string
+	Size   int
+}
+
+func NewRedisStreamBroker(client *redis.Client, key string, size int) *RedisStreamBroker {
+	return &RedisStreamBroker{Client: client, Key: key, Size: size}
+}
+
+func (b *RedisStreamBroker) Publish(ctx context.Context, event StreamEvent) (StreamEvent, error) {
+	event.ID = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return event, err
+	}
+	id, err := streamPublishScript.Run(ctx, b.Client, []string{b.Key}, data, b.Size).Text()
+	if err != nil {
+		return event, err
+	}
+	event.ID = id
+	return event, nil
+}
+
+func (b *RedisStreamBroker) Subscribe(ctx context.Context) (<-chan StreamEvent, error) {
+	pubsub := b.Client.Subscribe(ctx, b.Key)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	events := make(chan StreamEvent, 256)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				id, payload, _ := strings.Cut(msg.Payload, " ")
+				event, err := decodeStreamEvent(id, payload)
+				if err != nil {
+					log.Printf("stream: invalid message %s: %v", id, err)
+					continue
+				}
+				events <- event
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (b *RedisStreamBroker) Since(ctx context.Context, lastID string) ([]StreamEvent, bool, error) {
+	if _, _, err := parseStreamID(lastID); err != nil {
+		return nil, false, err
+	}
+	first, err := b.Client.XRangeN(ctx, b.Key, "-", "+", 1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	messages, err := b.Client.XRange(ctx, b.Key, "("+lastID, "+").Result()
+	if err != nil {
+		return nil, false, err
+	}
+	events := make([]StreamEvent, 0, len(messages))
+	for _, msg := range messages {
+		payload, _ := msg.Values["event"].(string)
+		event, err := decodeStreamEvent(msg.ID, payload)
+		if err != nil {
+			log.Printf("stream: invalid entry %s: %v", msg.ID, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	complete := len(first) == 0 || CompareStreamIDs(first[0].ID, lastID) <= 0
+	return events, complete, nil
+}
+
+func decodeStreamEvent(id, payload string) (StreamEvent, error) {
+	var event StreamEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return event, err
+	}
+	event.ID = id
+	return event, nil
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// --- Taxonomía: etiquetas y árbol de categorías ---
+
+type TaxonomyRepository interface {
+	ListTags(ctx context.Context, tags *[]models.Tag) error
+	GetTag(ctx context.Context, id uint, tag *models.Tag) error
+	SaveTag(ctx context.Context, tag *models.Tag) error
+	DeleteTag(ctx context.Context, tag *models.Tag) error
+	// FindOrCreateTags devuelve las etiquetas con esos slugs, creando las que falten
+	FindOrCreateTags(ctx context.Context, names []string) ([]models.Tag, error)
+
+	ListCategories(ctx context.Context, categories *[]models.Category) error
+	GetCategory(ctx context.Context, id uint, category *models.Category) error
+	SaveCategory(ctx context.Context, category *models.Category) error
+	DeleteCategory(ctx context.Context, category *models.Category) error
+
+	SetContentTags(ctx context.Context, content *models.Content, tags []models.Tag) error
+	LoadContentTaxonomy(ctx context.Context, content *models.Content) error
+	// FindContentsByTaxonomy filtra por slug de etiqueta y/o por un conjunto de categorías
+	FindContentsByTaxonomy(ctx context.Context, tagSlug string, categoryIDs []uint, contents *[]models.Content) error
+}
+
+var ErrCategoryNotEmpty = errors.New("category has children")
+
+// Slugify normaliza un nombre: minúsculas, sin acentos y con guiones
+func Slugify(name string) string {
+	return strings.Join(tokenTerms(name), "-")
+}
+
+// CategoryDescendants devuelve id y todos sus descendientes a partir de la lista plana
+func CategoryDescendants(categories []models.Category, id uint) []uint {
+	children := make(map[uint][]uint)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	result := []uint{id}
+	for i := 0; i < len(result); i++ {
+		result = append(result, children[result[i]]...)
+	}
+	return result
+}
+
+// BuildCategoryTree arma el árbol ordenado por Position y nombre
+func BuildCategoryTree(categories []models.Category) []*models.Category {
+	nodes := make(map[uint]*models.Category, len(categories))
+	for i := range categories {
+		category := categories[i]
+		category.Children = nil
+		nodes[category.ID] = &category
+	}
+
+	var roots []*models.Category
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	var sortNodes func([]*models.Category)
+	sortNodes = func(list []*models.Category) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Position != list[j].Position {
+				return list[i].Position < list[j].Position
+			}
+			return list[i].Name < list[j].Name
+		})
+		for _, node := range list {
+			sortNodes(node.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+// --- Handler de taxonomía ---
+
+type TaxonomyHandler struct {
+	Repo TaxonomyRepository
+	// Cache es opcional; los contenidos incluyen sus etiquetas y su categoría,
+	// así que cambiarlas invalida los contenidos afectados y los listados
+	Cache *ContentCache
+}
+
+func NewTaxonomyHandler(repo TaxonomyRepository) *TaxonomyHandler {
+	return &TaxonomyHandler{Repo: repo}
+}
+
+type tagRequest struct {
+	Name string `json:"name"`
+}
+
+type categoryRequest struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+	Position int    `json:"position"`
+}
+
+// ListTags obtiene todas las etiquetas
+func (h *TaxonomyHandler) ListTags(c *fiber.Ctx) error {
+	var tags []models.Tag
+	if err := h.Repo.ListTags(c.UserContext(), &tags); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve tags", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(tags)
+}
+
+// CreateTag crea una etiqueta (o devuelve la existente con el mismo slug)
+func (h *TaxonomyHandler) CreateTag(c *fiber.Ctx) error {
+	var req tagRequest
+	if err := c.BodyParser(&req); err != nil || Slugify(req.Name) == "" {
+		return utils.InvalidBody(c)
+	}
+	tags, err := h.Repo.FindOrCreateTags(c.UserContext(), []string{req.Name})
+	if err != nil {
+		return utils.StoreProblem(c, err, "Tag", "Failed to create tag")
+	}
+	return c.Status(fiber.StatusCreated).JSON(tags[0])
+}
+
+// UpdateTag renombra una etiqueta
+func (h *TaxonomyHandler) UpdateTag(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.InvalidID(c, "Invalid tag ID")
+	}
+	var tag models.Tag
+	if err := h.Repo.GetTag(c.UserContext(), uint(id), &tag); err != nil {
+		return utils.StoreProblem(c, err, "Tag", "Failed to retrieve tag")
+	}
+
+	var req tagRequest
+	if err := c.BodyParser(&req); err != nil || Slugify(req.Name) == "" {
+		return utils.InvalidBody(c)
+	}
+	affected, err := h.taxonomyContents(c.UserContext(), tag.Slug, 0)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve contents", err)
+	}
+	tag.Name = strings.TrimSpace(req.Name)
+	tag.Slug = Slugify(req.Name)
+	if err := h.Repo.SaveTag(c.UserContext(), &tag); err != nil {
+		return utils.StoreProblem(c, err, "Tag", "Failed to save tag")
+	}
+	h.taxonomyChanged(c.UserContext(), affected)
+	return c.Status(fiber.StatusOK).JSON(tag)
+}
+
+// DeleteTag elimina una etiqueta y sus asignaciones
+func (h *TaxonomyHandler) DeleteTag(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.InvalidID(c, "Invalid tag ID")
+	}
+	var tag models.Tag
+	if err := h.Repo.GetTag(c.UserContext(), uint(id), &tag); err != nil {
+		return utils.StoreProblem(c, err, "Tag", "Failed to retrieve tag")
+	}
+	affected, err := h.taxonomyContents(c.UserContext(), tag.Slug, 0)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve contents", err)
+	}
+	if err := h.Repo.DeleteTag(c.UserContext(), &tag); err != nil {
+		return utils.StoreProblem(c, err, "Tag", "Failed to delete tag")
+	}
+	h.taxonomyChanged(c.UserContext(), affected)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Tag deleted successfully"})
+}
+
+// ListCategories devuelve el árbol de categorías
+func (h *TaxonomyHandler) ListCategories(c *fiber.Ctx) error {
+	var categories []models.Category
+	if err := h.Repo.ListCategories(c.UserContext(), &categories); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve categories", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(BuildCategoryTree(categories))
+}
+
+// GetCategory obtiene una categoría por su id
+func (h *TaxonomyHandler) GetCategory(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.InvalidID(c, "Invalid category ID")
+	}
+	var category models.Category
+	if err := h.Repo.GetCategory(c.UserContext(), uint(id), &category); err != nil {
+		return utils.StoreProblem(c, err, "Category", "Failed to retrieve category")
+	}
+	return c.Status(fiber.StatusOK).JSON(category)
+}
+
+// CreateCategory crea una categoría, opcionalmente bajo un padre
+func (h *TaxonomyHandler) CreateCategory(c *fiber.Ctx) error {
+	var req categoryRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		return utils.InvalidBody(c)
+	}
+
+	category := models.Category{Position: req.Position}
+	if apiErr := h.applyCategoryRequest(c.UserContext(), &category, req); apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	if err := h.Repo.SaveCategory(c.UserContext(), &category); err != nil {
+		return utils.StoreProblem(c, err, "Category", "Failed to create category")
+	}
+	return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+// UpdateCategory modifica nombre, padre u orden de una categoría
+func (h *TaxonomyHandler) UpdateCategory(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.InvalidID(c, "Invalid category ID")
+	}
+	var category models.Category
+	if err := h.Repo.GetCategory(c.UserContext(), uint(id), &category); err != nil {
+		return utils.StoreProblem(c, err, "Category", "Failed to retrieve category")
+	}
+
+	var req categoryRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		return utils.InvalidBody(c)
+	}
+	category.Position = req.Position
+	if apiErr := h.applyCategoryRequest(c.UserContext(), &category, req); apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	affected, err := h.taxonomyContents(c.UserContext(), "", category.ID)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve contents", err)
+	}
+	if err := h.Repo.SaveCategory(c.UserContext(), &category); err != nil {
+		return utils.StoreProblem(c, err, "Category", "Failed to save category")
+	}
+	h.taxonomyChanged(c.UserContext(), affected)
+	return c.Status(fiber.StatusOK).JSON(category)
+}
+
+// DeleteCategory elimina una categoría sin hijos
+func (h *TaxonomyHandler) DeleteCategory(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.InvalidID(c, "Invalid category ID")
+	}
+	var category models.Category
+	if err := h.Repo.GetCategory(c.UserContext(), uint(id), &category); err != nil {
+		return utils.StoreProblem(c, err, "Category", "Failed to retrieve category")
+	}
+	affected, err := h.taxonomyContents(c.UserContext(), "", category.ID)
+	if err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve contents", err)
+	}
+	err = h.Repo.DeleteCategory(c.UserContext(), &category)
+	if errors.Is(err, ErrCategoryNotEmpty) {
+		return utils.Problem(c, fiber.StatusConflict, "Category has children")
+	}
+	if err != nil {
+		return utils.StoreProblem(c, err, "Category", "Failed to delete category")
+	}
+	h.taxonomyChanged(c.UserContext(), affected)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Category deleted successfully"})
+}
+
+// taxonomyContents devuelve los ids de los contenidos con la etiqueta o la
+// categoría. Se buscan antes de cambiarlas, porque después ya no se encuentran.
+func (h *TaxonomyHandler) taxonomyContents(ctx context.Context, tagSlug string, categoryID uint) ([]uint, error) {
+	if h.Cache == nil {
+		return nil, nil
+	}
+	var categoryIDs []uint
+	if categoryID != 0 {
+		categoryIDs = []uint{categoryID}
+	}
+	var contents []models.Content
+	if err := h.Repo.FindContentsByTaxonomy(ctx, tagSlug, categoryIDs, &contents); err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(contents))
+	for i, content := range contents {
+		ids[i] = content.ID
+	}
+	return ids, nil
+}
+
+// taxonomyChanged invalida los contenidos afectados y todos los listados
+func (h *TaxonomyHandler) taxonomyChanged(ctx context.Context, contentIDs []uint) {
+	tags := []string{contentsListTag}
+	for _, id := range contentIDs {
+		tags = append(tags, contentTag(id))
+	}
+	h.Cache.Invalidate(ctx, tags...)
+}
+
+// applyCategoryRequest valida el padre (que exista y que no genere ciclos) y el slug
+func (h *TaxonomyHandler) applyCategoryRequest(ctx context.Context, category *models.Category, req categoryRequest) *utils.Error {
+	category.Name = strings.TrimSpace(req.Name)
+	category.Slug = Slugify(req.Slug)
+	if category.Slug == "" {
+		category.Slug = Slugify(req.Name)
+	}
+	if category.Slug == "" {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid category name")
+	}
+
+	category.ParentID = req.ParentID
+	if req.ParentID == nil {
+		return nil
+	}
+
+	var categories []models.Category
+	if err := h.Repo.ListCategories(ctx, &categories); err != nil {
+		return utils.InternalError("Failed to retrieve categories", err)
+	}
+	found := false
+	for _, existing := range categories {
+		if existing.ID == *req.ParentID {
+			found = true
+		}
+	}
+	if !found {
+		return utils.NewError(fiber.StatusBadRequest, "Parent category not found")
+	}
+	if category.ID != 0 {
+		for _, id := range CategoryDescendants(categories, category.ID) {
+			if id == *req.ParentID {
+				return utils.NewError(fiber.StatusBadRequest, "Category cannot be its own ancestor")
+			}
+		}
+	}
+	return nil
+}
+
+// --- Asignación desde ContentHandler ---
+
+// assignTaxonomy aplica etiquetas y categoría al contenido; tags nil deja las actuales.
+// Devuelve el error para responder, o nil.
+func (h *ContentHandler) assignTaxonomy(ctx context.Context, content *models.Content, tags []string, categoryID *uint) *utils.Error {
+	if h.Taxonomy == nil {
+		return nil
+	}
+	if categoryID != nil {
+		var category models.Category
+		if err := h.Taxonomy.GetCategory(ctx, *categoryID, &category); utils.IsNotFound(err) {
+			return utils.NewError(fiber.StatusBadRequest, "Category not found")
+		} else if err != nil {
+			return utils.InternalError("Failed to retrieve category", err)
+		}
+		content.CategoryID = categoryID
+	}
+	if tags == nil {
+		return nil
+	}
+
+	found, err := h.Taxonomy.FindOrCreateTags(ctx, tags)
+	if err != nil {
+		return utils.StoreError(err, "Tag", "Failed to save tags")
+	}
+	content.Tags = found
+	return nil
+}
+
+// findContentsByTaxonomy resuelve los filtros ?tag= y ?category= de ListContents
+func (h *ContentHandler) findContentsByTaxonomy(ctx context.Context, tagSlug, category string, contents *[]models.Content) *utils.Error {
+	if h.Taxonomy == nil {
+		return utils.NewError(fiber.StatusBadRequest, "Taxonomy filters are not available")
+	}
+
+	var categoryIDs []uint
+	if category != "" {
+		id, err := strconv.ParseUint(category, 10, 64)
+		if err != nil {
+			return utils.InvalidIDError("Invalid category ID")
+		}
+		var categories []models.Category
+		if err := h.Taxonomy.ListCategories(ctx, &categories); err != nil {
+			return utils.InternalError("Failed to retrieve categories", err)
+		}
+		categoryIDs = CategoryDescendants(categories, uint(id))
+	}
+
+	if err := h.Taxonomy.FindContentsByTaxonomy(ctx, Slugify(tagSlug), categoryIDs, contents); err != nil {
+		return utils.InternalError("Failed to retrieve contents", err)
+	}
+	return nil
+}
+
+// --- Implementación con GORM ---
+
+type TaxonomyClient struct{}
+
+func (t *TaxonomyClient) ListTags(ctx context.Context, tags *[]models.Tag) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Order("slug").Find(tags).Error
+}
+
+func (t *TaxonomyClient) GetTag(ctx context.Context, id uint, tag *models.Tag) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).First(tag, id).Error
+}
+
+func (t *TaxonomyClient) SaveTag(ctx context.Context, tag *models.Tag) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Save(tag).Error
+}
+
+func (t *TaxonomyClient) DeleteTag(ctx context.Context, tag *models.Tag) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM content_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+}
+
+func (t *TaxonomyClient) FindOrCreateTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	tags := make([]models.Tag, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		slug := Slugify(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		tag := models.Tag{Name: strings.TrimSpace(name), Slug: slug}
+		if err := database.PostgresGetDB().WithContext(ctx).Where(models.Tag{Slug: slug}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (t *TaxonomyClient) ListCategories(ctx context.Context, categories *[]models.Category) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Order("position, name").Find(categories).Error
+}
+
+func (t *TaxonomyClient) GetCategory(ctx context.Context, id uint, category *models.Category) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).First(category, id).Error
+}
+
+func (t *TaxonomyClient) SaveCategory(ctx context.Context, category *models.Category) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Save(category).Error
+}
+
+func (t *TaxonomyClient) DeleteCategory(ctx context.Context, category *models.Category) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	var children int64
+	if err := database.PostgresGetDB().WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrCategoryNotEmpty
+	}
+	return database.PostgresGetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Content{}).Where("category_id = ?", category.ID).Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
+
+func (t *TaxonomyClient) SetContentTags(ctx context.Context, content *models.Content, tags []models.Tag) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Model(content).Association("Tags").Replace(tags)
+}
+
+func (t *TaxonomyClient) LoadContentTaxonomy(ctx context.Context, content *models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Preload("Tags").Preload("Category").First(content, content.ID).Error
+}
+
+func (t *TaxonomyClient) FindContentsByTaxonomy(ctx context.Context, tagSlug string, categoryIDs []uint, contents *[]models.Content) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	query := database.PostgresGetDB().WithContext(ctx).Preload("Tags").Preload("Category")
+	if tagSlug != "" {
+		query = query.Where("id IN (?)", database.PostgresGetDB().Table("content_tags").
+			Select("content_tags.content_id").
+			Joins("JOIN tags ON tags.id = content_tags.tag_id").
+			Where("tags.slug = ?", tagSlug))
+	}
+	if len(categoryIDs) > 0 {
+		query = query.Where("category_id IN ?", categoryIDs)
+	}
+	return query.Find(contents).Error
+}
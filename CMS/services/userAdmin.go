@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// --- Administración de usuarios desde la línea de comandos ---
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+)
+
+var Roles = []string{RoleAdmin, RoleEditor, RoleAuthor}
+
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+const minPasswordLength = 8
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrInvalidRole  = errors.New("invalid role, must be one of: " + strings.Join(Roles, ", "))
+	ErrWeakPassword = errors.New("password must have at least 8 characters")
+)
+
+// UserAdminRepository amplía UserRepository con lo necesario para modificar usuarios
+type UserAdminRepository interface {
+	UserRepository
+	Save(ctx context.Context, user *models.User) error
+}
+
+func (r *PostgresUserRepository) Save(ctx context.Context, user *models.User) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return r.DB.WithContext(ctx).Save(user).Error
+}
+
+type UserAdmin struct {
+	Repo UserAdminRepository
+}
+
+func NewUserAdmin(repo UserAdminRepository) *UserAdmin {
+	return &UserAdmin{Repo: repo}
+}
+
+// Create crea un usuario activo. Sin password se genera una aleatoria, que se devuelve.
+func (a *UserAdmin) Create(ctx context.Context, username, email, password, role string) (*models.User, string, error) {
+	if username == "" || email == "" {
+		return nil, "", errors.New("username and email are required")
+	}
+	if role == "" {
+		role = RoleAuthor
+	}
+	if !ValidRole(role) {
+		return nil, "", ErrInvalidRole
+	}
+	password, hashed, err := hashNewPassword(password)
+	if err != nil {
+		return nil, "", err
+	}
+	user := &models.User{
+		Username: username,
+		Email:    email,
+		Password: hashed,
+		IsActive: true,
+		Role:     role,
+	}
+	if err := a.Repo.Create(ctx, user); err != nil {
+		return nil, "", err
+	}
+	return user, password, nil
+}
+
+// SetBanned banea o readmite al usuario con ese nombre o email
+func (a *UserAdmin) SetBanned(ctx context.Context, login string, banned bool) (*models.User, error) {
+	return a.update(ctx, login, func(user *models.User) error {
+		user.IsBanned = banned
+		return nil
+	})
+}
+
+func (a *UserAdmin) SetRole(ctx context.Context, login, role string) (*models.User, error) {
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	return a.update(ctx, login, func(user *models.User) error {
+		user.Role = role
+		return nil
+	})
+}
+
+// ResetPassword cambia la contraseña y desbloquea la cuenta. Sin password se
+// genera una aleatoria, que se devuelve.
+func (a *UserAdmin) ResetPassword(ctx context.Context, login, password string) (*models.User, string, error) {
+	password, hashed, err := hashNewPassword(password)
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := a.update(ctx, login, func(user *models.User) error {
+		user.Password = hashed
+		user.FailedAttempts = 0
+		user.LockoutUntil = nil
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return user, password, nil
+}
+
+// Find busca por nombre o email; devuelve ErrUserNotFound si no existe
+func (a *UserAdmin) Find(ctx context.Context, login string) (*models.User, error) {
+	var user models.User
+	if err := a.Repo.FindByUsernameOrEmail(ctx, login, login, &user); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (a *UserAdmin) update(ctx context.Context, login string, change func(user *models.User) error) (*models.User, error) {
+	user, err := a.Find(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(user); err != nil {
+		return nil, err
+	}
+	if err := a.Repo.Save(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func hashNewPassword(password string) (string, string, error) {
+	if password == "" {
+		b := make([]byte, 12)
+		if _, err := rand.Read(b); err != nil {
+			return "", "", err
+		}
+		password = base64.RawURLEncoding.EncodeToString(b)
+	}
+	if len(password) < minPasswordLength {
+		return "", "", ErrWeakPassword
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return password, string(hashed), nil
+}
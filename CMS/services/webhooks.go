@@ -0,0 +1,554 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// --- Webhooks ---
+
+type WebhookRepository interface {
+	ListWebhooks(ctx context.Context, webhooks *[]models.Webhook) error
+	GetWebhook(ctx context.Context, id uint, webhook *models.Webhook) error
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	SaveWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, webhook *models.Webhook) error
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	// ClaimDeliveries reserva hasta limit entregas pendientes durante lease
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uint, delivery *models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID uint, limit, offset int, deliveries *[]models.WebhookDelivery) error
+}
+
+const (
+	WebhookSignatureHeader = "X-CMS-Signature"
+	WebhookEventHeader     = "X-CMS-Event"
+	WebhookDeliveryHeader  = "X-CMS-Delivery"
+	maxStoredResponse      = 1024
+)
+
+// SignWebhookPayload firma "timestamp.payload" con HMAC-SHA256. El receptor debe
+// recalcularla y rechazar timestamps viejos para evitar reenvíos.
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// ErrWebhookTarget indica un destino de webhook en la red interna
+var ErrWebhookTarget = errors.New("webhook target must be a public address")
+
+// sharedAddressSpace es 100.64.0.0/10 (CGNAT), que muchos proveedores usan como red interna
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP indica si ip está fuera de loopback, redes privadas, CGNAT y link-local,
+// donde un webhook podría alcanzar servicios internos o los metadatos de la nube
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip) &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// publicOnly es el Control del dialer de los webhooks. Se comprueba la IP ya
+// resuelta, así que vale también para redirecciones y para nombres DNS que
+// apuntan a la red interna.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookTarget, host)
+	}
+	return nil
+}
+
+// NewWebhookHTTPClient devuelve el cliente de las entregas, que solo conecta con
+// direcciones públicas. No usa proxy, porque el proxy haría la conexión final.
+func NewWebhookHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: publicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// WebhookDispatcher convierte eventos en entregas persistidas y las envía con reintentos
+type WebhookDispatcher struct {
+	Repo        WebhookRepository
+	Client      *http.Client
+	BatchSize   int
+	Interval    time.Duration
+	Lease       time.Duration
+	MaxAttempts int
+	wake        chan struct{}
+}
+
+func NewWebhookDispatcher(repo WebhookRepository) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Repo:        repo,
+		Client:      NewWebhookHTTPClient(),
+		BatchSize:   20,
+		Interval:    5 * time.Second,
+		Lease:       time.Minute,
+		MaxAttempts: 8,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Backoff es la espera antes del intento número attempts (10s, 20s, 40s... hasta 1 h)
+func (d *WebhookDispatcher) Backoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return time.Hour
+	}
+	return min(10*time.Second<<(attempts-1), time.Hour)
+}
+
+// Publish crea una entrega por cada webhook activo suscrito al evento
+func (d *WebhookDispatcher) Publish(ctx context.Context, event ContentEvent) {
+	var webhooks []models.Webhook
+	if err := d.Repo.ListWebhooks(ctx, &webhooks); err != nil {
+		log.Printf("webhooks: failed to list webhooks for %s: %v", event.Type, err)
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("webhooks: failed to encode event %s: %v", event.ID, err)
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Active || !subscribed(webhook.Events, event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			Event:         event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+	if err := d.Repo.CreateDeliveries(ctx, deliveries); err != nil {
+		log.Printf("webhooks: failed to store deliveries for %s: %v", event.ID, err)
+		return
+	}
+	d.Wake()
+}
+
+func subscribed(events []string, eventType string) bool {
+	for _, e := range events {
+		if e == eventType || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Wake hace que Run procese las entregas sin esperar al próximo tick
+func (d *WebhookDispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Deliver envía una entrega y actualiza su registro con el resultado
+func (d *WebhookDispatcher) Deliver(ctx context.Context, delivery models.WebhookDelivery) error {
+	var webhook models.Webhook
+	err := d.Repo.GetWebhook(ctx, delivery.WebhookID, &webhook)
+	if err == nil {
+		err = d.send(ctx, webhook, &delivery)
+	}
+
+	delivery.Attempts++
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.Backoff(delivery.Attempts))
+	}
+	if saveErr := d.Repo.SaveDelivery(ctx, &delivery); saveErr != nil {
+		log.Printf("webhooks: failed to save delivery %d: %v", delivery.ID, saveErr)
+	}
+	return err
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, webhook models.Webhook, delivery *models.WebhookDelivery) error {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CMS-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, time.Now().Unix(), payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		delivery.ResponseCode = 0
+		delivery.ResponseBody = ""
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxStoredResponse))
+	delivery.ResponseCode = resp.StatusCode
+	delivery.ResponseBody = string(body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ProcessBatch envía un lote de entregas pendientes y devuelve cuántas reservó.
+// Las envía a la vez: en serie, un lote de destinos lentos tardaría BatchSize
+// veces el timeout del cliente y superaría el Lease, y otro worker volvería a
+// reservar y enviar las mismas entregas.
+func (d *WebhookDispatcher) ProcessBatch(ctx context.Context) (int, error) {
+	deliveries, err := d.Repo.ClaimDeliveries(ctx, d.BatchSize, d.Lease)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery models.WebhookDelivery) {
+			defer wg.Done()
+			_ = d.Deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// Run envía las entregas pendientes hasta que se cancele ctx
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := d.ProcessBatch(ctx)
+			if err != nil {
+				log.Printf("webhooks: failed to claim deliveries: %v", err)
+			}
+			if err != nil || n < d.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// --- Administración ---
+
+type WebhookHandler struct {
+	Dispatcher *WebhookDispatcher
+}
+
+func NewWebhookHandler(dispatcher *WebhookDispatcher) *WebhookHandler {
+	return &WebhookHandler{Dispatcher: dispatcher}
+}
+
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// validate comprueba la URL y que los eventos existan. Las IP internas escritas
+// en la URL se rechazan aquí; las que vienen del DNS, al conectar.
+func (r webhookRequest) validate() string {
+	parsed, err := url.Parse(r.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "URL must be an absolute http(s) URL"
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if ip := net.ParseIP(host); (ip != nil && !publicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return "URL must point to a public address"
+	}
+	if len(r.Events) == 0 {
+		return "At least one event is required"
+	}
+	for _, event := range r.Events {
+		if event != "*" && !contains(ContentEventTypes, event) {
+			return "Unknown event " + event
+		}
+	}
+	return ""
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ListWebhooks lista los webhooks registrados
+func (h *WebhookHandler) ListWebhooks(c *fiber.Ctx) error {
+	webhooks := []models.Webhook{}
+	if err := h.Dispatcher.Repo.ListWebhooks(c.UserContext(), &webhooks); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve webhooks", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(webhooks)
+}
+
+// CreateWebhook registra un endpoint y devuelve su secreto por única vez
+func (h *WebhookHandler) CreateWebhook(c *fiber.Ctx) error {
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.InvalidBody(c)
+	}
+	if msg := req.validate(); msg != "" {
+		return utils.Problem(c, fiber.StatusBadRequest, msg)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return utils.InternalProblem(c, "Failed to generate secret", err)
+	}
+	webhook := models.Webhook{
+		URL:    req.URL,
+		Secret: hex.EncodeToString(secret),
+		Events: req.Events,
+		Active: req.Active == nil || *req.Active,
+	}
+	if err := h.Dispatcher.Repo.CreateWebhook(c.UserContext(), &webhook); err != nil {
+		return utils.InternalProblem(c, "Failed to create webhook", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"webhook": webhook, "secret": webhook.Secret})
+}
+
+// GetWebhook obtiene un webhook por su id
+func (h *WebhookHandler) GetWebhook(c *fiber.Ctx) error {
+	webhook, apiErr := h.findWebhook(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	return c.Status(fiber.StatusOK).JSON(webhook)
+}
+
+// UpdateWebhook cambia la URL, los eventos o el estado; el secreto se conserva
+func (h *WebhookHandler) UpdateWebhook(c *fiber.Ctx) error {
+	webhook, apiErr := h.findWebhook(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.InvalidBody(c)
+	}
+	if msg := req.validate(); msg != "" {
+		return utils.Problem(c, fiber.StatusBadRequest, msg)
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if err := h.Dispatcher.Repo.SaveWebhook(c.UserContext(), &webhook); err != nil {
+		return utils.InternalProblem(c, "Failed to save webhook", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(webhook)
+}
+
+// DeleteWebhook elimina un webhook; su historial de entregas se conserva
+func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
+	webhook, apiErr := h.findWebhook(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	if err := h.Dispatcher.Repo.DeleteWebhook(c.UserContext(), &webhook); err != nil {
+		return utils.InternalProblem(c, "Failed to delete webhook", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Webhook deleted successfully"})
+}
+
+// ListDeliveries devuelve el registro de entregas de un webhook, más recientes primero
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	webhook, apiErr := h.findWebhook(c)
+	if apiErr != nil {
+		return utils.SendError(c, apiErr)
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := h.Dispatcher.Repo.ListDeliveries(c.UserContext(), webhook.ID, limit, offset, &deliveries); err != nil {
+		return utils.InternalProblem(c, "Failed to retrieve deliveries", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(deliveries)
+}
+
+// Redeliver vuelve a enviar una entrega como una entrega nueva, así el registro
+// conserva el resultado original
+func (h *WebhookHandler) Redeliver(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("deliveryId"), 10, 64)
+	if err != nil {
+		return utils.InvalidID(c, "Invalid delivery ID")
+	}
+	var original models.WebhookDelivery
+	if err := h.Dispatcher.Repo.GetDelivery(c.UserContext(), uint(id), &original); err != nil {
+		return utils.StoreProblem(c, err, "Delivery", "Failed to retrieve delivery")
+	}
+	var webhook models.Webhook
+	if err := h.Dispatcher.Repo.GetWebhook(c.UserContext(), original.WebhookID, &webhook); err != nil {
+		return utils.StoreProblem(c, err, "Webhook", "Failed to retrieve webhook")
+	}
+	if !webhook.Active {
+		return utils.Problem(c, fiber.StatusConflict, "Webhook is not active")
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := h.Dispatcher.Repo.CreateDeliveries(c.UserContext(), []models.WebhookDelivery{delivery}); err != nil {
+		return utils.InternalProblem(c, "Failed to schedule delivery", err)
+	}
+	h.Dispatcher.Wake()
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Delivery scheduled"})
+}
+
+func (h *WebhookHandler) findWebhook(c *fiber.Ctx) (models.Webhook, *utils.Error) {
+	var webhook models.Webhook
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return webhook, utils.InvalidIDError("Invalid webhook ID")
+	}
+	if err := h.Dispatcher.Repo.GetWebhook(c.UserContext(), uint(id), &webhook); err != nil {
+		return webhook, utils.StoreError(err, "Webhook", "Failed to retrieve webhook")
+	}
+	return webhook, nil
+}
+
+// --- Implementación con GORM ---
+
+type WebhookClient struct{}
+
+func (w *WebhookClient) ListWebhooks(ctx context.Context, webhooks *[]models.Webhook) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Order("id").Find(webhooks).Error
+}
+
+func (w *WebhookClient) GetWebhook(ctx context.Context, id uint, webhook *models.Webhook) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).First(webhook, id).Error
+}
+
+func (w *WebhookClient) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Create(webhook).Error
+}
+
+func (w *WebhookClient) SaveWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Save(webhook).Error
+}
+
+func (w *WebhookClient) DeleteWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Delete(webhook).Error
+}
+
+func (w *WebhookClient) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Create(&deliveries).Error
+}
+
+func (w *WebhookClient) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	db := database.PostgresGetDB()
+	err := db.WithContext(ctx).Raw(`
+UPDATE webhook_deliveries SET next_attempt_at = ?
+WHERE id IN (
+	SELECT id FROM webhook_deliveries
+	WHERE status = ? AND next_attempt_at <= ?
+	ORDER BY id LIMIT ? `+skipLocked(db)+`
+)
+RETURNING *`, now.Add(lease), models.DeliveryPending, now, limit).Scan(&deliveries).Error
+	return deliveries, err
+}
+
+func (w *WebhookClient) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Save(delivery).Error
+}
+
+func (w *WebhookClient) GetDelivery(ctx context.Context, id uint, delivery *models.WebhookDelivery) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).First(delivery, id).Error
+}
+
+func (w *WebhookClient) ListDeliveries(ctx context.Context, webhookID uint, limit, offset int, deliveries *[]models.WebhookDelivery) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+	return database.PostgresGetDB().WithContext(ctx).Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Offset(offset).Find(deliveries).Error
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/migrations"
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestBodyFilterSQL(t *testing.T) {
+	where, args, err := services.BodyFilterSQL("postgres", bson.M{"content_id": 7})
+	require.NoError(t, err)
+	assert.Equal(t, "content_id = ?", where)
+	assert.Equal(t, []interface{}{int64(7)}, args)
+
+	where, args, err = services.BodyFilterSQL("postgres", bson.D{{Key: "content_id", Value: uint(7)}, {Key: "format", Value: "markdown"}})
+	require.NoError(t, err)
+	assert.Equal(t, "content_id = ? AND doc @> CAST(? AS jsonb)", where)
+	assert.Equal(t, []interface{}{int64(7), `{"format":"markdown"}`}, args)
+
+	where, _, err = services.BodyFilterSQL("postgres", bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, "TRUE", where)
+
+	for _, filter := range []interface{}{
+		bson.M{"content_id": bson.M{"$in": []int{1, 2}}},
+		bson.M{"$or": []bson.M{{"content_id": 1}}},
+		bson.M{"rendered.html": "<p>x</p>"},
+		bson.M{"content_id": "7"},
+	} {
+		_, _, err := services.BodyFilterSQL("postgres", filter)
+		assert.ErrorIs(t, err, services.ErrUnsupportedFilter, "%v", filter)
+	}
+}
+
+func TestBodyFilterSQLite(t *testing.T) {
+	where, args, err := services.BodyFilterSQL("sqlite", bson.D{{Key: "format", Value: "markdown"}, {Key: "content_id", Value: 7}, {Key: "body", Value: nil}})
+	require.NoError(t, err)
+	assert.Equal(t, "content_id = ? AND json_extract(doc, '$.body') IS NULL AND json_extract(doc, '$.format') = ?", where)
+	assert.Equal(t, []interface{}{int64(7), "markdown"}, args)
+
+	// json_extract solo compara escalares
+	_, _, err = services.BodyFilterSQL("sqlite", bson.M{"rendered": bson.M{"html": "<p>x</p>"}})
+	assert.ErrorIs(t, err, services.ErrUnsupportedFilter)
+	_, _, err = services.BodyFilterSQL("sqlite", bson.M{"it's": "x"})
+	assert.ErrorIs(t, err, services.ErrUnsupportedFilter)
+}
+
+// testPostgres abre TEST_POSTGRES_URL con el esquema migrado, o salta el test
+func testPostgres(t *testing.T) *gorm.DB {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	migrator, err := migrations.NewPostgresMigrator(sqlDB)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+// testSQLite abre un archivo de SQLite temporal con el esquema migrado
+func testSQLite(t *testing.T) *gorm.DB {
+	db, err := database.SQLiteOpen(filepath.Join(t.TempDir(), "cms.db"))
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	migrator, err := migrations.NewSQLiteMigrator(sqlDB)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func TestPostgresBodyStore(t *testing.T) {
+	db := testPostgres(t)
+	require.NoError(t, db.Exec("DELETE FROM content_bodies").Error)
+	testBodyStore(t, services.NewSQLBodyStore(db))
+}
+
+func TestSQLiteBodyStore(t *testing.T) {
+	testBodyStore(t, services.NewSQLBodyStore(testSQLite(t)))
+}
+
+func testBodyStore(t *testing.T, store *services.SQLBodyStore) {
+	ctx := context.Background()
+
+	rendered, err := services.RenderBody(services.FormatMarkdown, "# Hola")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "# Hola", Format: services.FormatMarkdown, Rendered: rendered}))
+	assert.Error(t, store.InsertContentBody(ctx, &models.ContentBody{ContentID: 1}))
+
+	var body models.ContentBody
+	require.NoError(t, store.GetContentBody(ctx, bson.M{"content_id": 1, "format": services.FormatMarkdown}, &body))
+	assert.Equal(t, "# Hola", body.Body)
+	assert.Equal(t, rendered.HTML, body.Rendered.HTML)
+	assert.ErrorIs(t, store.GetContentBody(ctx, bson.M{"content_id": 2}, &body), mongo.ErrNoDocuments)
+
+	// $set reemplaza solo los campos indicados
+	require.NoError(t, store.UpdateContentBody(ctx, bson.M{"content_id": 1}, bson.M{"$set": bson.M{"body": "Adiós", "title": "T"}}))
+	require.NoError(t, store.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+	assert.Equal(t, "Adiós", body.Body)
+	assert.Equal(t, services.FormatMarkdown, body.Format)
+
+	require.NoError(t, store.UpsertContentBody(ctx, &models.ContentBody{ContentID: 2, Body: "dos"}))
+	// Sobre un cuerpo existente, el upsert conserva los campos que no trae
+	require.NoError(t, store.UpsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "Otra vez"}))
+	require.NoError(t, store.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+	assert.Equal(t, "Otra vez", body.Body)
+	assert.Equal(t, rendered.HTML, body.Rendered.HTML)
+	ids, err := store.BodyContentIDs(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{1, 2}, ids)
+
+	require.NoError(t, store.DeleteContentBody(ctx, bson.M{"content_id": 1}))
+	assert.ErrorIs(t, store.GetContentBody(ctx, bson.M{"content_id": 1}, &body), mongo.ErrNoDocuments)
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+// Mock para ConsistencyRepository
+type MockConsistencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockConsistencyRepository) ContentRows(ctx context.Context) ([]uint, []uint, error) {
+	args := m.Called()
+	return args.Get(0).([]uint), args.Get(1).([]uint), args.Error(2)
+}
+
+func (m *MockConsistencyRepository) BodyContentIDs(ctx context.Context) ([]uint, error) {
+	args := m.Called()
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockConsistencyRepository) PendingContentIDs(ctx context.Context) ([]uint, error) {
+	args := m.Called()
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockConsistencyRepository) ContentState(ctx context.Context, id uint) (bool, bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+// Filas vivas 1, 2, 3 y 6 (6 con evento pendiente); borrada 4.
+// Cuerpos: 1, 1 (duplicado), 3, 4 (borrado) y 5 (huérfano).
+func driftedRepository() *MockConsistencyRepository {
+	repo := new(MockConsistencyRepository)
+	repo.On("ContentRows").Return([]uint{1, 2, 3, 6}, []uint{4}, nil)
+	repo.On("BodyContentIDs").Return([]uint{1, 1, 3, 4, 5}, nil)
+	repo.On("PendingContentIDs").Return([]uint{6}, nil)
+	repo.On("ContentState", uint(2)).Return(true, false, nil).Maybe()
+	repo.On("ContentState", uint(4)).Return(true, true, nil).Maybe()
+	repo.On("ContentState", uint(5)).Return(false, false, nil).Maybe()
+	return repo
+}
+
+func TestConsistencyReport(t *testing.T) {
+	mongoMock := new(MockMongoRepository)
+	checker := services.NewConsistencyChecker(driftedRepository(), mongoMock)
+
+	report, err := checker.Check(context.Background(), false, false)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []uint{5}, report.OrphanBodies)
+	assert.Equal(t, []uint{2}, report.MissingBodies)
+	assert.Equal(t, []uint{4}, report.DeletedWithBody)
+	assert.Equal(t, []uint{1}, report.DuplicateBodies)
+	assert.Equal(t, []uint{6}, report.Skipped)
+	assert.Empty(t, report.Repairs)
+	mongoMock.AssertNotCalled(t, "DeleteContentBody", mock.Anything, mock.Anything)
+}
+
+func TestConsistencyRepair(t *testing.T) {
+	mongoMock := new(MockMongoRepository)
+	checker := services.NewConsistencyChecker(driftedRepository(), mongoMock)
+	app := fiber.New()
+	app.Post("/admin/consistency/repair", services.NewConsistencyHandler(checker).RepairConsistency)
+
+	// dry-run lista las acciones sin tocar Mongo
+	resp, err := app.Test(httptest.NewRequest("POST", "/admin/consistency/repair?dry_run=true", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var report services.ConsistencyReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []services.ConsistencyRepair{
+		{ContentID: 5, Action: services.RepairDeleteBody},
+		{ContentID: 4, Action: services.RepairDeleteBody},
+		{ContentID: 2, Action: services.RepairCreateBody},
+	}, report.Repairs)
+	mongoMock.AssertExpectations(t)
+
+	mongoMock.On("DeleteContentBody", mock.Anything, bson.M{"content_id": 5}).Return(nil).Once()
+	mongoMock.On("DeleteContentBody", mock.Anything, bson.M{"content_id": 4}).Return(nil).Once()
+	mongoMock.On("InsertContentBody", mock.Anything, mock.MatchedBy(func(body *models.ContentBody) bool {
+		return body.ContentID == 2 && body.Rendered != nil
+	})).Return(nil).Once()
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/admin/consistency/repair", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	mongoMock.AssertExpectations(t)
+}
+
+// Un contenido creado entre las dos lecturas parece huérfano, y el cuerpo de
+// otro llega después de detectarse que faltaba: ninguno de los dos se toca.
+func TestConsistencyRepairRechecksRows(t *testing.T) {
+	repo := new(MockConsistencyRepository)
+	repo.On("ContentRows").Return([]uint{1, 2}, []uint{}, nil)
+	repo.On("BodyContentIDs").Return([]uint{1, 7}, nil)
+	repo.On("PendingContentIDs").Return([]uint{}, nil)
+	repo.On("ContentState", uint(7)).Return(true, false, nil)
+	repo.On("ContentState", uint(2)).Return(true, false, nil)
+	mongoMock := new(MockMongoRepository)
+	mongoMock.On("InsertContentBody", mock.Anything, mock.MatchedBy(func(body *models.ContentBody) bool {
+		return body.ContentID == 2
+	})).Return(gorm.ErrDuplicatedKey).Once()
+
+	cache := newMemoryCache()
+	checker := services.NewConsistencyChecker(repo, mongoMock)
+	checker.Cache = services.NewContentCache(cache)
+	report, err := checker.Check(context.Background(), true, false)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{7}, report.OrphanBodies)
+	assert.Equal(t, []uint{2}, report.MissingBodies)
+	assert.Equal(t, []services.ConsistencyRepair{
+		{ContentID: 7, Action: services.RepairDeleteBody, Skipped: true},
+		{ContentID: 2, Action: services.RepairCreateBody, Skipped: true},
+	}, report.Repairs)
+	mongoMock.AssertNotCalled(t, "DeleteContentBody", mock.Anything, mock.Anything)
+	mongoMock.AssertNotCalled(t, "UpsertContentBody", mock.Anything, mock.Anything)
+	mongoMock.AssertExpectations(t)
+	assert.Empty(t, cache.data)
+}
+
+func TestConsistencyRepairInvalidatesCache(t *testing.T) {
+	mongoMock := new(MockMongoRepository)
+	mongoMock.On("DeleteContentBody", mock.Anything, mock.Anything).Return(nil)
+	mongoMock.On("InsertContentBody", mock.Anything, mock.Anything).Return(nil)
+	cache := newMemoryCache()
+	checker := services.NewConsistencyChecker(driftedRepository(), mongoMock)
+	checker.Cache = services.NewContentCache(cache)
+
+	_, err := checker.Check(context.Background(), true, false)
+	require.NoError(t, err)
+	for _, tag := range []string{"content:2", "content:4", "content:5", "contents"} {
+		assert.Contains(t, cache.data, "cache:tag:"+tag)
+	}
+	assert.NotContains(t, cache.data, "cache:tag:content:1")
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryCache es un CacheRepository en memoria que ignora la expiración
+type memoryCache struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{data: map[string]string{}}
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.data[key]
+	if !ok {
+		return "", errors.New("cache miss")
+	}
+	return value, nil
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func TestContentCacheFetchAndInvalidate(t *testing.T) {
+	cache := services.NewContentCache(newMemoryCache())
+	ctx := context.Background()
+	loads := 0
+	load := func() (interface{}, error) {
+		loads++
+		return []string{"a", "b"}, nil
+	}
+
+	var out []string
+	require.NoError(t, cache.Fetch(ctx, "k", []string{"content:1"}, &out, load))
+	require.NoError(t, cache.Fetch(ctx, "k", []string{"content:1"}, &out, load))
+	assert.Equal(t, []string{"a", "b"}, out)
+	assert.Equal(t, 1, loads)
+
+	cache.Invalidate(ctx, "content:2")
+	require.NoError(t, cache.Fetch(ctx, "k", []string{"content:1"}, &out, load))
+	assert.Equal(t, 1, loads)
+
+	cache.InvalidateContent(ctx, 1)
+	require.NoError(t, cache.Fetch(ctx, "k", []string{"content:1"}, &out, load))
+	assert.Equal(t, 2, loads)
+}
+
+func TestContentCacheNegativeAndStampede(t *testing.T) {
+	cache := services.NewContentCache(newMemoryCache())
+	ctx := context.Background()
+
+	misses := 0
+	for i := 0; i < 3; i++ {
+		var out string
+		err := cache.Fetch(ctx, "missing", nil, &out, func() (interface{}, error) {
+			misses++
+			return nil, services.ErrCacheNotFound
+		})
+		assert.ErrorIs(t, err, services.ErrCacheNotFound)
+	}
+	assert.Equal(t, 1, misses)
+
+	var loads int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out int
+			_ = cache.Fetch(ctx, "slow", nil, &out, func() (interface{}, error) {
+				atomic.AddInt32(&loads, 1)
+				time.Sleep(50 * time.Millisecond)
+				return 42, nil
+			})
+			assert.Equal(t, 42, out)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads)
+}
+
+func TestListContentsCachedAsJSON(t *testing.T) {
+	pgMock := new(MockPGRepository)
+	handler := services.NewContentHandler(pgMock, new(MockMongoRepository), newMemoryCache())
+	app := fiber.New()
+	app.Get("/collection", handler.ListContents)
+	app.Get("/collection/:id<int>", handler.GetContent)
+	app.Delete("/collection/:id<int>", handler.DeleteContent)
+
+	pgMock.On("FindContents", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]models.Content) = []models.Content{{Model: gorm.Model{ID: 1}, Title: "Hola"}}
+	}).Return(nil).Once()
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/collection", nil), -1)
+		require.NoError(t, err)
+		var contents []models.Content
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&contents))
+		require.Len(t, contents, 1)
+		assert.Equal(t, "Hola", contents[0].Title)
+	}
+
+	// Un id inexistente se cachea como 404 durante NegativeTTL
+	pgMock.On("GetContent", mock.Anything, "9", mock.Anything).Return(gorm.ErrRecordNotFound).Once()
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/collection/9", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	}
+
+	// Borrar un contenido invalida los listados
+	mongoMock := handler.Mongo.(*MockMongoRepository)
+	pgMock.On("GetContent", mock.Anything, "1", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Content).ID = 1
+	}).Return(nil).Once()
+	pgMock.On("DeleteContent", mock.Anything, mock.Anything).Return(nil).Once()
+	mongoMock.On("DeleteContentBody", mock.Anything, mock.Anything).Return(nil).Once()
+	pgMock.On("FindContents", mock.Anything, mock.Anything).Return(nil).Once()
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/collection/1", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("GET", "/collection", nil), -1)
+	require.NoError(t, err)
+	var contents []models.Content
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&contents))
+	assert.Empty(t, contents)
+	pgMock.AssertExpectations(t)
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/routes"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
+)
+
+// Mock para ContentTypeRepository
+type MockContentTypeRepository struct {
+	mock.Mock
+}
+
+func (m *MockContentTypeRepository) ListContentTypes(ctx context.Context, types *[]models.ContentType) error {
+	return m.Called(ctx, types).Error(0)
+}
+
+func (m *MockContentTypeRepository) GetContentType(ctx context.Context, slug string, contentType *models.ContentType) error {
+	return m.Called(ctx, slug, contentType).Error(0)
+}
+
+func (m *MockContentTypeRepository) SaveContentType(ctx context.Context, contentType *models.ContentType) error {
+	return m.Called(ctx, contentType).Error(0)
+}
+
+func (m *MockContentTypeRepository) DeleteContentType(ctx context.Context, contentType *models.ContentType) error {
+	return m.Called(ctx, contentType).Error(0)
+}
+
+// Mock para EntryRepository
+type MockEntryRepository struct {
+	mock.Mock
+}
+
+func (m *MockEntryRepository) InsertEntry(ctx context.Context, entry *models.Entry) error {
+	return m.Called(ctx, entry).Error(0)
+}
+
+func (m *MockEntryRepository) FindEntries(ctx context.Context, typeSlug string, limit, offset int, entries *[]models.Entry) error {
+	return m.Called(ctx, typeSlug, limit, offset, entries).Error(0)
+}
+
+func (m *MockEntryRepository) GetEntry(ctx context.Context, typeSlug string, id primitive.ObjectID, entry *models.Entry) error {
+	return m.Called(ctx, typeSlug, id, entry).Error(0)
+}
+
+func (m *MockEntryRepository) ReplaceEntry(ctx context.Context, entry *models.Entry) error {
+	return m.Called(ctx, entry).Error(0)
+}
+
+func (m *MockEntryRepository) DeleteEntry(ctx context.Context, typeSlug string, id primitive.ObjectID) error {
+	return m.Called(ctx, typeSlug, id).Error(0)
+}
+
+func (m *MockEntryRepository) CountEntries(ctx context.Context, typeSlug string) (int64, error) {
+	args := m.Called(ctx, typeSlug)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+var articleType = models.ContentType{
+	Name: "Article",
+	Slug: "article",
+	Fields: models.FieldDefinitions{
+		{Name: "title", Type: models.FieldText, Required: true, MaxLength: intPtr(10)},
+		{Name: "views", Type: models.FieldNumber, Integer: true},
+		{Name: "published", Type: models.FieldDate},
+		{Name: "status", Type: models.FieldEnum, Options: []string{"draft", "published"}},
+		{Name: "author", Type: models.FieldReference, RefType: "author"},
+		{Name: "meta", Type: models.FieldJSON},
+	},
+}
+
+func TestValidateContentType(t *testing.T) {
+	assert.Empty(t, services.ValidateContentType(&articleType))
+
+	invalid := models.ContentType{
+		Name: "Bad",
+		Slug: "bad",
+		Fields: models.FieldDefinitions{
+			{Name: "Title", Type: models.FieldText},
+			{Name: "kind", Type: models.FieldEnum},
+			{Name: "other", Type: "color"},
+		},
+	}
+	problems := services.ValidateContentType(&invalid)
+	assert.Len(t, problems, 3)
+
+	numeric := models.ContentType{Name: "2024", Slug: "2024"}
+	assert.Contains(t, services.ValidateContentType(&numeric), "slug")
+	numeric.Slug = "2024-report"
+	assert.Empty(t, services.ValidateContentType(&numeric))
+}
+
+func TestValidateEntry(t *testing.T) {
+	clean, problems := services.ValidateEntry(articleType, map[string]interface{}{
+		"title":     "Hola",
+		"views":     float64(3),
+		"published": "2024-05-01",
+		"status":    "draft",
+		"meta":      map[string]interface{}{"a": 1},
+	})
+	require.Empty(t, problems)
+	assert.Equal(t, int64(3), clean["views"])
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), clean["published"])
+
+	_, problems = services.ValidateEntry(articleType, map[string]interface{}{
+		"title":  "Un título demasiado largo",
+		"views":  1.5,
+		"status": "archived",
+		"author": "not-an-id",
+		"extra":  true,
+	})
+	assert.Contains(t, problems, "title")
+	assert.Contains(t, problems, "views")
+	assert.Contains(t, problems, "status")
+	assert.Contains(t, problems, "author")
+	assert.Contains(t, problems, "extra")
+
+	_, problems = services.ValidateEntry(articleType, map[string]interface{}{})
+	assert.Equal(t, "is required", problems["title"])
+}
+
+func TestCollectionRoutesPreferNumericContent(t *testing.T) {
+	pgMock := new(MockPGRepository)
+	typesMock := new(MockContentTypeRepository)
+	contentHandler := services.NewContentHandler(pgMock, new(MockMongoRepository), missCache())
+	typeHandler := services.NewContentTypeHandler(typesMock, new(MockEntryRepository))
+
+	app := fiber.New()
+	collection := app.Group("/collection")
+	routes.RegisterContentRoutes(collection, contentHandler)
+	routes.RegisterEntryRoutes(collection, typeHandler)
+
+	pgMock.On("GetContent", mock.Anything, "5", mock.AnythingOfType("*models.Content")).Return(gorm.ErrRecordNotFound)
+	typesMock.On("GetContentType", mock.Anything, "article", mock.AnythingOfType("*models.ContentType")).Return(gorm.ErrRecordNotFound)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/collection/5", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/collection/article", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	pgMock.AssertExpectations(t)
+	typesMock.AssertExpectations(t)
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// problemResponse ejecuta req y decodifica la respuesta como problem+json
+func problemResponse(t *testing.T, app *fiber.App, req *http.Request) (int, utils.ProblemDetails) {
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, utils.ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+	var problem utils.ProblemDetails
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, resp.StatusCode, problem.Status)
+	assert.Equal(t, "urn:cms:problem:"+problem.Code, problem.Type)
+	return resp.StatusCode, problem
+}
+
+func TestErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+	app.Use(recover.New())
+	app.Get("/internal", func(c *fiber.Ctx) error {
+		return errors.New("dial tcp 10.0.0.1:5432: password authentication failed")
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("boom")
+	})
+	app.Get("/api", func(c *fiber.Ctx) error {
+		return utils.ValidationError("Invalid entry", map[string]string{"title": "is required"})
+	})
+
+	status, problem := problemResponse(t, app, httptest.NewRequest("GET", "/missing", nil))
+	assert.Equal(t, fiber.StatusNotFound, status)
+	assert.Equal(t, utils.CodeNotFound, problem.Code)
+	assert.Equal(t, "/missing", problem.Instance)
+
+	// La causa de un error interno no llega al cliente
+	status, problem = problemResponse(t, app, httptest.NewRequest("GET", "/internal", nil))
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, utils.CodeInternal, problem.Code)
+	assert.NotContains(t, problem.Detail, "password")
+
+	status, problem = problemResponse(t, app, httptest.NewRequest("GET", "/panic", nil))
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, utils.CodeInternal, problem.Code)
+	assert.NotContains(t, problem.Detail, "boom")
+
+	status, problem = problemResponse(t, app, httptest.NewRequest("GET", "/api", nil))
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, utils.CodeValidationFailed, problem.Code)
+	assert.Equal(t, map[string]string{"title": "is required"}, problem.Fields)
+}
+
+func TestStoreError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{gorm.ErrRecordNotFound, fiber.StatusNotFound, utils.CodeNotFound},
+		{fmt.Errorf("find: %w", mongo.ErrNoDocuments), fiber.StatusNotFound, utils.CodeNotFound},
+		{gorm.ErrDuplicatedKey, fiber.StatusConflict, utils.CodeConflict},
+		{mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, fiber.StatusConflict, utils.CodeConflict},
+		{errors.New("connection reset"), fiber.StatusInternalServerError, utils.CodeInternal},
+	}
+	for _, tt := range tests {
+		apiErr := utils.StoreError(tt.err, "Content", "Failed to fetch content")
+		assert.Equal(t, tt.status, apiErr.Status, tt.err.Error())
+		assert.Equal(t, tt.code, apiErr.Code, tt.err.Error())
+	}
+	assert.Equal(t, "Content not found", utils.StoreError(gorm.ErrRecordNotFound, "Content", "").Detail)
+	assert.Equal(t, "Failed to fetch content", utils.StoreError(errors.New("x"), "Content", "Failed to fetch content").Detail)
+}
+
+func TestContentInvalidID(t *testing.T) {
+	app := fiber.New()
+	pgMock := new(MockPGRepository)
+	handler := services.NewContentHandler(pgMock, new(MockMongoRepository), missCache())
+	app.Get("/content/:id", handler.GetContent)
+	app.Put("/content/:id", handler.UpdateContent)
+	app.Delete("/content/:id", handler.DeleteContent)
+
+	for _, method := range []string{"GET", "PUT", "DELETE"} {
+		req := httptest.NewRequest(method, "/content/abc", strings.NewReader(`{"title":"x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		status, problem := problemResponse(t, app, req)
+		assert.Equal(t, fiber.StatusBadRequest, status, method)
+		assert.Equal(t, utils.CodeInvalidID, problem.Code, method)
+	}
+	pgMock.AssertNotCalled(t, "GetContent", mock.Anything, mock.Anything, mock.Anything)
+}
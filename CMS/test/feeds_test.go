@@ -0,0 +1,178 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Mock para FeedRepository
+type MockFeedRepository struct {
+	mock.Mock
+}
+
+func (m *MockFeedRepository) FindPublishedContents(ctx context.Context, filter services.FeedFilter, limit int, contents *[]models.Content) error {
+	args := m.Called(ctx, filter, limit, contents)
+	if list, ok := args.Get(0).([]models.Content); ok {
+		*contents = list
+	}
+	return args.Error(1)
+}
+
+func (m *MockFeedRepository) FeedState(ctx context.Context, filter services.FeedFilter) (services.FeedState, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(services.FeedState), args.Error(1)
+}
+
+func newFeedApp(t *testing.T) (*fiber.App, *MockFeedRepository) {
+	published := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	content := models.Content{
+		Model:       gorm.Model{ID: 4, UpdatedAt: published},
+		Title:       "Hola <mundo>",
+		Slug:        "hola-mundo",
+		Description: "Resumen",
+		UserID:      3,
+		PublishedAt: &published,
+		Tags:        []models.Tag{{Name: "Go"}},
+	}
+
+	repo := new(MockFeedRepository)
+	repo.On("FeedState", mock.Anything, mock.Anything).Return(services.FeedState{Count: 1, LastModified: published}, nil)
+	repo.On("FindPublishedContents", mock.Anything, mock.Anything, 50, mock.Anything).Return([]models.Content{content}, nil)
+
+	mongoMock := new(MockMongoRepository)
+	mongoMock.On("GetContentBody", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.ContentBody) = models.ContentBody{ContentID: 4, Body: "# Título", Format: services.FormatMarkdown}
+	}).Return(nil).Maybe()
+	mongoMock.On("UpdateContentBody", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	contents := services.NewContentHandler(new(MockPGRepository), mongoMock, missCache())
+
+	app := fiber.New()
+	feeds := services.NewFeedHandler(repo, contents, "Noticias", "https://example.com/")
+	app.Get("/feeds/rss.xml", feeds.RSS)
+	app.Get("/feeds/atom.xml", feeds.Atom)
+	app.Get("/feeds/feed.json", feeds.JSONFeed)
+	return app, repo
+}
+
+func TestRSSFeed(t *testing.T) {
+	app, _ := newFeedApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/feeds/rss.xml", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/rss+xml")
+	assert.Equal(t, "Sat, 01 Mar 2025 10:00:00 GMT", resp.Header.Get("Last-Modified"))
+	assert.NotEmpty(t, resp.Header.Get("ETag"))
+
+	var feed struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				Description string `xml:"description"`
+				Category    string `xml:"category"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.NewDecoder(resp.Body).Decode(&feed))
+	assert.Equal(t, "Noticias", feed.Channel.Title)
+	require.Len(t, feed.Channel.Items, 1)
+	assert.Equal(t, "Hola <mundo>", feed.Channel.Items[0].Title)
+	assert.Equal(t, "https://example.com/hola-mundo", feed.Channel.Items[0].Link)
+	assert.Contains(t, feed.Channel.Items[0].Description, "<h1")
+	assert.Equal(t, "Go", feed.Channel.Items[0].Category)
+}
+
+func TestFeedConditionalRequests(t *testing.T) {
+	app, repo := newFeedApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/feeds/atom.xml", nil))
+	require.NoError(t, err)
+	etag := resp.Header.Get("ETag")
+
+	req := httptest.NewRequest("GET", "/feeds/atom.xml", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/feeds/atom.xml", nil)
+	req.Header.Set("If-Modified-Since", "Sat, 01 Mar 2025 10:00:00 GMT")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+
+	// El ETag depende del formato
+	resp, err = app.Test(httptest.NewRequest("GET", "/feeds/rss.xml", nil))
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	// Las respuestas 304 no cargan los contenidos
+	repo.AssertNumberOfCalls(t, "FindPublishedContents", 2)
+}
+
+func TestAtomAndJSONFeeds(t *testing.T) {
+	app, _ := newFeedApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/feeds/atom.xml", nil))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.True(t, strings.Contains(string(body), `xmlns="http://www.w3.org/2005/Atom"`))
+	var atom struct {
+		Entries []struct {
+			ID        string `xml:"id"`
+			Published string `xml:"published"`
+			Content   string `xml:"content"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(body, &atom))
+	require.Len(t, atom.Entries, 1)
+	assert.Equal(t, "2025-03-01T10:00:00Z", atom.Entries[0].Published)
+	assert.Contains(t, atom.Entries[0].Content, "Título")
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/feeds/feed.json", nil))
+	require.NoError(t, err)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/feed+json")
+	var feed struct {
+		Version string `json:"version"`
+		Items   []struct {
+			URL     string   `json:"url"`
+			Summary string   `json:"summary"`
+			Tags    []string `json:"tags"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&feed))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", feed.Version)
+	require.Len(t, feed.Items, 1)
+	assert.Equal(t, "Resumen", feed.Items[0].Summary)
+	assert.Equal(t, []string{"Go"}, feed.Items[0].Tags)
+}
+
+func TestFeedFilters(t *testing.T) {
+	app, repo := newFeedApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/feeds/feed.json?tag=Go%20News&author=3&category=9", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	repo.AssertCalled(t, "FeedState", mock.Anything, services.FeedFilter{TagSlug: "go-news", AuthorID: 3, CategoryIDs: []uint{9}})
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/feeds/feed.json?author=abc", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func healthCheck(name string, critical bool, err error) services.HealthCheck {
+	return services.HealthCheck{Name: name, Critical: critical, Check: func(ctx context.Context) error { return err }}
+}
+
+func readyz(t *testing.T, h *services.HealthHandler) (int, services.HealthReport) {
+	app := fiber.New()
+	app.Get("/readyz", h.Readiness)
+	resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil), -1)
+	require.NoError(t, err)
+	var report services.HealthReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func TestReadinessStatuses(t *testing.T) {
+	status, report := readyz(t, services.NewHealthHandler(healthCheck("postgres", true, nil), healthCheck("redis", false, nil)))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, services.HealthOK, report.Status)
+	assert.Equal(t, "up", report.Checks["postgres"].Status)
+
+	// Sin caché el servicio sigue listo, pero degradado
+	status, report = readyz(t, services.NewHealthHandler(healthCheck("postgres", true, nil), healthCheck("redis", false, errors.New("connection refused"))))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, services.HealthDegraded, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["redis"].Error)
+
+	status, report = readyz(t, services.NewHealthHandler(healthCheck("postgres", true, errors.New("down")), healthCheck("redis", false, errors.New("down"))))
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, services.HealthUnavailable, report.Status)
+}
+
+func TestReadinessTimeoutAndDraining(t *testing.T) {
+	slow := services.HealthCheck{Name: "mongo", Critical: true, Check: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}}
+	h := services.NewHealthHandler(slow)
+	h.Timeout = 20 * time.Millisecond
+
+	start := time.Now()
+	status, report := readyz(t, h)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Contains(t, report.Checks["mongo"].Error, "timeout")
+
+	h = services.NewHealthHandler(healthCheck("postgres", true, nil))
+	h.SetDraining()
+	status, _ = readyz(t, h)
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+
+	app := fiber.New()
+	app.Get("/healthz", h.Liveness)
+	resp, err := app.Test(httptest.NewRequest("GET", "/healthz", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,214 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func gradientPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestTransformImageFits(t *testing.T) {
+	data := gradientPNG(t, 200, 100)
+	cases := []struct {
+		params services.ImageParams
+		w, h   int
+		mime   string
+	}{
+		{services.ImageParams{Width: 50, Fit: "contain", Quality: 80}, 50, 25, "image/png"},
+		{services.ImageParams{Width: 50, Height: 50, Fit: "contain", Quality: 80}, 50, 25, "image/png"},
+		{services.ImageParams{Width: 50, Height: 50, Fit: "cover", Format: "jpeg", Quality: 80}, 50, 50, "image/jpeg"},
+		{services.ImageParams{Width: 30, Height: 60, Fit: "fill", Format: "webp", Quality: 80}, 30, 60, "image/webp"},
+		// contain no agranda el original
+		{services.ImageParams{Width: 800, Fit: "contain", Format: "gif", Quality: 80}, 200, 100, "image/gif"},
+	}
+	for _, tc := range cases {
+		out, mime, err := services.TransformImage(bytes.NewReader(data), tc.params, "image/png", services.DefaultMaxImagePixels)
+		require.NoError(t, err)
+		assert.Equal(t, tc.mime, mime)
+		config, _, err := image.DecodeConfig(bytes.NewReader(out))
+		require.NoError(t, err, tc.params.Canonical())
+		assert.Equal(t, tc.w, config.Width, tc.params.Canonical())
+		assert.Equal(t, tc.h, config.Height, tc.params.Canonical())
+	}
+}
+
+// pngHeader devuelve un PNG que declara w×h píxeles pero no trae datos
+func pngHeader(w, h uint32) []byte {
+	chunk := func(kind string, data []byte) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		out = append(out, kind...)
+		out = append(out, data...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+	}
+	ihdr := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, w), h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // RGBA de 8 bits
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = append(out, chunk("IHDR", ihdr)...)
+	return append(out, chunk("IEND", nil)...)
+}
+
+func TestTransformImageRejectsDecompressionBombs(t *testing.T) {
+	// 50000×50000 RGBA serían 10 GB al decodificar
+	_, _, err := services.TransformImage(bytes.NewReader(pngHeader(50000, 50000)), services.ImageParams{Width: 10, Fit: "contain"}, "image/png", services.DefaultMaxImagePixels)
+	assert.ErrorIs(t, err, services.ErrImageTooLarge)
+
+	data := gradientPNG(t, 200, 100)
+	_, _, err = services.TransformImage(bytes.NewReader(data), services.ImageParams{Width: 10, Fit: "contain"}, "image/png", 200*100-1)
+	assert.ErrorIs(t, err, services.ErrImageTooLarge)
+	_, _, err = services.TransformImage(bytes.NewReader(data), services.ImageParams{Width: 10, Fit: "contain"}, "image/png", 200*100)
+	assert.NoError(t, err)
+}
+
+func TestParseImageParams(t *testing.T) {
+	query := func(values map[string]string) func(string, ...string) string {
+		return func(key string, _ ...string) string { return values[key] }
+	}
+
+	params, err := services.ParseImageParams(query(map[string]string{"w": "800", "fmt": "JPG"}))
+	require.NoError(t, err)
+	assert.Equal(t, "fit=contain&fmt=jpeg&q=82&w=800", params.Canonical())
+
+	for _, bad := range []map[string]string{{"w": "0"}, {"h": "99999"}, {"fit": "stretch"}, {"fmt": "bmp"}, {"q": "101"}} {
+		_, err := services.ParseImageParams(query(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestServeSignedVariant(t *testing.T) {
+	store, err := services.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+	media := models.Media{ID: 7, MimeType: "image/png", SHA256: "abcdef0123", StorageKey: "ab/abcdef0123.png"}
+	data := gradientPNG(t, 120, 80)
+	require.NoError(t, store.Put(ctx, media.StorageKey, bytes.NewReader(data), int64(len(data)), media.MimeType))
+
+	repo := new(MockMediaRepository)
+	repo.On("GetMedia", mock.Anything, uint(7), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Media) = media
+	}).Return(nil)
+	cache := new(MockCacheRepository)
+
+	handler := services.NewMediaHandler(repo, store, 0)
+	handler.SigningKey = []byte("secret")
+	handler.Cache = cache
+	app := fiber.New()
+	app.Get("/media/:id<int>", handler.ServeMedia)
+
+	// El original se envía tal cual, con su tamaño
+	resp, err := app.Test(httptest.NewRequest("GET", "/media/7", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(len(data)), resp.ContentLength)
+	original, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, data, original)
+
+	params := services.ImageParams{Width: 60, Height: 60, Fit: "cover", Format: "webp", Quality: 82}
+	url := services.SignedImageURL(handler.SigningKey, 7, params)
+
+	// Primera petición: no está en Redis, se genera y se guarda en el BlobStore
+	cache.On("Get", mock.Anything, mock.Anything).Return("", errors.New("miss")).Once()
+	cache.On("Set", mock.Anything, mock.Anything, "1", mock.Anything).Return(nil).Once()
+	resp, err = app.Test(httptest.NewRequest("GET", url, nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/webp", resp.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(resp.Body)
+	config, _, err := image.DecodeConfig(bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, 60, config.Width)
+	cacheKey := cache.Calls[1].Arguments.String(1)
+
+	// Segunda petición: Redis indica que la variante ya existe y no se recalcula
+	require.NoError(t, store.Delete(ctx, media.StorageKey))
+	cache.On("Get", mock.Anything, cacheKey).Return("1", nil).Once()
+	resp, err = app.Test(httptest.NewRequest("GET", url, nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Parámetros alterados o sin firma
+	resp, err = app.Test(httptest.NewRequest("GET", "/media/7?w=61&h=60&fit=cover&fmt=webp&q=82&s="+services.SignImageParams(handler.SigningKey, 7, params), nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("GET", fmt.Sprintf("/media/7?w=%d", 60), nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	cache.AssertExpectations(t)
+}
+
+func TestDeleteMediaRemovesVariants(t *testing.T) {
+	store, err := services.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+	media := models.Media{ID: 7, MimeType: "image/png", SHA256: "abcdef0123", StorageKey: "ab/abcdef0123.png"}
+	data := gradientPNG(t, 120, 80)
+	require.NoError(t, store.Put(ctx, media.StorageKey, bytes.NewReader(data), int64(len(data)), media.MimeType))
+
+	repo := new(MockMediaRepository)
+	repo.On("GetMedia", mock.Anything, uint(7), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Media) = media
+	}).Return(nil)
+	repo.On("DeleteMedia", mock.Anything, mock.Anything).Return(nil).Once()
+	repo.On("FindMediaByHash", mock.Anything, media.SHA256, mock.Anything).Return(gorm.ErrRecordNotFound).Once()
+	cache := missCache()
+	cache.On("DeletePrefix", mock.Anything, mock.MatchedBy(func(prefix string) bool {
+		return strings.HasPrefix(prefix, "media:variant:") && strings.Contains(prefix, media.SHA256)
+	})).Return(int64(1), nil).Once()
+
+	handler := services.NewMediaHandler(repo, store, 0)
+	handler.SigningKey = []byte("secret")
+	handler.Cache = cache
+	app := fiber.New()
+	app.Get("/media/:id<int>", handler.ServeMedia)
+	app.Delete("/media/:id<int>", handler.DeleteMedia)
+
+	params := services.ImageParams{Width: 60, Height: 60, Fit: "cover", Format: "webp", Quality: 82}
+	resp, err := app.Test(httptest.NewRequest("GET", services.SignedImageURL(handler.SigningKey, 7, params), nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	variantKey := cache.Calls[1].Arguments.String(1)[len("media:variant:"):]
+	variant, _, err := store.Get(ctx, variantKey)
+	require.NoError(t, err)
+	variant.Close()
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/media/7", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Ni el original ni la variante sobreviven al borrado
+	_, _, err = store.Get(ctx, media.StorageKey)
+	assert.ErrorIs(t, err, services.ErrBlobNotFound)
+	_, _, err = store.Get(ctx, variantKey)
+	assert.ErrorIs(t, err, services.ErrBlobNotFound)
+	cache.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
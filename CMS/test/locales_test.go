@@ -0,0 +1,169 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+// Mock para TranslationRepository
+type MockTranslationRepository struct {
+	mock.Mock
+}
+
+func (m *MockTranslationRepository) GetTranslation(ctx context.Context, contentID uint, locale string, translation *models.ContentTranslation) error {
+	args := m.Called(contentID, locale)
+	if t, ok := args.Get(0).(models.ContentTranslation); ok {
+		*translation = t
+	}
+	return args.Error(1)
+}
+
+func (m *MockTranslationRepository) ListTranslations(ctx context.Context, contentID uint, translations *[]models.ContentTranslation) error {
+	args := m.Called(contentID)
+	if list, ok := args.Get(0).([]models.ContentTranslation); ok {
+		*translations = list
+	}
+	return args.Error(1)
+}
+
+func (m *MockTranslationRepository) SaveTranslation(ctx context.Context, translation *models.ContentTranslation) error {
+	return m.Called(translation).Error(0)
+}
+
+func (m *MockTranslationRepository) DeleteTranslation(ctx context.Context, contentID uint, locale string) error {
+	return m.Called(contentID, locale).Error(0)
+}
+
+func (m *MockTranslationRepository) DeleteTranslations(ctx context.Context, contentID uint) error {
+	return m.Called(contentID).Error(0)
+}
+
+func TestLocalesNegotiateAndChain(t *testing.T) {
+	locales, err := services.NewLocales("es, en, pt, es-MX", "pt:en")
+	require.NoError(t, err)
+	assert.Equal(t, "es", locales.Default)
+
+	locale, err := locales.Negotiate("", "fr;q=0.9, en-US;q=0.8, es;q=0.5")
+	require.NoError(t, err)
+	assert.Equal(t, "en", locale)
+
+	locale, err = locales.Negotiate("EN", "es")
+	require.NoError(t, err)
+	assert.Equal(t, "en", locale)
+
+	_, err = locales.Negotiate("fr", "")
+	assert.ErrorIs(t, err, services.ErrUnsupportedLocale)
+
+	assert.Equal(t, []string{"pt", "en", "es"}, locales.Chain("pt"))
+	assert.Equal(t, []string{"es-MX", "es"}, locales.Chain("es-MX"))
+
+	_, err = services.NewLocales("es,en", "fr:es")
+	assert.Error(t, err)
+}
+
+func localizedHandler(t *testing.T) (*services.ContentHandler, *MockPGRepository, *MockMongoRepository, *MockTranslationRepository) {
+	pgMock := new(MockPGRepository)
+	mongoMock := new(MockMongoRepository)
+	translations := new(MockTranslationRepository)
+	handler := services.NewContentHandler(pgMock, mongoMock, missCache())
+	locales, err := services.NewLocales("es,en,pt", "pt:en")
+	require.NoError(t, err)
+	handler.Locales = locales
+	handler.Translations = translations
+
+	pgMock.On("GetContent", mock.Anything, "1", mock.AnythingOfType("*models.Content")).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Content) = models.Content{
+			Model:  gorm.Model{ID: 1, UpdatedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+			Title:  "Hola",
+			Locale: "es",
+		}
+	}).Return(nil)
+	return handler, pgMock, mongoMock, translations
+}
+
+func TestGetContentLocalized(t *testing.T) {
+	handler, _, mongoMock, translations := localizedHandler(t)
+	app := fiber.New()
+	app.Get("/collection/:id<int>", handler.GetContent)
+
+	english := models.ContentTranslation{ContentID: 1, Locale: "en", Title: "Hello", Body: "Hi there", Format: services.FormatPlain}
+	translations.On("GetTranslation", uint(1), "en").Return(english, nil)
+	translations.On("GetTranslation", uint(1), "pt").Return(nil, errors.New("not found"))
+	// El renderizado de la traducción se calcula y se guarda en la primera lectura
+	translations.On("SaveTranslation", mock.AnythingOfType("*models.ContentTranslation")).Return(nil)
+
+	// pt no existe y su respaldo es en
+	req := httptest.NewRequest("GET", "/collection/1?locale=pt", nil)
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "en", resp.Header.Get("Content-Language"))
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "Hello", body["title"])
+	assert.Equal(t, "en", body["locale"])
+
+	// Accept-Language en español sirve el original sin consultar traducciones
+	mongoMock.On("GetContentBody", mock.Anything, bson.M{"content_id": 1}, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.ContentBody).Body = "Hola a todos"
+	}).Return(nil)
+	mongoMock.On("UpdateContentBody", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	req = httptest.NewRequest("GET", "/collection/1", nil)
+	req.Header.Set("Accept-Language", "es-AR,es;q=0.9")
+	resp, err = app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "Hola", body["title"])
+	assert.Equal(t, "es", body["locale"])
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/collection/1?locale=fr", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestMissingTranslations(t *testing.T) {
+	handler, _, _, translations := localizedHandler(t)
+	app := fiber.New()
+	app.Get("/collection/:id<int>/translations/missing", handler.MissingTranslations)
+	app.Put("/collection/:id<int>/translations/:locale", handler.SaveTranslation)
+
+	translations.On("ListTranslations", uint(1)).Return([]models.ContentTranslation{
+		{ContentID: 1, Locale: "en", UpdatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/collection/1/translations/missing", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var report struct {
+		Available []string `json:"available"`
+		Missing   []string `json:"missing"`
+		Outdated  []string `json:"outdated"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, []string{"es", "en"}, report.Available)
+	assert.Equal(t, []string{"pt"}, report.Missing)
+	assert.Equal(t, []string{"en"}, report.Outdated)
+
+	// El idioma original no se guarda como traducción
+	req := httptest.NewRequest("PUT", "/collection/1/translations/es", jsonBody(map[string]string{"title": "Hola"}))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
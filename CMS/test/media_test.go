@@ -0,0 +1,259 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Mock para MediaRepository
+type MockMediaRepository struct {
+	mock.Mock
+}
+
+func (m *MockMediaRepository) CreateMedia(ctx context.Context, media *models.Media) error {
+	return m.Called(ctx, media).Error(0)
+}
+
+func (m *MockMediaRepository) GetMedia(ctx context.Context, id uint, media *models.Media) error {
+	return m.Called(ctx, id, media).Error(0)
+}
+
+func (m *MockMediaRepository) FindMediaByHash(ctx context.Context, sha string, media *models.Media) error {
+	return m.Called(ctx, sha, media).Error(0)
+}
+
+func (m *MockMediaRepository) ListMedia(ctx context.Context, limit, offset int, media *[]models.Media) error {
+	return m.Called(ctx, limit, offset, media).Error(0)
+}
+
+func (m *MockMediaRepository) DeleteMedia(ctx context.Context, media *models.Media) error {
+	return m.Called(ctx, media).Error(0)
+}
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+// uploadRequest arma un POST multipart con el archivo en el campo "file"
+func uploadRequest(t *testing.T, filename string, data []byte) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/media", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return authenticate(t, req, "7", services.RoleAuthor)
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	store, err := services.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "ab/abc.txt", bytes.NewReader([]byte("hola")), 4, "text/plain"))
+	blob, size, err := store.Get(ctx, "ab/abc.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), size)
+	data, _ := io.ReadAll(blob)
+	blob.Close()
+	assert.Equal(t, "hola", string(data))
+
+	require.NoError(t, store.Delete(ctx, "ab/abc.txt"))
+	_, _, err = store.Get(ctx, "ab/abc.txt")
+	assert.ErrorIs(t, err, services.ErrBlobNotFound)
+
+	assert.Error(t, store.Put(ctx, "../escape", bytes.NewReader(nil), 0, ""))
+}
+
+func TestUploadMedia(t *testing.T) {
+	store, err := services.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	repo := new(MockMediaRepository)
+	handler := services.NewMediaHandler(repo, store, 1<<20)
+
+	app := fiber.New()
+	app.Use(utils.AuthMiddleware(testSecret))
+	app.Post("/media", handler.UploadMedia)
+
+	// El tipo se detecta por contenido: la extensión .txt no importa
+	data := pngBytes(t, 4, 3)
+	var saved *models.Media
+	repo.On("FindMediaByHash", mock.Anything, mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound).Once()
+	repo.On("CreateMedia", mock.Anything, mock.AnythingOfType("*models.Media")).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.Media)
+	}).Return(nil).Once()
+
+	resp, err := app.Test(uploadRequest(t, "logo.txt", data), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	require.NotNil(t, saved)
+	assert.Equal(t, "image/png", saved.MimeType)
+	assert.Equal(t, 4, saved.Width)
+	assert.Equal(t, 3, saved.Height)
+	assert.Equal(t, 7, saved.UserID)
+	exists, err := store.Exists(context.Background(), saved.StorageKey)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Segunda subida del mismo archivo: se devuelve el existente
+	repo.On("FindMediaByHash", mock.Anything, saved.SHA256, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Media) = *saved
+	}).Return(nil).Once()
+	resp, err = app.Test(uploadRequest(t, "copia.png", data), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, true, body["duplicate"])
+
+	resp, err = app.Test(uploadRequest(t, "script.png", []byte("#!/bin/sh\necho hola")), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = app.Test(uploadRequest(t, "big.png", append(pngBytes(t, 1, 1), make([]byte, 1<<20)...)), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+	repo.AssertExpectations(t)
+}
+
+// Solo la subida de medios admite cuerpos grandes; el resto de rutas mantiene el límite global
+func TestUploadBodyLimitOnlyOnUploadRoute(t *testing.T) {
+	store, err := services.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	repo := new(MockMediaRepository)
+	repo.On("FindMediaByHash", mock.Anything, mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound).Once()
+	repo.On("CreateMedia", mock.Anything, mock.Anything).Return(nil).Once()
+	handler := services.NewMediaHandler(repo, store, 64<<10)
+
+	// Un BodyLimit del servidor pequeño obliga a leer como stream los cuerpos de más de 512 bytes
+	app := fiber.New(fiber.Config{BodyLimit: 512, StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Use(utils.BodyLimit(1024, func(c *fiber.Ctx) bool { return c.Path() == "/media" }))
+	app.Use(utils.AuthMiddleware(testSecret))
+	app.Post("/media", utils.BodyLimit(handler.UploadBodyLimit(), nil), handler.UploadMedia)
+	app.Post("/collection", func(c *fiber.Ctx) error { return c.SendString(string(c.Body())) })
+
+	data := append(pngBytes(t, 2, 2), make([]byte, 8<<10)...)
+	resp, err := app.Test(uploadRequest(t, "grande.png", data), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	resp, err = app.Test(authenticate(t, httptest.NewRequest("POST", "/collection", bytes.NewReader(make([]byte, 2048))), "7", services.RoleAuthor), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// Un cuerpo leído como stream pero dentro del límite llega entero al handler
+	payload := strings.Repeat("a", 800)
+	resp, err = app.Test(authenticate(t, httptest.NewRequest("POST", "/collection", strings.NewReader(payload)), "7", services.RoleAuthor), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	echoed, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, payload, string(echoed))
+	repo.AssertExpectations(t)
+}
+
+// Si el mismo archivo se vuelve a subir mientras se borra, su blob no se pierde
+func TestDeleteMediaKeepsReuploadedBlob(t *testing.T) {
+	store, err := services.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+	media := models.Media{ID: 7, MimeType: "image/png", SHA256: "abcdef0123", StorageKey: "ab/abcdef0123.png"}
+	data := pngBytes(t, 2, 2)
+	require.NoError(t, store.Put(ctx, media.StorageKey, bytes.NewReader(data), int64(len(data)), media.MimeType))
+
+	repo := new(MockMediaRepository)
+	repo.On("GetMedia", mock.Anything, uint(7), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Media) = media
+	}).Return(nil)
+	repo.On("DeleteMedia", mock.Anything, mock.Anything).Return(nil).Once()
+	// La nueva subida creó su fila entre el borrado de la fila y el del blob
+	repo.On("FindMediaByHash", mock.Anything, media.SHA256, mock.Anything).Run(func(args mock.Arguments) {
+		reuploaded := media
+		reuploaded.ID = 8
+		*args.Get(2).(*models.Media) = reuploaded
+	}).Return(nil).Once()
+
+	handler := services.NewMediaHandler(repo, store, 0)
+	app := fiber.New()
+	app.Delete("/media/:id<int>", handler.DeleteMedia)
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/media/7", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	exists, err := store.Exists(ctx, media.StorageKey)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	repo.AssertExpectations(t)
+}
+
+func TestRenderResolvesMediaReferences(t *testing.T) {
+	rendered, err := services.RenderBody(services.FormatMarkdown, "![logo](media:12)")
+	require.NoError(t, err)
+	assert.Contains(t, rendered.HTML, `src="/media/12"`)
+}
+
+// TestS3BlobStore corre contra un MinIO local, p. ej.:
+// docker run -p 9000:9000 minio/minio server /data
+// MINIO_ENDPOINT=localhost:9000 go test ./test -run S3
+func TestS3BlobStore(t *testing.T) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_ENDPOINT not set")
+	}
+	store, err := services.NewS3BlobStore(services.S3Config{
+		Endpoint:  endpoint,
+		AccessKey: envOr("MINIO_ACCESS_KEY", "minioadmin"),
+		SecretKey: envOr("MINIO_SECRET_KEY", "minioadmin"),
+		Bucket:    "cms-test",
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "te/test.txt", bytes.NewReader([]byte("hola")), 4, "text/plain"))
+	exists, err := store.Exists(ctx, "te/test.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	blob, size, err := store.Get(ctx, "te/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), size)
+	data, _ := io.ReadAll(blob)
+	blob.Close()
+	assert.Equal(t, "hola", string(data))
+
+	require.NoError(t, store.Delete(ctx, "te/test.txt"))
+	_, _, err = store.Get(ctx, "te/test.txt")
+	assert.ErrorIs(t, err, services.ErrBlobNotFound)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,244 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/routes"
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// newMemoryApp monta las rutas de contenidos sobre los almacenes en memoria, como serve con storage=memory
+func newMemoryApp(t *testing.T) *fiber.App {
+	contents := services.NewMemoryContentStore()
+	handler := services.NewContentHandler(contents, services.NewMemoryBodyStore(), services.NewMemoryCache())
+	handler.Slugs = contents
+	index, err := services.NewMemoryIndex("")
+	require.NoError(t, err)
+	handler.Search = index
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", "1")
+		return c.Next()
+	})
+	routes.RegisterContentRoutes(app.Group("/collection"), handler)
+	return app
+}
+
+func memoryRequest(t *testing.T, app *fiber.App, method, path string, body interface{}) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, nil)
+	if body != nil {
+		req = httptest.NewRequest(method, path, jsonBody(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	var out map[string]interface{}
+	_ = json.Unmarshal(data, &out)
+	return resp.StatusCode, out
+}
+
+func TestMemoryStorageContentLifecycle(t *testing.T) {
+	app := newMemoryApp(t)
+
+	status, created := memoryRequest(t, app, "POST", "/collection", map[string]interface{}{"title": "Hola mundo", "body": "# Hola", "format": "markdown"})
+	require.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, "hola-mundo", created["slug"])
+
+	// El mismo título genera otro slug
+	_, second := memoryRequest(t, app, "POST", "/collection", map[string]interface{}{"title": "Hola mundo", "body": "otro"})
+	assert.Equal(t, "hola-mundo-2", second["slug"])
+
+	status, got := memoryRequest(t, app, "GET", "/collection/1", nil)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "# Hola", got["description"])
+	assert.Contains(t, got["html"], "<h1")
+
+	status, _ = memoryRequest(t, app, "PUT", "/collection/1", map[string]interface{}{"title": "Hola de nuevo", "body": "Cambiado"})
+	require.Equal(t, fiber.StatusOK, status)
+	_, got = memoryRequest(t, app, "GET", "/collection/1", nil)
+	assert.Equal(t, "Cambiado", got["description"])
+
+	status, found := memoryRequest(t, app, "GET", "/collection/search?q=cambiado", nil)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.EqualValues(t, 1, found["total"])
+
+	status, _ = memoryRequest(t, app, "DELETE", "/collection/1", nil)
+	require.Equal(t, fiber.StatusOK, status)
+	status, _ = memoryRequest(t, app, "GET", "/collection/1", nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+}
+
+func TestMemoryBodyStoreFilters(t *testing.T) {
+	ctx := context.Background()
+	store := services.NewMemoryBodyStore()
+	require.NoError(t, store.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "uno"}))
+	assert.Error(t, store.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "otro"}))
+
+	// Los números se comparan por valor, sin importar el tipo del filtro
+	var body models.ContentBody
+	require.NoError(t, store.GetContentBody(ctx, bson.M{"content_id": int64(1)}, &body))
+	assert.Equal(t, "uno", body.Body)
+	assert.ErrorIs(t, store.GetContentBody(ctx, bson.M{"content_id": 2}, &body), mongo.ErrNoDocuments)
+	assert.ErrorIs(t, store.GetContentBody(ctx, bson.M{"content_id": bson.M{"$gt": 0}}, &body), services.ErrUnsupportedFilter)
+
+	require.NoError(t, store.UpdateContentBody(ctx, bson.M{"content_id": 1}, bson.M{"$set": bson.M{"format": "markdown"}}))
+	assert.ErrorIs(t, store.UpdateContentBody(ctx, bson.M{"content_id": 1}, bson.M{"$inc": bson.M{"n": 1}}), services.ErrUnsupportedFilter)
+	require.NoError(t, store.UpsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "dos", Format: "plain"}))
+	require.NoError(t, store.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+	assert.Equal(t, models.ContentBody{ContentID: 1, Body: "dos", Format: "plain"}, body)
+
+	require.NoError(t, store.DeleteContentBody(ctx, bson.M{"content_id": 1}))
+	assert.ErrorIs(t, store.GetContentBody(ctx, bson.M{"content_id": 1}, &body), mongo.ErrNoDocuments)
+}
+
+func TestMemoryCacheExpiration(t *testing.T) {
+	ctx := context.Background()
+	cache := services.NewMemoryCache()
+	require.NoError(t, cache.Set(ctx, "corta", "v", 20*time.Millisecond))
+	require.NoError(t, cache.Set(ctx, "siempre", "v", 0))
+
+	value, err := cache.Get(ctx, "corta")
+	require.NoError(t, err)
+	assert.Equal(t, "v", value)
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.Get(ctx, "corta")
+	assert.ErrorIs(t, err, services.ErrCacheMiss)
+	_, err = cache.Get(ctx, "siempre")
+	assert.NoError(t, err)
+}
+
+func TestMemoryUserStoreRegisterAndLogin(t *testing.T) {
+	users := services.NewMemoryUserStore()
+	app := fiber.New()
+	routes.PublicRoutes(app, &services.Handler{UserRepo: users, TokenService: services.NewTokenService("s3cret")})
+
+	credentials := map[string]string{"username": "ana", "email": "ana@example.com", "password": "secreto123"}
+	status, _ := memoryRequest(t, app, "POST", "/register", credentials)
+	require.Equal(t, fiber.StatusOK, status)
+	status, _ = memoryRequest(t, app, "POST", "/register", credentials)
+	assert.Equal(t, fiber.StatusConflict, status)
+
+	status, _ = memoryRequest(t, app, "POST", "/login", map[string]string{"email": "ana@example.com", "password": "secreto123"})
+	assert.Equal(t, fiber.StatusOK, status)
+	status, _ = memoryRequest(t, app, "POST", "/login", map[string]string{"username": "ana", "password": "mala"})
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+
+	// La cuenta se puede administrar igual que en Postgres
+	banned, err := services.NewUserAdmin(users).SetBanned(context.Background(), "ana", true)
+	require.NoError(t, err)
+	assert.True(t, banned.IsBanned)
+	status, _ = memoryRequest(t, app, "POST", "/login", map[string]string{"username": "ana", "password": "secreto123"})
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func TestMemoryContentStoreFeeds(t *testing.T) {
+	ctx := context.Background()
+	store := services.NewMemoryContentStore()
+	older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	require.NoError(t, store.CreateContent(ctx, &models.Content{Title: "Viejo", Slug: "viejo", UserID: 1, PublishedAt: &older}))
+	require.NoError(t, store.CreateContent(ctx, &models.Content{Title: "Nuevo", Slug: "nuevo", UserID: 2, PublishedAt: &newer}))
+	require.NoError(t, store.CreateContent(ctx, &models.Content{Title: "Borrador", Slug: "borrador", UserID: 1}))
+
+	var contents []models.Content
+	require.NoError(t, store.FindPublishedContents(ctx, services.FeedFilter{}, 50, &contents))
+	require.Len(t, contents, 2)
+	assert.Equal(t, "nuevo", contents[0].Slug)
+	require.NoError(t, store.FindPublishedContents(ctx, services.FeedFilter{AuthorID: 1}, 50, &contents))
+	require.Len(t, contents, 1)
+	assert.Equal(t, "viejo", contents[0].Slug)
+
+	state, err := store.FeedState(ctx, services.FeedFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), state.Count)
+
+	// Un borrado baja el recuento y mueve LastModified para invalidar las cachés de los lectores
+	require.NoError(t, store.DeleteContent(ctx, &contents[0]))
+	after, err := store.FeedState(ctx, services.FeedFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), after.Count)
+	assert.True(t, after.LastModified.After(state.LastModified))
+}
+
+func TestMemoryMediaStore(t *testing.T) {
+	ctx := context.Background()
+	store := services.NewMemoryMediaStore()
+	first := models.Media{Filename: "a.png", SHA256: "aaa", StorageKey: "aaa"}
+	second := models.Media{Filename: "b.png", SHA256: "bbb", StorageKey: "bbb"}
+	require.NoError(t, store.CreateMedia(ctx, &first))
+	require.NoError(t, store.CreateMedia(ctx, &second))
+	duplicate := models.Media{Filename: "c.png", SHA256: "aaa"}
+	assert.True(t, utils.IsConflict(store.CreateMedia(ctx, &duplicate)))
+
+	var list []models.Media
+	require.NoError(t, store.ListMedia(ctx, 10, 0, &list))
+	require.Len(t, list, 2)
+	assert.Equal(t, second.ID, list[0].ID)
+	require.NoError(t, store.ListMedia(ctx, 10, 1, &list))
+	assert.Equal(t, []models.Media{first}, list)
+
+	var found models.Media
+	require.NoError(t, store.FindMediaByHash(ctx, "bbb", &found))
+	assert.Equal(t, second.ID, found.ID)
+	require.NoError(t, store.DeleteMedia(ctx, &found))
+	assert.True(t, utils.IsNotFound(store.GetMedia(ctx, second.ID, &found)))
+}
+
+func TestMemoryTranslationStore(t *testing.T) {
+	ctx := context.Background()
+	store := services.NewMemoryTranslationStore()
+	require.NoError(t, store.SaveTranslation(ctx, &models.ContentTranslation{ContentID: 1, Locale: "fr", Title: "Bonjour"}))
+	require.NoError(t, store.SaveTranslation(ctx, &models.ContentTranslation{ContentID: 1, Locale: "en", Title: "Hello"}))
+
+	var list []models.ContentTranslation
+	require.NoError(t, store.ListTranslations(ctx, 1, &list))
+	require.Len(t, list, 2)
+	assert.Equal(t, "en", list[0].Locale)
+
+	require.NoError(t, store.DeleteTranslation(ctx, 1, "en"))
+	var translation models.ContentTranslation
+	assert.ErrorIs(t, store.GetTranslation(ctx, 1, "en", &translation), mongo.ErrNoDocuments)
+	require.NoError(t, store.DeleteTranslations(ctx, 1))
+	require.NoError(t, store.ListTranslations(ctx, 1, &list))
+	assert.Empty(t, list)
+}
+
+func TestMemoryCacheDeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	cache := services.NewMemoryCache()
+	require.NoError(t, cache.Set(ctx, "media:1:w200", "v", 0))
+	require.NoError(t, cache.Set(ctx, "media:1:w400", "v", 0))
+	require.NoError(t, cache.Set(ctx, "media:10:w200", "v", 0))
+
+	deleted, err := cache.DeletePrefix(ctx, "media:1:")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	_, err = cache.Get(ctx, "media:10:w200")
+	assert.NoError(t, err)
+}
+
+// Las rutas que memoria no implementa responden 501 con un problem+json en vez de desaparecer
+func TestNotImplementedProblemCode(t *testing.T) {
+	app := fiber.New()
+	app.Get("/webhooks", func(c *fiber.Ctx) error {
+		return utils.Problem(c, fiber.StatusNotImplemented, "Not available with storage=memory")
+	})
+	status, body := memoryRequest(t, app, "GET", "/webhooks", nil)
+	assert.Equal(t, fiber.StatusNotImplemented, status)
+	assert.Equal(t, "not_implemented", body["code"])
+}
@@ -0,0 +1,174 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_add_flags.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN f BOOLEAN;")},
+		"sql/0001_create_t.up.sql":    {Data: []byte("CREATE TABLE t (id INT);")},
+		"sql/0001_create_t.down.sql":  {Data: []byte("DROP TABLE t;")},
+		"sql/README.md":               {Data: []byte("ignored")},
+		"sql/0003_broken.down.sql":    {Data: []byte("SELECT 1;")},
+		"other/0009_elsewhere.up.sql": {Data: []byte("SELECT 1;")},
+	}
+	_, err := migrations.Load(fsys, "sql")
+	assert.ErrorContains(t, err, "3_broken has no up script")
+
+	delete(fsys, "sql/0003_broken.down.sql")
+	list, err := migrations.Load(fsys, "sql")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, int64(1), list[0].Version)
+	assert.Equal(t, "create_t", list[0].Name)
+	assert.Equal(t, "DROP TABLE t;", list[0].Down)
+	assert.Equal(t, "", list[1].Down)
+
+	fsys["sql/0002_renamed.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = migrations.Load(fsys, "sql")
+	assert.ErrorContains(t, err, "two names")
+}
+
+// Un script mal nombrado en migrations/ hace fallar estos tests en lugar del binario
+func TestEmbeddedPostgresMigrations(t *testing.T) {
+	list, err := migrations.Postgres()
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+	assert.Equal(t, "initial_schema", list[0].Name)
+	for i, m := range list {
+		assert.NotEmpty(t, m.Down, "migration %d should be reversible", m.Version)
+		if i > 0 {
+			assert.Greater(t, m.Version, list[i-1].Version)
+		}
+	}
+}
+
+func TestEmbeddedSQLiteMigrations(t *testing.T) {
+	postgres, err := migrations.Postgres()
+	require.NoError(t, err)
+	sqlite, err := migrations.SQLite()
+	require.NoError(t, err)
+	require.Len(t, sqlite, len(postgres))
+	for i, m := range sqlite {
+		assert.Equal(t, postgres[i].Version, m.Version)
+		assert.Equal(t, postgres[i].Name, m.Name)
+		assert.NotEmpty(t, m.Down, "migration %d should be reversible", m.Version)
+	}
+}
+
+func TestSQLiteMigrator(t *testing.T) {
+	db, err := database.SQLiteOpen(filepath.Join(t.TempDir(), "cms.db"))
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	ctx := context.Background()
+
+	migrator, err := migrations.NewMigrator(migrations.DialectSQLite, sqlDB)
+	require.NoError(t, err)
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(migrator.Migrations))
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.AppliedAt.IsZero())
+	}
+	var tables int
+	require.NoError(t, sqlDB.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name IN ('contents', 'content_tags', 'content_bodies')`).Scan(&tables))
+	assert.Equal(t, 3, tables)
+
+	modified, err := migrations.NewSQLiteMigrator(sqlDB)
+	require.NoError(t, err)
+	modified.Migrations[0].Up += "\n-- cambio"
+	_, err = modified.Up(ctx)
+	assert.ErrorContains(t, err, "modified after being applied")
+
+	reverted, err := migrator.Down(ctx, len(migrator.Migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(migrator.Migrations))
+	require.NoError(t, sqlDB.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'contents'`).Scan(&tables))
+	assert.Equal(t, 0, tables)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	_, err = migrations.NewMigrator("mysql", sqlDB)
+	assert.Error(t, err)
+}
+
+func TestPostgresMigrator(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	ctx := context.Background()
+
+	migrator, err := migrations.NewPostgresMigrator(sqlDB)
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx, len(migrator.Migrations))
+	require.NoError(t, err)
+
+	// Dos instancias a la vez: el lock hace que solo una aplique cada migración
+	var wg sync.WaitGroup
+	applied := make([][]migrations.Migration, 2)
+	for i := range applied {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			migrator, err := migrations.NewPostgresMigrator(sqlDB)
+			if assert.NoError(t, err) {
+				applied[i], err = migrator.Up(ctx)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, len(migrator.Migrations), len(applied[0])+len(applied[1]))
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.Modified)
+	}
+	var tables int
+	require.NoError(t, sqlDB.QueryRow(`SELECT count(*) FROM information_schema.tables WHERE table_name IN ('contents', 'content_tags', 'webhook_deliveries', 'search_documents')`).Scan(&tables))
+	assert.Equal(t, 4, tables)
+
+	// Un script modificado después de aplicarse se detecta
+	modified, err := migrations.NewPostgresMigrator(sqlDB)
+	require.NoError(t, err)
+	modified.Migrations[0].Up += "\n-- cambio"
+	_, err = modified.Up(ctx)
+	assert.ErrorContains(t, err, "modified after being applied")
+
+	reverted, err := migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Mock para OutboxRepository
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) CreateContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error {
+	return m.Called(ctx, content, event).Error(0)
+}
+
+func (m *MockOutboxRepository) SaveContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error {
+	return m.Called(ctx, content, event).Error(0)
+}
+
+func (m *MockOutboxRepository) DeleteContent(ctx context.Context, content *models.Content, event *models.OutboxEvent) error {
+	return m.Called(ctx, content, event).Error(0)
+}
+
+func (m *MockOutboxRepository) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	args := m.Called(ctx, limit, lease)
+	events, _ := args.Get(0).([]models.OutboxEvent)
+	return events, args.Error(1)
+}
+
+func (m *MockOutboxRepository) ClaimEvent(ctx context.Context, id uint, lease time.Duration) (bool, error) {
+	args := m.Called(ctx, id, lease)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOutboxRepository) CompleteEvent(ctx context.Context, id uint) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockOutboxRepository) FailEvent(ctx context.Context, event *models.OutboxEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *MockOutboxRepository) ListEvents(ctx context.Context, status string, limit, offset int, events *[]models.OutboxEvent) error {
+	return m.Called(ctx, status, limit, offset, events).Error(0)
+}
+
+func (m *MockOutboxRepository) RetryEvent(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestCreateContentThroughOutbox(t *testing.T) {
+	pgMock := new(MockPGRepository)
+	mongoMock := new(MockMongoRepository)
+	outbox := new(MockOutboxRepository)
+	handler := services.NewContentHandler(pgMock, mongoMock, missCache())
+	handler.Outbox = services.NewOutboxRelay(outbox, mongoMock)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", "1")
+		return c.Next()
+	})
+	app.Post("/content", handler.CreateContent)
+
+	// La fila y el evento se guardan juntos; Mongo falla al aplicarlo enseguida
+	outbox.On("CreateContent", mock.Anything, mock.AnythingOfType("*models.Content"), mock.AnythingOfType("*models.OutboxEvent")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Content).ID = 5
+		event := args.Get(2).(*models.OutboxEvent)
+		event.ID, event.ContentID = 9, 5
+	}).Return(nil)
+	outbox.On("ClaimEvent", mock.Anything, uint(9), time.Minute).Return(true, nil)
+	mongoMock.On("UpsertContentBody", mock.Anything, mock.AnythingOfType("*models.ContentBody")).Return(errors.New("mongo down")).Once()
+	var failed *models.OutboxEvent
+	outbox.On("FailEvent", mock.Anything, mock.AnythingOfType("*models.OutboxEvent")).Run(func(args mock.Arguments) {
+		failed = args.Get(1).(*models.OutboxEvent)
+	}).Return(nil)
+
+	req := httptest.NewRequest("POST", "/content", jsonBody(map[string]string{"title": "Hola", "body": "# Hola", "format": "markdown"}))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	require.NotNil(t, failed)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, "mongo down", failed.LastError)
+	assert.True(t, failed.NextAttemptAt.After(time.Now()))
+	pgMock.AssertNotCalled(t, "CreateContent", mock.Anything, mock.Anything)
+
+	// El relay lo reintenta y esta vez el cuerpo se escribe renderizado
+	outbox.On("ClaimEvents", mock.Anything, 50, time.Minute).Return([]models.OutboxEvent{*failed}, nil).Once()
+	mongoMock.On("UpsertContentBody", mock.Anything, mock.AnythingOfType("*models.ContentBody")).Run(func(args mock.Arguments) {
+		body := args.Get(1).(*models.ContentBody)
+		assert.Equal(t, uint(5), body.ContentID)
+		assert.Contains(t, body.Rendered.HTML, "<h1")
+	}).Return(nil).Once()
+	outbox.On("CompleteEvent", mock.Anything, uint(9)).Return(nil).Once()
+
+	n, err := handler.Outbox.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	outbox.AssertExpectations(t)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestOutboxRelayDeadLetter(t *testing.T) {
+	mongoMock := new(MockMongoRepository)
+	outbox := new(MockOutboxRepository)
+	relay := services.NewOutboxRelay(outbox, mongoMock)
+	relay.MaxAttempts = 3
+
+	assert.Equal(t, time.Second, relay.Backoff(1))
+	assert.Equal(t, 8*time.Second, relay.Backoff(4))
+	assert.Equal(t, 15*time.Minute, relay.Backoff(40))
+
+	event := models.OutboxEvent{ID: 3, ContentID: 7, Operation: services.OutboxDeleteBody, Status: models.OutboxPending, Attempts: 2}
+	mongoMock.On("DeleteContentBody", mock.Anything, bson.M{"content_id": 7}).Return(errors.New("timeout"))
+	outbox.On("FailEvent", mock.Anything, mock.MatchedBy(func(e *models.OutboxEvent) bool {
+		return e.Status == models.OutboxDead && e.Attempts == 3
+	})).Return(nil).Once()
+
+	assert.Error(t, relay.Process(context.Background(), event))
+	outbox.AssertExpectations(t)
+
+	// Desde la vista de dead letter se puede reencolar
+	app := fiber.New()
+	app.Post("/outbox/:id<int>/retry", services.NewOutboxHandler(relay).RetryEvent)
+	outbox.On("RetryEvent", mock.Anything, uint(3)).Return(true, nil).Once()
+	outbox.On("RetryEvent", mock.Anything, uint(4)).Return(false, nil).Once()
+	outbox.On("RetryEvent", mock.Anything, uint(5)).Return(false, services.ErrOutboxEventSuperseded).Once()
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/outbox/3/retry", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("POST", "/outbox/4/retry", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("POST", "/outbox/5/retry", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
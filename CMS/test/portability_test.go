@@ -0,0 +1,81 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+func TestExportContents(t *testing.T) {
+	pgMock, mongoMock := new(MockPGRepository), new(MockMongoRepository)
+	published := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	pgMock.On("FindContents", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]models.Content) = []models.Content{
+			{Model: gorm.Model{ID: 1}, Title: "Hola", Slug: "hola", UserID: 2, PublishedAt: &published},
+			{Model: gorm.Model{ID: 2}, Title: "Sin cuerpo", Slug: "sin-cuerpo"},
+		}
+	}).Return(nil)
+	mongoMock.On("GetContentBody", mock.Anything, bson.M{"content_id": 1}, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.ContentBody) = models.ContentBody{ContentID: 1, Body: "# Hola", Format: services.FormatMarkdown}
+	}).Return(nil)
+	mongoMock.On("GetContentBody", mock.Anything, bson.M{"content_id": 2}, mock.Anything).Return(nil)
+	h := services.NewContentHandler(pgMock, mongoMock, nil)
+
+	var out bytes.Buffer
+	n, err := h.ExportContents(context.Background(), &out)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	var record services.ContentRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "hola", record.Slug)
+	assert.Equal(t, "# Hola", record.Body)
+	assert.Equal(t, services.FormatMarkdown, record.Format)
+	assert.True(t, published.Equal(*record.PublishedAt))
+}
+
+func TestImportContentsSkipsExistingSlugs(t *testing.T) {
+	pgMock, mongoMock, slugMock := new(MockPGRepository), new(MockMongoRepository), new(MockSlugRepository)
+	slugMock.On("FindContentBySlug", mock.Anything, "existente", mock.Anything).Return(nil)
+	slugMock.On("FindContentBySlug", mock.Anything, "nuevo", mock.Anything).Return(gorm.ErrRecordNotFound)
+	slugMock.On("SlugTaken", mock.Anything, "nuevo", uint(0)).Return(false, nil)
+
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	pgMock.On("CreateContent", mock.Anything, mock.MatchedBy(func(c *models.Content) bool {
+		return c.Slug == "nuevo" && c.UserID == 7 && c.CreatedAt.Equal(created)
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Content).ID = 10
+	}).Return(nil).Once()
+	mongoMock.On("InsertContentBody", mock.Anything, mock.MatchedBy(func(b *models.ContentBody) bool {
+		return b.ContentID == 10 && b.Body == "Texto" && b.Format == services.FormatPlain && b.Rendered != nil
+	})).Return(nil).Once()
+
+	h := services.NewContentHandler(pgMock, mongoMock, nil)
+	h.Slugs = slugMock
+	input := `{"title":"Existente","slug":"existente","body":"x"}
+
+{"title":"Nuevo","slug":"nuevo","body":"Texto","user_id":7,"created_at":"2023-01-02T03:04:05Z"}
+`
+	result, err := h.ImportContents(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, services.ImportResult{Created: 1, Skipped: 1}, result)
+	pgMock.AssertExpectations(t)
+	mongoMock.AssertExpectations(t)
+
+	_, err = h.ImportContents(context.Background(), strings.NewReader(`{"title":"Malo","body":"x","format":"rtf"}`))
+	assert.ErrorContains(t, err, "line 1: invalid body format")
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"JSanches/CMD/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	body := "# Introducción\n\nTexto con **negrita**.\n\n## Detalles\n\n<script>alert(1)</script>\n\n[link](javascript:alert(1))"
+	rendered, err := services.RenderBody(services.FormatMarkdown, body)
+	require.NoError(t, err)
+
+	assert.Contains(t, rendered.HTML, "<strong>negrita</strong>")
+	assert.NotContains(t, rendered.HTML, "<script>")
+	assert.NotContains(t, rendered.HTML, "javascript:")
+	require.Len(t, rendered.TOC, 2)
+	assert.Equal(t, 1, rendered.TOC[0].Level)
+	assert.Equal(t, "Introducción", rendered.TOC[0].Text)
+	assert.Contains(t, rendered.HTML, `id="`+rendered.TOC[1].ID+`"`)
+	assert.Equal(t, 1, rendered.ReadingTime)
+}
+
+func TestRenderHTMLAddsAnchors(t *testing.T) {
+	body := `<h2>Uno</h2><p onclick="x()">a</p><h2>Uno</h2><img src="x.png" onerror="alert(1)">`
+	rendered, err := services.RenderBody(services.FormatHTML, body)
+	require.NoError(t, err)
+
+	require.Len(t, rendered.TOC, 2)
+	assert.Equal(t, "uno", rendered.TOC[0].ID)
+	assert.Equal(t, "uno-1", rendered.TOC[1].ID)
+	assert.NotContains(t, rendered.HTML, "onclick")
+	assert.NotContains(t, rendered.HTML, "onerror")
+}
+
+func TestRenderPlainAndWordCount(t *testing.T) {
+	body := strings.Repeat("palabra ", 450) + "\n\n<b>no es html</b>"
+	rendered, err := services.RenderBody(services.FormatPlain, body)
+	require.NoError(t, err)
+
+	assert.Contains(t, rendered.HTML, "&lt;b&gt;")
+	assert.Equal(t, 453, rendered.WordCount)
+	assert.Equal(t, 3, rendered.ReadingTime)
+	assert.Equal(t, services.BodySourceHash(services.FormatPlain, body), rendered.SourceHash)
+	assert.NotEqual(t, rendered.SourceHash, services.BodySourceHash(services.FormatMarkdown, body))
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/migrations"
+	"JSanches/CMD/services"
+	"JSanches/CMD/test/repotest"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+// Cada backend pasa las mismas pruebas de conformidad. Los que necesitan un
+// servidor se saltan si no se indica uno con TEST_POSTGRES_URL, TEST_MONGO_URI
+// o TEST_REDIS_ADDR; las bases que usan deben ser de pruebas, porque se vacían.
+
+// usePostgres deja Postgres con las tablas vacías como base de los clientes de GORM
+func usePostgres(t *testing.T) *gorm.DB {
+	db := testPostgres(t)
+	require.NoError(t, db.Exec(`TRUNCATE contents, content_tags, content_bodies, users RESTART IDENTITY CASCADE`).Error)
+	previous := database.DBConn
+	database.DBConn = db
+	t.Cleanup(func() { database.DBConn = previous })
+	return db
+}
+
+func TestContentRepoConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunContentRepoSuite(t, func(t *testing.T) services.PostgresRepository {
+			return services.NewMemoryContentStore()
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.RunContentRepoSuite(t, func(t *testing.T) services.PostgresRepository {
+			useSQLite(t)
+			return &services.PGClient{}
+		})
+	})
+	t.Run("postgres", func(t *testing.T) {
+		repotest.RunContentRepoSuite(t, func(t *testing.T) services.PostgresRepository {
+			usePostgres(t)
+			return &services.PGClient{}
+		})
+	})
+}
+
+func TestBodyRepoConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunBodyRepoSuite(t, func(t *testing.T) services.MongoRepository {
+			return services.NewMemoryBodyStore()
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.RunBodyRepoSuite(t, func(t *testing.T) services.MongoRepository {
+			return services.NewSQLBodyStore(testSQLite(t))
+		})
+	})
+	t.Run("postgres", func(t *testing.T) {
+		repotest.RunBodyRepoSuite(t, func(t *testing.T) services.MongoRepository {
+			return services.NewSQLBodyStore(usePostgres(t))
+		})
+	})
+	t.Run("mongo", func(t *testing.T) {
+		repotest.RunBodyRepoSuite(t, func(t *testing.T) services.MongoRepository {
+			uri := os.Getenv("TEST_MONGO_URI")
+			if uri == "" {
+				t.Skip("TEST_MONGO_URI not set")
+			}
+			database.MongoInit(uri)
+			ctx := context.Background()
+			_, err := database.MongoGetCollection("contents", "contents").DeleteMany(ctx, bson.M{})
+			require.NoError(t, err)
+			require.NoError(t, migrations.EnsureMongoIndexes(ctx, database.MongoGetClient()))
+			return &services.MongoClient{}
+		})
+	})
+}
+
+func TestCacheRepoConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunCacheRepoSuite(t, func(t *testing.T) services.CacheRepository {
+			return services.NewMemoryCache()
+		})
+	})
+	t.Run("redis", func(t *testing.T) {
+		repotest.RunCacheRepoSuite(t, func(t *testing.T) services.CacheRepository {
+			addr := os.Getenv("TEST_REDIS_ADDR")
+			if addr == "" {
+				t.Skip("TEST_REDIS_ADDR not set")
+			}
+			database.RedisInit(addr, "", 0)
+			cache := &services.RedisClient{}
+			_, err := cache.DeletePrefix(context.Background(), repotest.CacheKeyPrefix)
+			require.NoError(t, err)
+			return cache
+		})
+	})
+}
+
+func TestUserRepoConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunUserRepoSuite(t, func(t *testing.T) services.UserRepository {
+			return services.NewMemoryUserStore()
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.RunUserRepoSuite(t, func(t *testing.T) services.UserRepository {
+			return &services.PostgresUserRepository{DB: testSQLite(t)}
+		})
+	})
+	t.Run("postgres", func(t *testing.T) {
+		repotest.RunUserRepoSuite(t, func(t *testing.T) services.UserRepository {
+			return &services.PostgresUserRepository{DB: usePostgres(t)}
+		})
+	})
+}
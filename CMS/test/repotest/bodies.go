@@ -0,0 +1,138 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RunBodyRepoSuite comprueba un MongoRepository con los filtros de igualdad y
+// las actualizaciones $set que usa el resto del código. newRepo se llama en
+// cada subtest y debe devolver un almacén sin cuerpos.
+func RunBodyRepoSuite(t *testing.T, newRepo func(t *testing.T) services.MongoRepository) {
+	t.Run("InsertAndGet", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		rendered, err := services.RenderBody(services.FormatMarkdown, "# Hola")
+		require.NoError(t, err)
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "# Hola", Format: services.FormatMarkdown, Rendered: rendered}))
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 2, Body: "dos"}))
+
+		var body models.ContentBody
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+		assert.Equal(t, uint(1), body.ContentID)
+		assert.Equal(t, "# Hola", body.Body)
+		assert.Equal(t, services.FormatMarkdown, body.Format)
+		require.NotNil(t, body.Rendered)
+		assert.Equal(t, rendered.HTML, body.Rendered.HTML)
+
+		body = models.ContentBody{}
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 2, "body": "dos"}, &body))
+		assert.Equal(t, uint(2), body.ContentID)
+		assert.Nil(t, body.Rendered)
+
+		// content_id es único, como el índice de Mongo
+		assert.True(t, utils.IsConflict(repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "otra"})))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "uno"}))
+
+		var body models.ContentBody
+		assert.ErrorIs(t, repo.GetContentBody(ctx, bson.M{"content_id": 2}, &body), mongo.ErrNoDocuments)
+		assert.ErrorIs(t, repo.GetContentBody(ctx, bson.M{"content_id": 1, "body": "otro"}, &body), mongo.ErrNoDocuments)
+		// Actualizar o borrar lo que no existe no es un error, como UpdateMany y DeleteOne
+		assert.NoError(t, repo.UpdateContentBody(ctx, bson.M{"content_id": 2}, bson.M{"$set": bson.M{"body": "x"}}))
+		assert.NoError(t, repo.DeleteContentBody(ctx, bson.M{"content_id": 2}))
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+		assert.Equal(t, "uno", body.Body)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "uno", Format: services.FormatMarkdown}))
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 2, Body: "dos"}))
+
+		// $set solo cambia los campos indicados de los documentos que cumplen el filtro
+		require.NoError(t, repo.UpdateContentBody(ctx, bson.M{"content_id": 1}, bson.M{"$set": bson.M{"body": "nuevo"}}))
+		var body models.ContentBody
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+		assert.Equal(t, "nuevo", body.Body)
+		assert.Equal(t, services.FormatMarkdown, body.Format)
+		body = models.ContentBody{}
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 2}, &body))
+		assert.Equal(t, "dos", body.Body)
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		rendered, err := services.RenderBody(services.FormatPlain, "uno")
+		require.NoError(t, err)
+		require.NoError(t, repo.UpsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "uno", Format: services.FormatPlain, Rendered: rendered}))
+
+		// Sobre un cuerpo existente conserva los campos vacíos que no trae
+		require.NoError(t, repo.UpsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "otra vez"}))
+		var body models.ContentBody
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+		assert.Equal(t, "otra vez", body.Body)
+		assert.Equal(t, services.FormatPlain, body.Format)
+		require.NotNil(t, body.Rendered)
+		assert.Equal(t, rendered.HTML, body.Rendered.HTML)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "uno"}))
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 2, Body: "dos"}))
+
+		require.NoError(t, repo.DeleteContentBody(ctx, bson.M{"content_id": 1}))
+		var body models.ContentBody
+		assert.ErrorIs(t, repo.GetContentBody(ctx, bson.M{"content_id": 1}, &body), mongo.ErrNoDocuments)
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 2}, &body))
+
+		// Un cuerpo borrado se puede volver a crear
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "de nuevo"}))
+	})
+
+	t.Run("ConcurrentUpserts", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		errs := parallel(concurrency, func(i int) error {
+			return repo.UpsertContentBody(ctx, &models.ContentBody{ContentID: uint(i + 1), Body: fmt.Sprintf("b%d", i)})
+		})
+		assert.Equal(t, concurrency, successes(errs), "%v", errs)
+		for i := 0; i < concurrency; i++ {
+			var body models.ContentBody
+			require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": i + 1}, &body))
+			assert.Equal(t, fmt.Sprintf("b%d", i), body.Body)
+		}
+	})
+
+	t.Run("ConcurrentUpdates", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		require.NoError(t, repo.InsertContentBody(ctx, &models.ContentBody{ContentID: 1, Body: "viejo", Format: services.FormatPlain}))
+
+		// Unos cambian body y otros format: si alguno reescribiera el documento
+		// entero con lo que leyó antes, se perdería uno de los dos cambios
+		errs := parallel(concurrency, func(i int) error {
+			set := bson.M{"body": "nuevo"}
+			if i%2 == 1 {
+				set = bson.M{"format": services.FormatMarkdown}
+			}
+			return repo.UpdateContentBody(ctx, bson.M{"content_id": 1}, bson.M{"$set": set})
+		})
+		assert.Equal(t, concurrency, successes(errs), "%v", errs)
+		var body models.ContentBody
+		require.NoError(t, repo.GetContentBody(ctx, bson.M{"content_id": 1}, &body))
+		assert.Equal(t, "nuevo", body.Body)
+		assert.Equal(t, services.FormatMarkdown, body.Format)
+	})
+}
@@ -0,0 +1,80 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"JSanches/CMD/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CacheKeyPrefix es el prefijo de todas las claves que escribe RunCacheRepoSuite,
+// para que un backend compartido pueda limpiarlas
+const CacheKeyPrefix = "repotest:"
+
+// RunCacheRepoSuite comprueba un CacheRepository. newCache se llama en cada
+// subtest y debe devolver una caché sin claves con CacheKeyPrefix.
+func RunCacheRepoSuite(t *testing.T, newCache func(t *testing.T) services.CacheRepository) {
+	key := func(name string) string { return CacheKeyPrefix + name }
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		cache, ctx := newCache(t), context.Background()
+		require.NoError(t, cache.Set(ctx, key("a"), "uno", time.Minute))
+		value, err := cache.Get(ctx, key("a"))
+		require.NoError(t, err)
+		assert.Equal(t, "uno", value)
+
+		require.NoError(t, cache.Set(ctx, key("a"), "dos", time.Minute))
+		value, err = cache.Get(ctx, key("a"))
+		require.NoError(t, err)
+		assert.Equal(t, "dos", value)
+
+		// Sin expiración la clave no caduca
+		require.NoError(t, cache.Set(ctx, key("b"), "", 0))
+		value, err = cache.Get(ctx, key("b"))
+		require.NoError(t, err)
+		assert.Equal(t, "", value)
+	})
+
+	t.Run("Miss", func(t *testing.T) {
+		cache, ctx := newCache(t), context.Background()
+		_, err := cache.Get(ctx, key("missing"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		cache, ctx := newCache(t), context.Background()
+		require.NoError(t, cache.Set(ctx, key("short"), "uno", 100*time.Millisecond))
+		require.NoError(t, cache.Set(ctx, key("long"), "dos", time.Minute))
+		time.Sleep(250 * time.Millisecond)
+
+		_, err := cache.Get(ctx, key("short"))
+		assert.Error(t, err)
+		value, err := cache.Get(ctx, key("long"))
+		require.NoError(t, err)
+		assert.Equal(t, "dos", value)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		cache, ctx := newCache(t), context.Background()
+		errs := parallel(concurrency, func(i int) error {
+			if err := cache.Set(ctx, key(fmt.Sprint(i)), fmt.Sprint(i), time.Minute); err != nil {
+				return err
+			}
+			return cache.Set(ctx, key("shared"), fmt.Sprint(i), time.Minute)
+		})
+		assert.Equal(t, concurrency, successes(errs), "%v", errs)
+		for i := 0; i < concurrency; i++ {
+			value, err := cache.Get(ctx, key(fmt.Sprint(i)))
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprint(i), value)
+		}
+		value, err := cache.Get(ctx, key("shared"))
+		require.NoError(t, err)
+		assert.Regexp(t, `^\d+$`, value)
+	})
+}
@@ -0,0 +1,198 @@
+// Package repotest reúne las pruebas de conformidad de los repositorios. Cada
+// backend (memoria, SQLite, Postgres, Mongo, Redis) las ejecuta con una fábrica
+// que devuelve un almacén vacío, para que todos cumplan el mismo contrato que
+// esperan los handlers.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// concurrency es el número de goroutines de las pruebas concurrentes
+const concurrency = 16
+
+// RunContentRepoSuite comprueba un PostgresRepository. newRepo se llama en cada
+// subtest y debe devolver un repositorio sin contenidos.
+func RunContentRepoSuite(t *testing.T, newRepo func(t *testing.T) services.PostgresRepository) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		published := time.Now().Add(-time.Hour).Truncate(time.Second)
+		content := models.Content{Title: "Hola", Slug: "hola", Description: "Primero", Locale: "es", PublishedAt: &published, UserID: 7}
+		require.NoError(t, repo.CreateContent(ctx, &content))
+		assert.NotZero(t, content.ID)
+		assert.False(t, content.CreatedAt.IsZero())
+		assert.False(t, content.UpdatedAt.IsZero())
+
+		var found models.Content
+		require.NoError(t, repo.GetContent(ctx, strconv.Itoa(int(content.ID)), &found))
+		assert.Equal(t, content.ID, found.ID)
+		assert.Equal(t, "Hola", found.Title)
+		assert.Equal(t, "hola", found.Slug)
+		assert.Equal(t, "Primero", found.Description)
+		assert.Equal(t, "es", found.Locale)
+		assert.Equal(t, 7, found.UserID)
+		require.NotNil(t, found.PublishedAt)
+		assert.True(t, published.Equal(*found.PublishedAt), "published_at %s != %s", found.PublishedAt, published)
+		assert.Nil(t, found.CategoryID)
+
+		other := models.Content{Title: "Adiós"}
+		require.NoError(t, repo.CreateContent(ctx, &other))
+		assert.NotEqual(t, content.ID, other.ID)
+		assert.ElementsMatch(t, []string{"Hola", "Adiós"}, titles(t, repo))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		var found models.Content
+		assert.ErrorIs(t, repo.GetContent(ctx, "999", &found), gorm.ErrRecordNotFound)
+		assert.Empty(t, titles(t, repo))
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		content := models.Content{Title: "Hola", Slug: "hola"}
+		require.NoError(t, repo.CreateContent(ctx, &content))
+		created := content.UpdatedAt
+
+		content.Title = "Hola de nuevo"
+		content.Slug = "hola-de-nuevo"
+		require.NoError(t, repo.SaveContent(ctx, &content))
+		var found models.Content
+		require.NoError(t, repo.GetContent(ctx, strconv.Itoa(int(content.ID)), &found))
+		assert.Equal(t, "Hola de nuevo", found.Title)
+		assert.Equal(t, "hola-de-nuevo", found.Slug)
+		assert.False(t, found.UpdatedAt.Before(created))
+		assert.Len(t, titles(t, repo), 1)
+	})
+
+	t.Run("UniqueSlug", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		require.NoError(t, repo.CreateContent(ctx, &models.Content{Title: "Uno", Slug: "hola"}))
+		assert.True(t, utils.IsConflict(repo.CreateContent(ctx, &models.Content{Title: "Dos", Slug: "hola"})))
+
+		// Un slug vacío no reserva nada
+		require.NoError(t, repo.CreateContent(ctx, &models.Content{Title: "Tres"}))
+		require.NoError(t, repo.CreateContent(ctx, &models.Content{Title: "Cuatro"}))
+
+		taken := models.Content{Title: "Cinco", Slug: "cinco"}
+		require.NoError(t, repo.CreateContent(ctx, &taken))
+		taken.Slug = "hola"
+		assert.True(t, utils.IsConflict(repo.SaveContent(ctx, &taken)))
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		content := models.Content{Title: "Hola", Slug: "hola"}
+		require.NoError(t, repo.CreateContent(ctx, &content))
+		require.NoError(t, repo.CreateContent(ctx, &models.Content{Title: "Otro"}))
+
+		require.NoError(t, repo.DeleteContent(ctx, &content))
+		assert.True(t, content.DeletedAt.Valid)
+		var found models.Content
+		assert.ErrorIs(t, repo.GetContent(ctx, strconv.Itoa(int(content.ID)), &found), gorm.ErrRecordNotFound)
+		assert.Equal(t, []string{"Otro"}, titles(t, repo))
+
+		// Borrar otra vez no falla y el slug sigue ocupado por la fila borrada
+		require.NoError(t, repo.DeleteContent(ctx, &content))
+		assert.True(t, utils.IsConflict(repo.CreateContent(ctx, &models.Content{Title: "Nuevo", Slug: "hola"})))
+	})
+
+	t.Run("ConcurrentCreates", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		ids := make([]uint, concurrency)
+		errs := parallel(concurrency, func(i int) error {
+			content := models.Content{Title: fmt.Sprintf("t%d", i), Slug: fmt.Sprintf("s%d", i)}
+			err := repo.CreateContent(ctx, &content)
+			ids[i] = content.ID
+			return err
+		})
+		seen := map[uint]bool{}
+		for i, err := range errs {
+			require.NoError(t, err)
+			assert.False(t, seen[ids[i]], "duplicate id %d", ids[i])
+			seen[ids[i]] = true
+		}
+		assert.Len(t, titles(t, repo), concurrency)
+
+		// Con el mismo slug solo puede ganar uno
+		errs = parallel(concurrency, func(i int) error {
+			return repo.CreateContent(ctx, &models.Content{Title: fmt.Sprintf("d%d", i), Slug: "disputado"})
+		})
+		assert.Equal(t, 1, successes(errs))
+	})
+
+	t.Run("ConcurrentUpdates", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		content := models.Content{Title: "Hola", Slug: "hola"}
+		require.NoError(t, repo.CreateContent(ctx, &content))
+		id := strconv.Itoa(int(content.ID))
+
+		errs := parallel(concurrency, func(i int) error {
+			var found models.Content
+			if err := repo.GetContent(ctx, id, &found); err != nil {
+				return err
+			}
+			found.Title = fmt.Sprintf("t%d", i)
+			return repo.SaveContent(ctx, &found)
+		})
+		assert.Equal(t, concurrency, successes(errs), "%v", errs)
+
+		// Gana la última escritura, pero sin duplicar ni perder la fila
+		var found models.Content
+		require.NoError(t, repo.GetContent(ctx, id, &found))
+		assert.Regexp(t, `^t\d+$`, found.Title)
+		assert.Equal(t, "hola", found.Slug)
+		assert.Len(t, titles(t, repo), 1)
+	})
+}
+
+func titles(t *testing.T, repo services.PostgresRepository) []string {
+	var contents []models.Content
+	require.NoError(t, repo.FindContents(context.Background(), &contents))
+	titles := []string{}
+	for _, content := range contents {
+		titles = append(titles, content.Title)
+	}
+	return titles
+}
+
+// parallel ejecuta fn n veces a la vez y devuelve el error de cada una
+func parallel(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func successes(errs []error) int {
+	n := 0
+	for _, err := range errs {
+		if err == nil {
+			n++
+		}
+	}
+	return n
+}
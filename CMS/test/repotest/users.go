@@ -0,0 +1,99 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+	"JSanches/CMD/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// RunUserRepoSuite comprueba un UserRepository y, si lo implementa, el Save de
+// UserAdminRepository. newRepo se llama en cada subtest y debe devolver un
+// repositorio sin usuarios.
+func RunUserRepoSuite(t *testing.T, newRepo func(t *testing.T) services.UserRepository) {
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		user := models.User{Username: "ana", Email: "ana@example.com", Password: "hash"}
+		require.NoError(t, repo.Create(ctx, &user))
+		assert.NotZero(t, user.ID)
+		assert.Equal(t, services.RoleAuthor, user.Role)
+		require.NoError(t, repo.Create(ctx, &models.User{Username: "bea", Email: "bea@example.com", Role: services.RoleEditor}))
+
+		var found models.User
+		require.NoError(t, repo.FindByUsernameOrEmail(ctx, "ana", "", &found))
+		assert.Equal(t, user.ID, found.ID)
+		assert.Equal(t, "ana@example.com", found.Email)
+		assert.Equal(t, "hash", found.Password)
+		assert.Equal(t, services.RoleAuthor, found.Role)
+
+		found = models.User{}
+		require.NoError(t, repo.FindByUsernameOrEmail(ctx, "", "bea@example.com", &found))
+		assert.Equal(t, "bea", found.Username)
+		assert.Equal(t, services.RoleEditor, found.Role)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		require.NoError(t, repo.Create(ctx, &models.User{Username: "ana", Email: "ana@example.com"}))
+		var found models.User
+		assert.ErrorIs(t, repo.FindByUsernameOrEmail(ctx, "nadie", "nadie@example.com", &found), gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Unique", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		require.NoError(t, repo.Create(ctx, &models.User{Username: "ana", Email: "ana@example.com"}))
+		assert.True(t, utils.IsConflict(repo.Create(ctx, &models.User{Username: "ana", Email: "otra@example.com"})))
+		assert.True(t, utils.IsConflict(repo.Create(ctx, &models.User{Username: "otra", Email: "ana@example.com"})))
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		admin, ok := repo.(services.UserAdminRepository)
+		if !ok {
+			t.Skip("repository does not implement UserAdminRepository")
+		}
+		user := models.User{Username: "ana", Email: "ana@example.com"}
+		require.NoError(t, admin.Create(ctx, &user))
+		user.Role = services.RoleAdmin
+		user.IsBanned = true
+		user.FailedAttempts = 3
+		require.NoError(t, admin.Save(ctx, &user))
+
+		var found models.User
+		require.NoError(t, admin.FindByUsernameOrEmail(ctx, "ana", "", &found))
+		assert.Equal(t, user.ID, found.ID)
+		assert.Equal(t, services.RoleAdmin, found.Role)
+		assert.True(t, found.IsBanned)
+		assert.Equal(t, 3, found.FailedAttempts)
+	})
+
+	t.Run("ConcurrentCreates", func(t *testing.T) {
+		repo, ctx := newRepo(t), context.Background()
+		ids := make([]uint, concurrency)
+		errs := parallel(concurrency, func(i int) error {
+			user := models.User{Username: fmt.Sprintf("u%d", i), Email: fmt.Sprintf("u%d@example.com", i)}
+			err := repo.Create(ctx, &user)
+			ids[i] = user.ID
+			return err
+		})
+		seen := map[uint]bool{}
+		for i, err := range errs {
+			require.NoError(t, err)
+			assert.False(t, seen[ids[i]], "duplicate id %d", ids[i])
+			seen[ids[i]] = true
+		}
+
+		// Dos registros a la vez con el mismo nombre: solo uno se crea
+		errs = parallel(concurrency, func(i int) error {
+			return repo.Create(ctx, &models.User{Username: "disputado", Email: fmt.Sprintf("d%d@example.com", i)})
+		})
+		assert.Equal(t, 1, successes(errs))
+	})
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex(t *testing.T, path string) *services.MemoryIndex {
+	idx, err := services.NewMemoryIndex(path)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	docs := []services.SearchDocument{
+		{ContentID: 1, Title: "Guía de Go", Body: "Aprende programación concurrente con goroutines y canales.", UserID: 1, CreatedAt: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{ContentID: 2, Title: "Recetas", Body: "Una guía de cocina con recetas de programación de menús.", UserID: 2, CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ContentID: 3, Title: "Notas", Body: "Concurrente no es lo mismo que paralelo.", UserID: 1, CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, doc := range docs {
+		require.NoError(t, idx.Index(ctx, doc))
+	}
+	return idx
+}
+
+func TestMemoryIndexRanking(t *testing.T) {
+	idx := newTestIndex(t, "")
+
+	// El título pesa más que el cuerpo y se ignoran los acentos
+	hits, total, err := idx.Search(context.Background(), services.SearchQuery{Text: "guia"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, uint(1), hits[0].ContentID)
+	assert.Contains(t, hits[1].Snippet, "<mark>guía</mark>")
+}
+
+func TestMemoryIndexPhraseAndPrefix(t *testing.T) {
+	idx := newTestIndex(t, "")
+	ctx := context.Background()
+
+	hits, _, err := idx.Search(ctx, services.SearchQuery{Text: `"programación concurrente"`})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, uint(1), hits[0].ContentID)
+
+	hits, _, err = idx.Search(ctx, services.SearchQuery{Text: "concurr*"})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	hits, _, err = idx.Search(ctx, services.SearchQuery{Text: "concurr*", AuthorID: 1, From: ptrTime(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, uint(3), hits[0].ContentID)
+}
+
+func TestMemoryIndexDeleteAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.idx")
+	idx := newTestIndex(t, path)
+	require.NoError(t, idx.Delete(context.Background(), 1))
+
+	reopened, err := services.NewMemoryIndex(path)
+	require.NoError(t, err)
+	hits, _, err := reopened.Search(context.Background(), services.SearchQuery{Text: "guia"})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, uint(2), hits[0].ContentID)
+}
+
+func TestMemoryIndexBatchPersistsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.idx")
+	idx, err := services.NewMemoryIndex(path)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, idx.Batch(ctx, func() error {
+		require.NoError(t, idx.Reset(ctx))
+		for id := uint(1); id <= 50; id++ {
+			require.NoError(t, idx.Index(ctx, services.SearchDocument{ContentID: id, Title: "Documento", Body: "texto de prueba"}))
+		}
+		// Nada se escribe hasta que termina el lote
+		_, err := os.Stat(path)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		return nil
+	}))
+
+	reopened, err := services.NewMemoryIndex(path)
+	require.NoError(t, err)
+	_, total, err := reopened.Search(ctx, services.SearchQuery{Text: "documento"})
+	require.NoError(t, err)
+	assert.Equal(t, 50, total)
+}
+
+func TestSearchContentsHandler(t *testing.T) {
+	handler := services.NewContentHandler(new(MockPGRepository), new(MockMongoRepository), missCache())
+	handler.Search = newTestIndex(t, "")
+
+	app := fiber.New()
+	app.Get("/collection/search", handler.SearchContents)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/collection/search?q=canales", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Total   int                  `json:"total"`
+		Results []services.SearchHit `json:"results"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 1, body.Total)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/collection/search?q=", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+// searchBackends devuelve un índice vacío de cada backend; Postgres se salta sin
+// TEST_POSTGRES_URL
+var searchBackends = map[string]func(t *testing.T) services.SearchIndex{
+	"memory": func(t *testing.T) services.SearchIndex {
+		idx, err := services.NewMemoryIndex("")
+		require.NoError(t, err)
+		return idx
+	},
+	"postgres": func(t *testing.T) services.SearchIndex {
+		idx := services.NewPostgresSearchIndex(testPostgres(t), "")
+		require.NoError(t, idx.Reset(context.Background()))
+		return idx
+	},
+}
+
+func TestSearchSnippetEscapesBody(t *testing.T) {
+	for name, newIndex := range searchBackends {
+		t.Run(name, func(t *testing.T) {
+			idx := newIndex(t)
+			ctx := context.Background()
+			require.NoError(t, idx.Index(ctx, services.SearchDocument{
+				ContentID: 1, Title: "Aviso", Body: `Hola <script>alert("xss")</script> mundo`, CreatedAt: time.Now(),
+			}))
+
+			hits, _, err := idx.Search(ctx, services.SearchQuery{Text: "script", Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, hits, 1)
+			assert.NotContains(t, hits[0].Snippet, "<script>")
+			assert.Contains(t, hits[0].Snippet, "&lt;<mark>script</mark>&gt;")
+		})
+	}
+}
+
+func TestSearchIgnoresAccentsOnEveryBackend(t *testing.T) {
+	for name, newIndex := range searchBackends {
+		t.Run(name, func(t *testing.T) {
+			idx := newIndex(t)
+			ctx := context.Background()
+			require.NoError(t, idx.Index(ctx, services.SearchDocument{ContentID: 1, Title: "Canción de cuna", Body: "Una canción para dormir.", CreatedAt: time.Now()}))
+			require.NoError(t, idx.Index(ctx, services.SearchDocument{ContentID: 2, Title: "Notas", Body: "La educación pública.", CreatedAt: time.Now()}))
+
+			for query, want := range map[string]uint{"canción": 1, "cancion": 1, "EDUCACIÓN": 2, "educacion": 2, "educ*": 2} {
+				hits, total, err := idx.Search(ctx, services.SearchQuery{Text: query, Limit: 10})
+				require.NoError(t, err, query)
+				require.Equal(t, 1, total, query)
+				assert.Equal(t, want, hits[0].ContentID, query)
+			}
+
+			hits, _, err := idx.Search(ctx, services.SearchQuery{Text: "cancion", Limit: 10})
+			require.NoError(t, err)
+			assert.Contains(t, hits[0].Snippet, "<mark>canción</mark>")
+		})
+	}
+}
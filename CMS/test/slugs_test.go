@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Mock para SlugRepository
+type MockSlugRepository struct {
+	mock.Mock
+}
+
+func (m *MockSlugRepository) SlugTaken(ctx context.Context, slug string, exceptContentID uint) (bool, error) {
+	args := m.Called(ctx, slug, exceptContentID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSlugRepository) FindContentBySlug(ctx context.Context, slug string, content *models.Content) error {
+	return m.Called(ctx, slug, content).Error(0)
+}
+
+func (m *MockSlugRepository) FindRedirect(ctx context.Context, slug string, redirect *models.SlugRedirect) error {
+	args := m.Called(ctx, slug, redirect)
+	if r, ok := args.Get(0).(models.SlugRedirect); ok {
+		*redirect = r
+	}
+	return args.Error(1)
+}
+
+func (m *MockSlugRepository) RecordRedirect(ctx context.Context, oldSlug string, contentID uint) error {
+	return m.Called(ctx, oldSlug, contentID).Error(0)
+}
+
+func TestContentSlug(t *testing.T) {
+	assert.Equal(t, "strasse-y-cafe-noel", services.ContentSlug("Straße & Café Noël"))
+
+	long := services.ContentSlug(strings.Repeat("palabra ", 20))
+	assert.LessOrEqual(t, len(long), 80)
+	assert.False(t, strings.HasSuffix(long, "-"))
+
+	// Las letras de varios bytes no se cortan por la mitad
+	for _, title := range []string{strings.Repeat("漢字", 40), strings.Repeat("ж", 100), strings.Repeat("слово ", 20)} {
+		slug := services.ContentSlug(title)
+		assert.LessOrEqual(t, len(slug), 80, title)
+		assert.NotEmpty(t, slug, title)
+		assert.True(t, utf8.ValidString(slug), slug)
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	taken := map[string]bool{"hola": true, "hola-2": true}
+	slug, err := services.UniqueSlug("hola", func(s string) (bool, error) { return taken[s], nil })
+	require.NoError(t, err)
+	assert.Equal(t, "hola-3", slug)
+}
+
+func TestGetContentBySlugRedirectsOldSlug(t *testing.T) {
+	pgMock := new(MockPGRepository)
+	slugMock := new(MockSlugRepository)
+	handler := services.NewContentHandler(pgMock, new(MockMongoRepository), missCache())
+	handler.Slugs = slugMock
+
+	app := fiber.New()
+	app.Get("/collection/by-slug/:slug", handler.GetContentBySlug)
+
+	slugMock.On("FindContentBySlug", mock.Anything, "viejo", mock.Anything).Return(gorm.ErrRecordNotFound)
+	slugMock.On("FindRedirect", mock.Anything, "viejo", mock.Anything).Return(models.SlugRedirect{OldSlug: "viejo", ContentID: 7}, nil)
+	pgMock.On("GetContent", mock.Anything, "7", mock.AnythingOfType("*models.Content")).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Content).Slug = "nuevo"
+	}).Return(nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/collection/by-slug/viejo", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/collection/by-slug/nuevo", resp.Header.Get("Location"))
+
+	slugMock.On("FindContentBySlug", mock.Anything, "nada", mock.Anything).Return(gorm.ErrRecordNotFound)
+	slugMock.On("FindRedirect", mock.Anything, "nada", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	resp, err = app.Test(httptest.NewRequest("GET", "/collection/by-slug/nada", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"JSanches/CMD/database"
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// useSQLite deja un archivo de SQLite migrado como base de los clientes de GORM
+func useSQLite(t *testing.T) *gorm.DB {
+	db := testSQLite(t)
+	previous := database.DBConn
+	database.DBConn = db
+	t.Cleanup(func() { database.DBConn = previous })
+	return db
+}
+
+func TestSQLiteContentsAndUsers(t *testing.T) {
+	db := useSQLite(t)
+	ctx := context.Background()
+
+	repo := &services.PGClient{}
+	content := models.Content{Title: "Hola", Slug: "hola"}
+	require.NoError(t, repo.CreateContent(ctx, &content))
+	assert.Error(t, repo.CreateContent(ctx, &models.Content{Title: "Otra", Slug: "hola"}), "slug is unique")
+
+	var found models.Content
+	require.NoError(t, repo.GetContent(ctx, "1", &found))
+	assert.Equal(t, "Hola", found.Title)
+	found.Title = "Adiós"
+	require.NoError(t, repo.SaveContent(ctx, &found))
+	require.NoError(t, repo.DeleteContent(ctx, &found))
+	assert.ErrorIs(t, repo.GetContent(ctx, "1", &found), gorm.ErrRecordNotFound)
+
+	users := &services.PostgresUserRepository{DB: db}
+	require.NoError(t, users.Create(ctx, &models.User{Username: "ana", Email: "ana@example.com", Password: "x"}))
+	var user models.User
+	require.NoError(t, users.FindByUsernameOrEmail(ctx, "", "ana@example.com", &user))
+	assert.Equal(t, services.RoleAuthor, user.Role)
+	assert.True(t, user.IsActive)
+	user.IsBanned = true
+	require.NoError(t, users.Save(ctx, &user))
+	require.NoError(t, users.FindByUsernameOrEmail(ctx, "ana", "", &user))
+	assert.True(t, user.IsBanned)
+
+	slugs := &services.SlugClient{}
+	content = models.Content{Title: "Nuevo", Slug: "nuevo"}
+	require.NoError(t, repo.CreateContent(ctx, &content))
+	require.NoError(t, slugs.RecordRedirect(ctx, "viejo", content.ID))
+	require.NoError(t, slugs.RecordRedirect(ctx, "viejo", content.ID))
+	var redirect models.SlugRedirect
+	require.NoError(t, slugs.FindRedirect(ctx, "viejo", &redirect))
+	assert.Equal(t, content.ID, redirect.ContentID)
+}
+
+func TestSQLiteClaims(t *testing.T) {
+	useSQLite(t)
+	ctx := context.Background()
+
+	outbox := &services.OutboxClient{}
+	content := models.Content{Title: "Hola"}
+	require.NoError(t, outbox.CreateContent(ctx, &content, &models.OutboxEvent{Operation: services.OutboxUpsertBody}))
+	require.NoError(t, outbox.SaveContent(ctx, &content, &models.OutboxEvent{Operation: services.OutboxUpsertBody}))
+
+	// El segundo evento espera a que termine el primero del mismo contenido
+	events, err := outbox.ClaimEvents(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	events, err = outbox.ClaimEvents(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	require.NoError(t, outbox.CompleteEvent(ctx, 1))
+	claimed, err := outbox.ClaimEvent(ctx, 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	webhooks := &services.WebhookClient{}
+	require.NoError(t, webhooks.CreateDeliveries(ctx, []models.WebhookDelivery{
+		{WebhookID: 1, EventID: "e1", Event: "content.created", Status: models.DeliveryPending, NextAttemptAt: time.Now().Add(-time.Second)},
+	}))
+	deliveries, err := webhooks.ClaimDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	deliveries, err = webhooks.ClaimDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func TestSQLiteRetryAfterNewerWrite(t *testing.T) {
+	useSQLite(t)
+	ctx := context.Background()
+	outbox := &services.OutboxClient{}
+
+	// El primer upsert muere y después se guarda una versión más nueva
+	content := models.Content{Title: "Hola"}
+	require.NoError(t, outbox.CreateContent(ctx, &content, &models.OutboxEvent{Operation: services.OutboxUpsertBody}))
+	dead := models.OutboxEvent{ID: 1, Status: models.OutboxDead, Attempts: 10, LastError: "mongo down"}
+	require.NoError(t, outbox.FailEvent(ctx, &dead))
+	require.NoError(t, outbox.SaveContent(ctx, &content, &models.OutboxEvent{Operation: services.OutboxUpsertBody}))
+
+	// Reintentarlo pisaría el cuerpo nuevo con el viejo
+	retried, err := outbox.RetryEvent(ctx, 1)
+	assert.ErrorIs(t, err, services.ErrOutboxEventSuperseded)
+	assert.False(t, retried)
+
+	// Sin eventos posteriores el reintento sí se permite
+	other := models.Content{Title: "Otro"}
+	require.NoError(t, outbox.CreateContent(ctx, &other, &models.OutboxEvent{Operation: services.OutboxUpsertBody}))
+	dead = models.OutboxEvent{ID: 3, Status: models.OutboxDead, Attempts: 10}
+	require.NoError(t, outbox.FailEvent(ctx, &dead))
+	retried, err = outbox.RetryEvent(ctx, 3)
+	require.NoError(t, err)
+	assert.True(t, retried)
+
+	retried, err = outbox.RetryEvent(ctx, 99)
+	require.NoError(t, err)
+	assert.False(t, retried)
+}
+
+func TestSQLiteFeedState(t *testing.T) {
+	useSQLite(t)
+	ctx := context.Background()
+	feeds := &services.FeedClient{}
+
+	state, err := feeds.FeedState(ctx, services.FeedFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, state.Count)
+	assert.True(t, state.LastModified.IsZero())
+
+	published := time.Now().Add(-time.Hour).Truncate(time.Second)
+	content := models.Content{Title: "Hola", PublishedAt: &published}
+	require.NoError(t, (&services.PGClient{}).CreateContent(ctx, &content))
+	state, err = feeds.FeedState(ctx, services.FeedFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), state.Count)
+	assert.WithinDuration(t, content.UpdatedAt, state.LastModified, time.Millisecond)
+
+	// Borrar un contenido también cuenta como cambio
+	require.NoError(t, (&services.PGClient{}).DeleteContent(ctx, &content))
+	state, err = feeds.FeedState(ctx, services.FeedFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, state.Count)
+	assert.False(t, state.LastModified.Before(content.UpdatedAt))
+}
+
+func TestSQLiteContextCancellation(t *testing.T) {
+	useSQLite(t)
+	repo := &services.PGClient{}
+
+	// Una petición cancelada no llega a consultar la base
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var found models.Content
+	assert.ErrorIs(t, repo.GetContent(ctx, "1", &found), context.Canceled)
+
+	// El plazo por operación se aplica aunque la petición no tenga ninguno
+	previous := database.OperationTimeout
+	database.OperationTimeout = time.Nanosecond
+	t.Cleanup(func() { database.OperationTimeout = previous })
+	var contents []models.Content
+	assert.ErrorIs(t, repo.FindContents(context.Background(), &contents), context.DeadlineExceeded)
+}
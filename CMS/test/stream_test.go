@@ -0,0 +1,181 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
+)
+
+// runStream arranca el reparto y espera a que el broker tenga la suscripción
+func runStream(t *testing.T, broker services.StreamBroker) *services.ContentStream {
+	stream := services.NewContentStream(broker)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go stream.Run(ctx)
+	time.Sleep(20 * time.Millisecond)
+	return stream
+}
+
+func receive(t *testing.T, events <-chan services.StreamEvent) services.StreamEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return services.StreamEvent{}
+	}
+}
+
+func TestCompareStreamIDs(t *testing.T) {
+	assert.Equal(t, -1, services.CompareStreamIDs("5-1", "5-2"))
+	assert.Equal(t, -1, services.CompareStreamIDs("5-9", "10-0"))
+	assert.Equal(t, 0, services.CompareStreamIDs("7-3", "7-3"))
+	assert.Equal(t, 1, services.CompareStreamIDs("8", "7-3"))
+}
+
+func TestContentStreamFilters(t *testing.T) {
+	stream := runStream(t, services.NewMemoryStreamBroker(100))
+	filter, err := services.ParseStreamFilter("article", "7")
+	require.NoError(t, err)
+	sub, err := stream.Open(context.Background(), filter, "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	stream.Publish(context.Background(), services.NewContentEvent(services.EventContentCreated, models.Content{Title: "x", UserID: 7}))
+	stream.PublishEntry(context.Background(), services.EventContentCreated, models.Entry{ID: primitive.NewObjectID(), Type: "article", UserID: 8})
+	entry := models.Entry{ID: primitive.NewObjectID(), Type: "article", UserID: 7}
+	stream.PublishEntry(context.Background(), services.EventContentUpdated, entry)
+
+	event := receive(t, sub.Events)
+	assert.Equal(t, services.EventContentUpdated, event.Type)
+	assert.Equal(t, entry.ID.Hex(), event.ContentID)
+	assert.NotEmpty(t, event.ID)
+
+	_, err = services.ParseStreamFilter("", "abc")
+	assert.Error(t, err)
+}
+
+func TestContentStreamResume(t *testing.T) {
+	broker := services.NewMemoryStreamBroker(3)
+	stream := runStream(t, broker)
+	var ids []string
+	for i := 0; i < 4; i++ {
+		event, err := broker.Publish(context.Background(), services.StreamEvent{Type: services.EventContentUpdated, ContentType: "content"})
+		require.NoError(t, err)
+		ids = append(ids, event.ID)
+	}
+
+	sub, err := stream.Open(context.Background(), services.StreamFilter{}, ids[1])
+	require.NoError(t, err)
+	assert.False(t, sub.Reset)
+	require.Len(t, sub.Backlog, 2)
+	assert.Equal(t, ids[2], sub.Backlog[0].ID)
+	assert.Equal(t, ids[3], sub.Backlog[1].ID)
+	// Un evento en vivo ya incluido en el historial se descarta
+	assert.True(t, sub.Duplicate(services.StreamEvent{ID: ids[3]}))
+	sub.Close()
+
+	// ids[0] ya salió del historial: el cliente debe recargar
+	sub, err = stream.Open(context.Background(), services.StreamFilter{}, ids[0])
+	require.NoError(t, err)
+	assert.True(t, sub.Reset)
+	sub.Close()
+
+	_, err = stream.Open(context.Background(), services.StreamFilter{}, "abc")
+	assert.ErrorIs(t, err, services.ErrInvalidStreamID)
+}
+
+func TestStreamContentsSSE(t *testing.T) {
+	broker := services.NewMemoryStreamBroker(100)
+	stream := runStream(t, broker)
+	first, _ := broker.Publish(context.Background(), services.StreamEvent{Type: services.EventContentCreated, ContentType: "content", ContentID: "1"})
+	_, _ = broker.Publish(context.Background(), services.StreamEvent{Type: services.EventContentUpdated, ContentType: "content", ContentID: "1"})
+
+	// Un heartbeat corto hace que la desconexión se detecte enseguida al cerrar
+	stream.Heartbeat = 50 * time.Millisecond
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/collection/stream", services.NewStreamHandler(stream).StreamContents)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	defer app.Shutdown()
+
+	req, _ := http.NewRequest("GET", "http://"+ln.Addr().String()+"/collection/stream?content_type=content", nil)
+	req.Header.Set("Last-Event-ID", first.ID)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	// nextEvent lee hasta la siguiente línea data: y devuelve el evento
+	nextEvent := func() (string, services.StreamEvent) {
+		var name string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "event: ") {
+				name = strings.TrimPrefix(line, "event: ")
+			}
+			if strings.HasPrefix(line, "data: ") {
+				var event services.StreamEvent
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+				return name, event
+			}
+		}
+	}
+
+	name, event := nextEvent()
+	assert.Equal(t, services.EventContentUpdated, name)
+	assert.Equal(t, "1", event.ContentID)
+
+	stream.Publish(context.Background(), services.NewContentEvent(services.EventContentDeleted, models.Content{Model: gorm.Model{ID: 2}}))
+	name, event = nextEvent()
+	assert.Equal(t, services.EventContentDeleted, name)
+	assert.Equal(t, "2", event.ContentID)
+}
+
+func TestStreamContentsRejectsInvalidParams(t *testing.T) {
+	app := fiber.New()
+	app.Get("/collection/stream", services.NewStreamHandler(services.NewContentStream(services.NewMemoryStreamBroker(10))).StreamContents)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/collection/stream?author=x", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	req := httptest.NewRequest("GET", "/collection/stream", nil)
+	req.Header.Set("Last-Event-ID", "nope")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestContentStreamClose(t *testing.T) {
+	stream := services.NewContentStream(services.NewMemoryStreamBroker(10))
+	sub, err := stream.Open(context.Background(), services.StreamFilter{}, "")
+	require.NoError(t, err)
+
+	stream.Close()
+	_, ok := <-sub.Events
+	assert.False(t, ok, "open subscriptions end on close")
+	sub.Close()
+
+	_, err = stream.Open(context.Background(), services.StreamFilter{}, "")
+	assert.ErrorIs(t, err, services.ErrStreamClosed)
+}
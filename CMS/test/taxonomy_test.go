@@ -0,0 +1,217 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Mock para TaxonomyRepository
+type MockTaxonomyRepository struct {
+	mock.Mock
+}
+
+func (m *MockTaxonomyRepository) ListTags(ctx context.Context, tags *[]models.Tag) error {
+	return m.Called(ctx, tags).Error(0)
+}
+
+func (m *MockTaxonomyRepository) GetTag(ctx context.Context, id uint, tag *models.Tag) error {
+	return m.Called(ctx, id, tag).Error(0)
+}
+
+func (m *MockTaxonomyRepository) SaveTag(ctx context.Context, tag *models.Tag) error {
+	return m.Called(ctx, tag).Error(0)
+}
+
+func (m *MockTaxonomyRepository) DeleteTag(ctx context.Context, tag *models.Tag) error {
+	return m.Called(ctx, tag).Error(0)
+}
+
+func (m *MockTaxonomyRepository) FindOrCreateTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	args := m.Called(ctx, names)
+	tags, _ := args.Get(0).([]models.Tag)
+	return tags, args.Error(1)
+}
+
+func (m *MockTaxonomyRepository) ListCategories(ctx context.Context, categories *[]models.Category) error {
+	args := m.Called(ctx, categories)
+	if list, ok := args.Get(0).([]models.Category); ok {
+		*categories = list
+	}
+	return args.Error(1)
+}
+
+func (m *MockTaxonomyRepository) GetCategory(ctx context.Context, id uint, category *models.Category) error {
+	return m.Called(ctx, id, category).Error(0)
+}
+
+func (m *MockTaxonomyRepository) SaveCategory(ctx context.Context, category *models.Category) error {
+	return m.Called(ctx, category).Error(0)
+}
+
+func (m *MockTaxonomyRepository) DeleteCategory(ctx context.Context, category *models.Category) error {
+	return m.Called(ctx, category).Error(0)
+}
+
+func (m *MockTaxonomyRepository) SetContentTags(ctx context.Context, content *models.Content, tags []models.Tag) error {
+	return m.Called(ctx, content, tags).Error(0)
+}
+
+func (m *MockTaxonomyRepository) LoadContentTaxonomy(ctx context.Context, content *models.Content) error {
+	return m.Called(ctx, content).Error(0)
+}
+
+func (m *MockTaxonomyRepository) FindContentsByTaxonomy(ctx context.Context, tagSlug string, categoryIDs []uint, contents *[]models.Content) error {
+	return m.Called(ctx, tagSlug, categoryIDs, contents).Error(0)
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+// Árbol: 1 Noticias > 2 Deportes > 4 Fútbol, 3 Opinión
+var testCategories = []models.Category{
+	{ID: 1, Name: "Noticias", Slug: "noticias"},
+	{ID: 2, Name: "Deportes", Slug: "deportes", ParentID: uintPtr(1), Position: 2},
+	{ID: 3, Name: "Opinión", Slug: "opinion", Position: 1},
+	{ID: 4, Name: "Fútbol", Slug: "futbol", ParentID: uintPtr(2)},
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "programacion-en-go", services.Slugify("  Programación en Go! "))
+	assert.Equal(t, "", services.Slugify("¡¿?!"))
+}
+
+func TestCategoryTree(t *testing.T) {
+	assert.ElementsMatch(t, []uint{1, 2, 4}, services.CategoryDescendants(testCategories, 1))
+
+	tree := services.BuildCategoryTree(testCategories)
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Noticias", tree[0].Name)
+	assert.Equal(t, "Opinión", tree[1].Name)
+	require.Len(t, tree[0].Children, 1)
+	assert.Equal(t, "Fútbol", tree[0].Children[0].Children[0].Name)
+}
+
+func TestUpdateCategoryRejectsCycle(t *testing.T) {
+	repo := new(MockTaxonomyRepository)
+	handler := services.NewTaxonomyHandler(repo)
+
+	app := fiber.New()
+	app.Put("/categories/:id", handler.UpdateCategory)
+
+	repo.On("GetCategory", mock.Anything, uint(1), mock.AnythingOfType("*models.Category")).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Category) = testCategories[0]
+	}).Return(nil)
+	repo.On("ListCategories", mock.Anything, mock.Anything).Return(testCategories, nil)
+
+	req := httptest.NewRequest("PUT", "/categories/1", jsonBody(map[string]interface{}{"name": "Noticias", "parent_id": 4}))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	repo.AssertNotCalled(t, "SaveCategory", mock.Anything, mock.Anything)
+}
+
+func TestListContentsByCategoryIncludesDescendants(t *testing.T) {
+	repo := new(MockTaxonomyRepository)
+	handler := services.NewContentHandler(new(MockPGRepository), new(MockMongoRepository), missCache())
+	handler.Taxonomy = repo
+
+	app := fiber.New()
+	app.Get("/collection", handler.ListContents)
+
+	repo.On("ListCategories", mock.Anything, mock.Anything).Return(testCategories, nil)
+	repo.On("FindContentsByTaxonomy", mock.Anything, "", []uint{2, 4}, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(3).(*[]models.Content) = []models.Content{{Title: "Final"}}
+	}).Return(nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/collection?category=2", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var contents []models.Content
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&contents))
+	assert.Len(t, contents, 1)
+	repo.AssertExpectations(t)
+}
+
+// Renombrar o borrar una etiqueta o categoría invalida los contenidos que la
+// muestran y los listados
+func TestTaxonomyWritesInvalidateContentCache(t *testing.T) {
+	repo := new(MockTaxonomyRepository)
+	handler := services.NewTaxonomyHandler(repo)
+	handler.Cache = services.NewContentCache(newMemoryCache())
+	app := fiber.New()
+	app.Put("/tags/:id", handler.UpdateTag)
+	app.Delete("/tags/:id", handler.DeleteTag)
+	app.Delete("/categories/:id", handler.DeleteCategory)
+
+	ctx := context.Background()
+	loads := map[string]int{}
+	fetch := func(key string, tags ...string) {
+		var out string
+		require.NoError(t, handler.Cache.Fetch(ctx, key, tags, &out, func() (interface{}, error) {
+			loads[key]++
+			return key, nil
+		}))
+	}
+	warm := func() {
+		fetch("content:5", "content:5")
+		fetch("content:6", "content:6")
+		fetch("contents", "contents")
+	}
+	send := func(method, target string, body interface{}) {
+		req := httptest.NewRequest(method, target, jsonBody(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode, target)
+	}
+
+	repo.On("GetTag", mock.Anything, uint(1), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Tag) = models.Tag{ID: 1, Name: "Go", Slug: "go"}
+	}).Return(nil)
+	repo.On("FindContentsByTaxonomy", mock.Anything, "go", []uint(nil), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(3).(*[]models.Content) = []models.Content{{Model: gorm.Model{ID: 5}}}
+	}).Return(nil)
+	repo.On("SaveTag", mock.Anything, mock.Anything).Return(nil)
+	repo.On("DeleteTag", mock.Anything, mock.Anything).Return(nil)
+	repo.On("GetCategory", mock.Anything, uint(2), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.Category) = testCategories[1]
+	}).Return(nil)
+	repo.On("FindContentsByTaxonomy", mock.Anything, "", []uint{2}, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(3).(*[]models.Content) = []models.Content{{Model: gorm.Model{ID: 6}}}
+	}).Return(nil)
+	repo.On("DeleteCategory", mock.Anything, mock.Anything).Return(nil)
+
+	warm()
+	send("PUT", "/tags/1", map[string]string{"name": "Golang"})
+	warm()
+	assert.Equal(t, map[string]int{"content:5": 2, "content:6": 1, "contents": 2}, loads)
+
+	send("DELETE", "/tags/1", nil)
+	warm()
+	assert.Equal(t, map[string]int{"content:5": 3, "content:6": 1, "contents": 3}, loads)
+
+	send("DELETE", "/categories/2", nil)
+	warm()
+	assert.Equal(t, map[string]int{"content:5": 3, "content:6": 2, "contents": 4}, loads)
+	repo.AssertExpectations(t)
+}
+
+func jsonBody(v interface{}) *bytes.Reader {
+	body, _ := json.Marshal(v)
+	return bytes.NewReader(body)
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type MockUserAdminRepository struct {
+	MockUserRepository
+}
+
+func (m *MockUserAdminRepository) Save(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func TestUserAdminCreate(t *testing.T) {
+	repo := new(MockUserAdminRepository)
+	admin, ctx := services.NewUserAdmin(repo), context.Background()
+
+	_, _, err := admin.Create(ctx, "ana", "ana@example.com", "secreto123", "owner")
+	assert.ErrorIs(t, err, services.ErrInvalidRole)
+	_, _, err = admin.Create(ctx, "ana", "ana@example.com", "corta", services.RoleEditor)
+	assert.ErrorIs(t, err, services.ErrWeakPassword)
+
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Once()
+	user, password, err := admin.Create(ctx, "ana", "ana@example.com", "", services.RoleEditor)
+	require.NoError(t, err)
+	assert.Equal(t, services.RoleEditor, user.Role)
+	assert.True(t, user.IsActive)
+	assert.GreaterOrEqual(t, len(password), 8)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)))
+	repo.AssertExpectations(t)
+}
+
+func TestUserAdminUpdates(t *testing.T) {
+	repo := new(MockUserAdminRepository)
+	admin, ctx := services.NewUserAdmin(repo), context.Background()
+	lockout := time.Now().Add(time.Hour)
+	existing := &models.User{ID: 3, Username: "ana", Role: services.RoleAuthor, FailedAttempts: 5, LockoutUntil: &lockout}
+	repo.On("FindByUsernameOrEmail", mock.Anything, "ana", "ana").Return(existing, nil)
+	repo.On("FindByUsernameOrEmail", mock.Anything, "nadie", "nadie").Return(nil, gorm.ErrRecordNotFound)
+	repo.On("Save", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
+
+	_, err := admin.SetBanned(ctx, "nadie", true)
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+
+	user, err := admin.SetBanned(ctx, "ana", true)
+	require.NoError(t, err)
+	assert.True(t, user.IsBanned)
+
+	_, err = admin.SetRole(ctx, "ana", "root")
+	assert.ErrorIs(t, err, services.ErrInvalidRole)
+	user, err = admin.SetRole(ctx, "ana", services.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, services.RoleAdmin, user.Role)
+
+	// Restablecer la contraseña también desbloquea la cuenta
+	user, password, err := admin.ResetPassword(ctx, "ana", "nueva-clave")
+	require.NoError(t, err)
+	assert.Equal(t, "nueva-clave", password)
+	assert.Zero(t, user.FailedAttempts)
+	assert.Nil(t, user.LockoutUntil)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("nueva-clave")))
+}
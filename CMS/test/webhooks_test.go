@@ -0,0 +1,288 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"JSanches/CMD/models"
+	"JSanches/CMD/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Mock para WebhookRepository
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) ListWebhooks(ctx context.Context, webhooks *[]models.Webhook) error {
+	args := m.Called(ctx, webhooks)
+	if list, ok := args.Get(0).([]models.Webhook); ok {
+		*webhooks = list
+	}
+	return args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhook(ctx context.Context, id uint, webhook *models.Webhook) error {
+	args := m.Called(ctx, id, webhook)
+	if w, ok := args.Get(0).(models.Webhook); ok {
+		*webhook = w
+	}
+	return args.Error(1)
+}
+
+func (m *MockWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return m.Called(ctx, webhook).Error(0)
+}
+
+func (m *MockWebhookRepository) SaveWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return m.Called(ctx, webhook).Error(0)
+}
+
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return m.Called(ctx, webhook).Error(0)
+}
+
+func (m *MockWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	return m.Called(ctx, deliveries).Error(0)
+}
+
+func (m *MockWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, limit, lease)
+	deliveries, _ := args.Get(0).([]models.WebhookDelivery)
+	return deliveries, args.Error(1)
+}
+
+func (m *MockWebhookRepository) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return m.Called(ctx, delivery).Error(0)
+}
+
+func (m *MockWebhookRepository) GetDelivery(ctx context.Context, id uint, delivery *models.WebhookDelivery) error {
+	args := m.Called(ctx, id, delivery)
+	if d, ok := args.Get(0).(models.WebhookDelivery); ok {
+		*delivery = d
+	}
+	return args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, webhookID uint, limit, offset int, deliveries *[]models.WebhookDelivery) error {
+	return m.Called(ctx, webhookID, limit, offset, deliveries).Error(0)
+}
+
+// recordingPublisher guarda los eventos emitidos
+type recordingPublisher struct {
+	events []services.ContentEvent
+}
+
+func (r *recordingPublisher) Publish(ctx context.Context, event services.ContentEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestWebhookPublishFiltersSubscriptions(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	dispatcher := services.NewWebhookDispatcher(repo)
+
+	repo.On("ListWebhooks", mock.Anything, mock.Anything).Return([]models.Webhook{
+		{ID: 1, Active: true, Events: models.StringList{services.EventContentCreated}},
+		{ID: 2, Active: true, Events: models.StringList{services.EventContentDeleted}},
+		{ID: 3, Active: false, Events: models.StringList{"*"}},
+		{ID: 4, Active: true, Events: models.StringList{"*"}},
+	}, nil)
+	repo.On("CreateDeliveries", mock.Anything, mock.MatchedBy(func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 2 && deliveries[0].WebhookID == 1 && deliveries[1].WebhookID == 4 &&
+			deliveries[0].Status == models.DeliveryPending
+	})).Return(nil).Once()
+
+	dispatcher.Publish(context.Background(), services.NewContentEvent(services.EventContentCreated, models.Content{Title: "Hola"}))
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookDeliverySignedAndRetried(t *testing.T) {
+	secret := "s3cret"
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// El receptor recalcula la firma con el timestamp recibido
+		signature := r.Header.Get(services.WebhookSignatureHeader)
+		ts, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, services.SignWebhookPayload(secret, ts, body), signature)
+		assert.Equal(t, services.EventContentUpdated, r.Header.Get(services.WebhookEventHeader))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("nope"))
+	}))
+	defer server.Close()
+
+	repo := new(MockWebhookRepository)
+	dispatcher := services.NewWebhookDispatcher(repo)
+	// El servidor de prueba escucha en loopback, que el cliente por defecto rechaza
+	dispatcher.Client = server.Client()
+	dispatcher.MaxAttempts = 2
+	repo.On("GetWebhook", mock.Anything, uint(1), mock.Anything).Return(models.Webhook{ID: 1, URL: server.URL, Secret: secret}, nil)
+	var saved []models.WebhookDelivery
+	repo.On("SaveDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = append(saved, *args.Get(1).(*models.WebhookDelivery))
+	}).Return(nil)
+
+	delivery := models.WebhookDelivery{ID: 7, WebhookID: 1, Event: services.EventContentUpdated, Payload: `{"id":"x"}`, Status: models.DeliveryPending}
+	assert.Error(t, dispatcher.Deliver(context.Background(), delivery))
+	require.Len(t, saved, 1)
+	assert.Equal(t, models.DeliveryPending, saved[0].Status)
+	assert.Equal(t, 500, saved[0].ResponseCode)
+	assert.Equal(t, "nope", saved[0].ResponseBody)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), saved[0].NextAttemptAt, time.Second)
+
+	// El último intento permitido marca la entrega como fallida
+	assert.Error(t, dispatcher.Deliver(context.Background(), saved[0]))
+	assert.Equal(t, models.DeliveryFailed, saved[1].Status)
+
+	status = http.StatusNoContent
+	assert.NoError(t, dispatcher.Deliver(context.Background(), delivery))
+	assert.Equal(t, models.DeliverySucceeded, saved[2].Status)
+	assert.NotNil(t, saved[2].DeliveredAt)
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	app := fiber.New()
+	app.Post("/webhooks", services.NewWebhookHandler(services.NewWebhookDispatcher(repo)).CreateWebhook)
+
+	post := func(body interface{}) *http.Response {
+		req := httptest.NewRequest("POST", "/webhooks", jsonBody(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, fiber.StatusBadRequest, post(map[string]interface{}{"url": "ftp://x", "events": []string{"content.created"}}).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, post(map[string]interface{}{"url": "https://x.test/hook", "events": []string{"content.viewed"}}).StatusCode)
+	for _, target := range []string{"http://127.0.0.1:8080/", "http://localhost/hook", "http://169.254.169.254/latest/meta-data", "http://10.0.0.5/", "http://100.64.0.1/", "http://[::1]/"} {
+		assert.Equal(t, fiber.StatusBadRequest, post(map[string]interface{}{"url": target, "events": []string{"content.created"}}).StatusCode, target)
+	}
+
+	repo.On("CreateWebhook", mock.Anything, mock.AnythingOfType("*models.Webhook")).Return(nil).Once()
+	resp := post(map[string]interface{}{"url": "https://x.test/hook", "events": []string{"content.created"}})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Len(t, body["secret"], 64)
+	assert.NotContains(t, body["webhook"], "secret")
+}
+
+func TestContentHandlerEmitsLifecycleEvents(t *testing.T) {
+	pgMock := new(MockPGRepository)
+	mongoMock := new(MockMongoRepository)
+	publisher := &recordingPublisher{}
+	handler := services.NewContentHandler(pgMock, mongoMock, missCache())
+	handler.Events = publisher
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", "1")
+		return c.Next()
+	})
+	app.Post("/content", handler.CreateContent)
+
+	pgMock.On("CreateContent", mock.Anything, mock.AnythingOfType("*models.Content")).Return(nil)
+	mongoMock.On("InsertContentBody", mock.Anything, mock.Anything).Return(nil)
+
+	req := httptest.NewRequest("POST", "/content", jsonBody(map[string]interface{}{"title": "Hola", "published": true}))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	require.Len(t, publisher.events, 2)
+	assert.Equal(t, services.EventContentCreated, publisher.events[0].Type)
+	assert.Equal(t, services.EventContentPublished, publisher.events[1].Type)
+	assert.NotNil(t, publisher.events[1].Content.PublishedAt)
+}
+
+func TestWebhookDeliveryRejectsInternalTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal data"))
+	}))
+	defer server.Close()
+
+	// La URL registrada puede resolver a la red interna aunque pase la validación
+	repo := new(MockWebhookRepository)
+	dispatcher := services.NewWebhookDispatcher(repo)
+	repo.On("GetWebhook", mock.Anything, uint(1), mock.Anything).Return(models.Webhook{ID: 1, URL: server.URL, Secret: "s"}, nil)
+	var saved models.WebhookDelivery
+	repo.On("SaveDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = *args.Get(1).(*models.WebhookDelivery)
+	}).Return(nil)
+
+	err := dispatcher.Deliver(context.Background(), models.WebhookDelivery{ID: 1, WebhookID: 1, Event: services.EventContentCreated, Payload: `{}`})
+	assert.ErrorIs(t, err, services.ErrWebhookTarget)
+	assert.Zero(t, saved.ResponseCode)
+	assert.Empty(t, saved.ResponseBody)
+}
+
+func TestWebhookBatchDeliversConcurrently(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := new(MockWebhookRepository)
+	dispatcher := services.NewWebhookDispatcher(repo)
+	dispatcher.Client = server.Client()
+	deliveries := make([]models.WebhookDelivery, 10)
+	for i := range deliveries {
+		deliveries[i] = models.WebhookDelivery{ID: uint(i + 1), WebhookID: 1, Event: services.EventContentCreated, Payload: `{}`}
+	}
+	repo.On("ClaimDeliveries", mock.Anything, dispatcher.BatchSize, dispatcher.Lease).Return(deliveries, nil).Once()
+	repo.On("GetWebhook", mock.Anything, uint(1), mock.Anything).Return(models.Webhook{ID: 1, URL: server.URL, Secret: "s", Active: true}, nil)
+	repo.On("SaveDelivery", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.DeliverySucceeded
+	})).Return(nil).Times(len(deliveries))
+
+	// En serie tardaría 2s; el lote entero debe caber en el tiempo de una entrega
+	start := time.Now()
+	n, err := dispatcher.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, len(deliveries), n)
+	assert.Less(t, time.Since(start), time.Second)
+	repo.AssertExpectations(t)
+}
+
+func TestRedeliverRequiresActiveWebhook(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	app := fiber.New()
+	app.Post("/webhooks/deliveries/:deliveryId/redeliver", services.NewWebhookHandler(services.NewWebhookDispatcher(repo)).Redeliver)
+	redeliver := func(id int) int {
+		resp, err := app.Test(httptest.NewRequest("POST", fmt.Sprintf("/webhooks/deliveries/%d/redeliver", id), nil), -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	for id, webhookID := range map[uint]uint{1: 10, 2: 20, 3: 30} {
+		repo.On("GetDelivery", mock.Anything, id, mock.Anything).Return(models.WebhookDelivery{ID: id, WebhookID: webhookID, Event: services.EventContentCreated, Payload: `{}`}, nil)
+	}
+	repo.On("GetWebhook", mock.Anything, uint(10), mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("GetWebhook", mock.Anything, uint(20), mock.Anything).Return(models.Webhook{ID: 20, Active: false}, nil)
+	repo.On("GetWebhook", mock.Anything, uint(30), mock.Anything).Return(models.Webhook{ID: 30, Active: true}, nil)
+	repo.On("CreateDeliveries", mock.Anything, mock.MatchedBy(func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 1 && deliveries[0].WebhookID == 30
+	})).Return(nil).Once()
+
+	assert.Equal(t, fiber.StatusNotFound, redeliver(1))
+	assert.Equal(t, fiber.StatusConflict, redeliver(2))
+	assert.Equal(t, fiber.StatusAccepted, redeliver(3))
+	repo.AssertExpectations(t)
+}
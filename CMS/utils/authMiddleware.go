@@ -1,47 +1,63 @@
-package utils
-
-import (
-	"os"
-
-	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
-)
-
-func AuthMiddleware() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		cookie := c.Get("auth_token")
-		if cookie == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized",
-			})
-		}
-
-		secretKey, exist := os.LookupEnv("SECRET_KEY")
-		if !exist {
-			panic("SECRET_KEY not found")
-		}
-
-		token, err := jwt.ParseWithClaims(cookie, &AuthClaims{}, func(t *jwt.Token) (interface{}, error) {
-			return []byte(secretKey), nil
-		})
-
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized",
-			})
-		}
-
-		claims, ok := token.Claims.(*AuthClaims)
-		if !ok || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized",
-			})
-		}
-
-		c.Locals("user_id", claims.Id)
-		c.Locals("username", claims.Username)
-
-		return c.Next()
-	}
-
-}
+package utils
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func AuthMiddleware(secretKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cookie := c.Get("auth_token")
+		if cookie == "" {
+			return Problem(c, fiber.StatusUnauthorized, "Unauthorized")
+		}
+
+		token, err := jwt.ParseWithClaims(cookie, &AuthClaims{}, func(t *jwt.Token) (interface{}, error) {
+			return []byte(secretKey), nil
+		})
+
+		if err != nil {
+			return Problem(c, fiber.StatusUnauthorized, "Unauthorized")
+		}
+
+		claims, ok := token.Claims.(*AuthClaims)
+		if !ok || !token.Valid {
+			return Problem(c, fiber.StatusUnauthorized, "Unauthorized")
+		}
+
+		c.Locals("user_id", claims.Id)
+		c.Locals("username", claims.Username)
+		c.Locals("role", claims.Role)
+
+		return c.Next()
+	}
+
+}
+
+// UserID devuelve el id del usuario que AuthMiddleware dejó en Locals; ok es
+// false si la petición no pasó por el middleware o el token no trae un id.
+func UserID(c *fiber.Ctx) (int, bool) {
+	id, _ := c.Locals("user_id").(string)
+	userID, err := strconv.Atoi(id)
+	if err != nil || userID <= 0 {
+		return 0, false
+	}
+	return userID, true
+}
+
+// RequireRole solo deja pasar a los usuarios con alguno de roles. Va después de
+// AuthMiddleware y se fía del rol del token sin consultar la base: un cambio de
+// rol o un baneo no se aplica hasta que caduca el token ya emitido (24 horas).
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		for _, allowed := range roles {
+			if role == allowed {
+				return c.Next()
+			}
+		}
+		return Problem(c, fiber.StatusForbidden, "Forbidden")
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit rechaza con 413 los cuerpos de más de limit bytes. El servidor se
+// configura con StreamRequestBody para no cortar él los cuerpos grandes, así que
+// este middleware es el que aplica el límite de cada ruta; skip deja pasar las
+// rutas que llevan su propio BodyLimit.
+func BodyLimit(limit int, skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+		req := c.Request()
+		if req.Header.ContentLength() > limit {
+			return Problem(c, fiber.StatusRequestEntityTooLarge, "Request body is too large")
+		}
+		if !req.IsBodyStream() {
+			if len(req.Body()) > limit {
+				return Problem(c, fiber.StatusRequestEntityTooLarge, "Request body is too large")
+			}
+			return c.Next()
+		}
+		// Los cuerpos chunked no declaran su tamaño: se leen hasta pasarse del límite
+		body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+		if err != nil {
+			return Problem(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		if len(body) > limit {
+			return Problem(c, fiber.StatusRequestEntityTooLarge, "Request body is too large")
+		}
+		req.SetBody(body)
+		return c.Next()
+	}
+}
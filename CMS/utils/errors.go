@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// Las respuestas de error siguen RFC 7807 (application/problem+json). code es un
+// identificador estable para los clientes; detail es el texto para personas y
+// puede cambiar. Los errores internos solo se registran en el log.
+
+const ProblemContentType = "application/problem+json"
+
+// Códigos estables de error
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidID            = "invalid_id"
+	CodeInvalidBody          = "invalid_body"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeTooManyRequests      = "too_many_requests"
+	CodeInternal             = "internal_error"
+	CodeNotImplemented       = "not_implemented"
+	CodeUnavailable          = "service_unavailable"
+)
+
+var statusCodes = map[int]string{
+	fiber.StatusBadRequest:            CodeInvalidRequest,
+	fiber.StatusUnauthorized:          CodeUnauthorized,
+	fiber.StatusForbidden:             CodeForbidden,
+	fiber.StatusNotFound:              CodeNotFound,
+	fiber.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	fiber.StatusConflict:              CodeConflict,
+	fiber.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	fiber.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+	fiber.StatusUnprocessableEntity:   CodeValidationFailed,
+	fiber.StatusTooManyRequests:       CodeTooManyRequests,
+	fiber.StatusNotImplemented:        CodeNotImplemented,
+	fiber.StatusServiceUnavailable:    CodeUnavailable,
+}
+
+// StatusCode devuelve el código por defecto de un status HTTP
+func StatusCode(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
+
+// Error es un error de la API. Err guarda la causa para el log y nunca se envía.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields map[string]string
+	Err    error
+}
+
+// NewError crea un error con el código por defecto de status
+func NewError(status int, detail string) *Error {
+	return &Error{Status: status, Code: StatusCode(status), Detail: detail}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ProblemDetails es el cuerpo de la respuesta
+type ProblemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// SendError responde con err como problem+json
+func SendError(c *fiber.Ctx, err *Error) error {
+	if err.Err != nil {
+		log.Printf("%s %s: %d %s: %v", c.Method(), c.Path(), err.Status, err.Code, err.Err)
+	}
+	return c.Status(err.Status).JSON(ProblemDetails{
+		Type:     "urn:cms:problem:" + err.Code,
+		Title:    http.StatusText(err.Status),
+		Status:   err.Status,
+		Detail:   err.Detail,
+		Instance: c.Path(),
+		Code:     err.Code,
+		Fields:   err.Fields,
+	}, ProblemContentType)
+}
+
+// Problem responde con status y detail usando el código por defecto del status
+func Problem(c *fiber.Ctx, status int, detail string) error {
+	return SendError(c, NewError(status, detail))
+}
+
+// InternalError es un 500 con detail; err se registra pero no se envía
+func InternalError(detail string, err error) *Error {
+	return &Error{Status: fiber.StatusInternalServerError, Code: CodeInternal, Detail: detail, Err: err}
+}
+
+// InvalidIDError es un 400 por un identificador de la ruta mal formado
+func InvalidIDError(detail string) *Error {
+	return &Error{Status: fiber.StatusBadRequest, Code: CodeInvalidID, Detail: detail}
+}
+
+// ValidationError es un 422 con el problema de cada campo en fields
+func ValidationError(detail string, fields map[string]string) *Error {
+	return &Error{Status: fiber.StatusUnprocessableEntity, Code: CodeValidationFailed, Detail: detail, Fields: fields}
+}
+
+// InvalidBodyError es un 400 por un cuerpo que no se puede leer
+func InvalidBodyError() *Error {
+	return &Error{Status: fiber.StatusBadRequest, Code: CodeInvalidBody, Detail: "Invalid request body"}
+}
+
+// InternalProblem responde con InternalError
+func InternalProblem(c *fiber.Ctx, detail string, err error) error {
+	return SendError(c, InternalError(detail, err))
+}
+
+// InvalidID responde con InvalidIDError
+func InvalidID(c *fiber.Ctx, detail string) error {
+	return SendError(c, InvalidIDError(detail))
+}
+
+// InvalidBody responde con InvalidBodyError
+func InvalidBody(c *fiber.Ctx) error {
+	return SendError(c, InvalidBodyError())
+}
+
+// IsNotFound indica si err es la ausencia de un registro en GORM o Mongo
+func IsNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, mongo.ErrNoDocuments)
+}
+
+// IsConflict indica si err es una violación de unicidad. GORM la traduce a
+// ErrDuplicatedKey con TranslateError; Mongo la devuelve como error de escritura.
+func IsConflict(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) || mongo.IsDuplicateKeyError(err)
+}
+
+// StoreError clasifica el error de un repositorio: la ausencia es 404 y la
+// unicidad 409, ambos con resource en el detalle; el resto es un 500 con failed
+// que registra la causa.
+func StoreError(err error, resource, failed string) *Error {
+	switch {
+	case IsNotFound(err):
+		return &Error{Status: fiber.StatusNotFound, Code: CodeNotFound, Detail: resource + " not found"}
+	case IsConflict(err):
+		return &Error{Status: fiber.StatusConflict, Code: CodeConflict, Detail: resource + " already exists"}
+	}
+	return InternalError(failed, err)
+}
+
+// StoreProblem responde con StoreError
+func StoreProblem(c *fiber.Ctx, err error, resource, failed string) error {
+	return SendError(c, StoreError(err, resource, failed))
+}
+
+// ErrorHandler es el ErrorHandler de Fiber: responde problem+json a los errores
+// que devuelven los handlers, el router (404, 405, 413) o recover. Los errores
+// que no son de la API se registran y se responden como un 500 genérico.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return SendError(c, apiErr)
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) && fiberErr.Code < fiber.StatusInternalServerError {
+		return SendError(c, NewError(fiberErr.Code, fiberErr.Message))
+	}
+	return SendError(c, InternalError("Internal server error", err))
+}